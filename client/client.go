@@ -0,0 +1,231 @@
+/*
+Client Package is a typed Go client of the /v1 REST API, for teams integrating with Smlr-Backend
+Requests and responses are the structs the handlers use (userinfo.WorkerInfo, contacts.Contact, danger.Alert...)
+Every call takes a context, calls that are safe to send twice are retried on network errors, 429 and 5xx gateway errors
+	smlr := client.NewClient("http://localhost:4000")
+	workerInfoList, err := smlr.ListWorkerInfo(ctx, client.ListOptions{Range: "today"})
+Checked against the real engine by the package tests
+*/
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go_backend/util"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const BasePath = "/v1"
+const UserAgent = "smlr-go-client"
+
+const (
+	DefaultMaxRetries = 3
+	DefaultRetryWait  = 200 * time.Millisecond // Doubled after every attempt
+	MaxRetryWait      = 10 * time.Second
+	maxErrorBody      = 4096
+)
+
+type Client struct {
+	BaseURL    string       // Scheme and host, http://localhost:4000
+	HTTPClient *http.Client // Keep Timeout at 0 when using StreamAlerts, use contexts for deadlines
+	MaxRetries int          // Attempts after the first one, 0 disables retries
+	RetryWait  time.Duration
+}
+
+func NewClient(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: &http.Client{},
+		MaxRetries: DefaultMaxRetries,
+		RetryWait:  DefaultRetryWait,
+	}
+}
+
+// APIError is returned for every response outside 2xx, the fields come from the util.ErrorBody the handler wrote
+// A body that isn't an ErrorBody (from a proxy) is kept whole in Message
+type APIError struct {
+	StatusCode int
+	Code       string // not_found, throttled... see util.ErrorCode
+	Message    string
+	RequestId  string // Quote it when reporting a problem, the server logs the reason under it
+}
+
+func (err *APIError) Error() string {
+	message := err.Message
+	if message == "" {
+		message = http.StatusText(err.StatusCode)
+	}
+	if err.RequestId == "" {
+		return fmt.Sprintf("smlr: %v %v", err.StatusCode, message)
+	}
+	return fmt.Sprintf("smlr: %v %v (request %v)", err.StatusCode, message, err.RequestId)
+}
+
+// StatusCode Returns the HTTP status of an APIError, 0 for any other error
+func StatusCode(err error) int {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+type request struct {
+	method string
+	path   string // Under BasePath, /userinfo
+	query  url.Values
+	header http.Header
+	body   interface{} // Sent as JSON, nil for none
+	retry  bool        // Safe to send twice
+}
+
+func newRequest(method, path string) *request {
+	return &request{
+		method: method,
+		path:   path,
+		query:  url.Values{},
+		header: http.Header{},
+		retry:  method != http.MethodPost, // GET, PUT and DELETE replace or read, a POST opts in
+	}
+}
+
+// send Returns the 2xx response of req, retried while it is safe to, the caller closes the body
+func (c *Client) send(ctx context.Context, req *request) (*http.Response, error) {
+	var body []byte
+	if req.body != nil {
+		var err error
+		if body, err = json.Marshal(req.body); err != nil {
+			return nil, err
+		}
+	}
+	target := c.BaseURL + BasePath + req.path
+	if len(req.query) > 0 {
+		target += "?" + req.query.Encode()
+	}
+
+	backoff := c.RetryWait
+	for attempt := 0; ; attempt++ {
+		httpReq, err := http.NewRequestWithContext(ctx, req.method, target, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		for name, values := range req.header {
+			httpReq.Header[name] = values
+		}
+		httpReq.Header.Set("User-Agent", UserAgent)
+		if req.body != nil {
+			httpReq.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := c.HTTPClient.Do(httpReq)
+		if err == nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return resp, nil
+		}
+		wait := backoff
+		if err == nil {
+			err = readError(resp)
+			// Retry-After only stretches this wait, a short or past one doesn't undo the backoff
+			if retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); retryAfter > wait {
+				wait = retryAfter
+			}
+			if wait > MaxRetryWait {
+				wait = MaxRetryWait
+			}
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !req.retry || attempt >= c.MaxRetries || !isRetryable(err) {
+			return nil, err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		if backoff *= 2; backoff > MaxRetryWait {
+			backoff = MaxRetryWait
+		}
+	}
+}
+
+// parseRetryAfter Reads a Retry-After header in seconds or as an HTTP-date, 0 when it is missing or invalid
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// call Sends req and decodes the JSON response into out (nil to discard it), returns the response headers
+func (c *Client) call(ctx context.Context, req *request, out interface{}) (http.Header, error) {
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return resp.Header, err
+	}
+	if err = json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return resp.Header, fmt.Errorf("smlr: couldn't decode %v %v: %w", req.method, req.path, err)
+	}
+	return resp.Header, nil
+}
+
+func readError(resp *http.Response) error {
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	errorBody := util.ErrorBody{}
+	if err := json.Unmarshal(body, &errorBody); err == nil && errorBody.Code != "" {
+		return &APIError{StatusCode: resp.StatusCode, Code: errorBody.Code, Message: errorBody.Message, RequestId: errorBody.RequestId}
+	}
+	return &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body)), RequestId: resp.Header.Get(util.RequestIdHeader)}
+}
+
+// isRetryable Network errors, rate limiting and the statuses a proxy returns while the server restarts
+func isRetryable(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return true
+	}
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// NewIdempotencyKey Returns a random key, sent with POSTs that have none so they can be retried
+func NewIdempotencyKey() string {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(key)
+}
@@ -0,0 +1,386 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_backend/routes"
+	"go_backend/routes/contacts"
+	"go_backend/routes/danger"
+	"go_backend/routes/hospital"
+	"go_backend/routes/table"
+	"go_backend/routes/userinfo"
+	"go_backend/util"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// TestMain Runs the real engine on the memory backend in a scratch directory, the alert queue opens LOCAL_DB_FILE there
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "client-test")
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err = os.Chdir(dir); err != nil {
+		log.Fatalln(err)
+	}
+	viper.Set("STORAGE_BACKEND", util.StorageMemory)
+	gin.SetMode(gin.TestMode)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// startFlakyEngine Serves the gin engine, every third GET fails like a restarting server behind a proxy
+func startFlakyEngine(t *testing.T) *httptest.Server {
+	t.Helper()
+	engine := gin.New()
+	routes.Attach(engine)
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.URL.Path != "/v1/danger/stream" && requests.Add(1)%3 == 0 {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "restarting", http.StatusServiceUnavailable)
+			return
+		}
+		engine.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func expectStatus(t *testing.T, err error, status int) {
+	t.Helper()
+	if StatusCode(err) != status {
+		t.Fatalf("want status %v, got %v", status, err)
+	}
+}
+
+// TestAgainstEngine Runs the client against the real handlers, the checks run in order and share the stores
+func TestAgainstEngine(t *testing.T) {
+	server := startFlakyEngine(t)
+	smlr := NewClient(server.URL)
+	smlr.RetryWait = 10 * time.Millisecond
+
+	t.Run("contacts", func(t *testing.T) {
+		ctx := testContext(t)
+		if err := smlr.InsertContact(ctx, &contacts.Contact{Name: "Site doctor", PhoneNumber: "200"}); err != nil {
+			t.Fatal(err)
+		}
+		contactList, err := smlr.Contacts(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page, err := smlr.ContactPage(ctx, 10, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(contactList) != 1 || len(page.Items) != 1 {
+			t.Fatalf("want 1 contact, list has %v, page %v", len(contactList), len(page.Items))
+		}
+		if err = smlr.DeleteContact(ctx, "200"); err != nil {
+			t.Fatal(err)
+		}
+		if contactList, err = smlr.Contacts(ctx); err != nil || len(contactList) != 0 {
+			t.Fatalf("want no contacts after delete, got %v, %v", contactList, err)
+		}
+	})
+
+	t.Run("hospital", func(t *testing.T) {
+		ctx := testContext(t)
+		for _, phoneNumber := range []string{"100", "101", "102"} {
+			if err := smlr.InsertHospital(ctx, &hospital.Hospital{Name: "General " + phoneNumber, PhoneNumber: phoneNumber}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		pages := 0
+		err := EachPage(ctx, 2, smlr.HospitalPage, func(items []hospital.Hospital) error {
+			pages++
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		hospitalList, err := All(ctx, 2, smlr.HospitalPage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pages != 2 || len(hospitalList) != 3 {
+			t.Fatalf("want 3 hospitals in 2 pages, got %v in %v", len(hospitalList), pages)
+		}
+		if err = smlr.DeleteHospital(ctx, "101"); err != nil {
+			t.Fatal(err)
+		}
+		if hospitalList, err = smlr.Hospitals(ctx); err != nil || len(hospitalList) != 2 {
+			t.Fatalf("want 2 hospitals after a delete, got %v, %v", len(hospitalList), err)
+		}
+	})
+
+	t.Run("userinfo", func(t *testing.T) {
+		ctx := testContext(t)
+		reading := &userinfo.RawWorkerInfo{GroundNumber: "G1", HelmetNumber: "H1", Spo2Level: 97, Temperature: 37, HeartRate: 80, Sequence: 1}
+		if replayed, err := smlr.InsertWorkerInfo(ctx, reading); err != nil || replayed {
+			t.Fatalf("first insert: replayed %v, %v", replayed, err)
+		}
+		if replayed, err := smlr.InsertWorkerInfo(ctx, reading); err != nil || !replayed {
+			t.Fatalf("second insert: replayed %v, %v", replayed, err)
+		}
+		// No sequence, the client adds an Idempotency-Key so it can be retried
+		if _, err := smlr.InsertWorkerInfo(ctx, &userinfo.RawWorkerInfo{GroundNumber: "G1", HelmetNumber: "H2", Spo2Level: 91, HeartRate: 120}); err != nil {
+			t.Fatal(err)
+		}
+
+		workerInfo, err := smlr.WorkerInfo(ctx, "G1_H1")
+		if err != nil || workerInfo.Id != "G1_H1" {
+			t.Fatalf("want G1_H1, got %v, %v", workerInfo.Id, err)
+		}
+		workerInfoList, err := smlr.ListWorkerInfo(ctx, ListOptions{Range: "today", SortByScore: true})
+		if err != nil || len(workerInfoList) != 2 || workerInfoList[0].Id != "G1_H2" {
+			t.Fatalf("want G1_H2 first of 2, got %v, %v", workerInfoList, err)
+		}
+		if paged, err := All(ctx, 1, smlr.WorkerInfoPages(ListOptions{Range: "today"})); err != nil || len(paged) != 2 {
+			t.Fatalf("want 2 workers over pages, got %v, %v", len(paged), err)
+		}
+
+		report, err := smlr.InsertBatch(ctx, []userinfo.RawWorkerInfo{
+			{GroundNumber: "G1", HelmetNumber: "H1", Spo2Level: 96, HeartRate: 82, Sequence: 2},
+			{GroundNumber: "G1", HelmetNumber: "H1", Spo2Level: 96, HeartRate: 84, Sequence: 5},
+			{GroundNumber: "G1", HelmetNumber: "H1", Spo2Level: 96, HeartRate: 84, Sequence: 5}, // resent
+		})
+		if err != nil || report.Accepted != 2 || report.Duplicates != 1 {
+			t.Fatalf("want 2 accepted and 1 duplicate, got %+v, %v", report, err)
+		}
+		gaps, err := smlr.Gaps(ctx, "G1_H1")
+		if err != nil || len(gaps) != 1 || gaps[0].From != 3 || gaps[0].To != 4 {
+			t.Fatalf("want gap 3-4, got %+v, %v", gaps, err)
+		}
+
+		workerInfo.Name = "Ravi"
+		if err = smlr.UpdateWorkerInfo(ctx, &workerInfo); err != nil {
+			t.Fatal(err)
+		}
+		if err = smlr.DeleteWorkerInfo(ctx, "G1_H2"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("readings", func(t *testing.T) {
+		ctx := testContext(t)
+		readings, err := smlr.Readings(ctx, "G1_H1", userinfo.ReadingsQuery{Range: "today"})
+		if err != nil || len(readings) != 3 {
+			t.Fatalf("want 3 readings of G1_H1, got %v, %v", len(readings), err)
+		}
+		buckets, err := smlr.AggregateReadings(ctx, "G1_H1", "1h", userinfo.ReadingsQuery{Range: "today"})
+		if err != nil || len(buckets) == 0 || buckets[len(buckets)-1].Fields["HeartRate"].Max != 84 {
+			t.Fatalf("want a HeartRate max of 84, got %+v, %v", buckets, err)
+		}
+		series, err := smlr.DownsampleReadings(ctx, "G1_H1", 0, "HeartRate", userinfo.ReadingsQuery{Range: "today"})
+		if err != nil || len(series["HeartRate"]) != 3 {
+			t.Fatalf("want 3 HeartRate points, got %v, %v", series, err)
+		}
+		_, err = smlr.AggregateReadings(ctx, "G1_H1", "2m", userinfo.ReadingsQuery{})
+		expectStatus(t, err, http.StatusBadRequest)
+	})
+
+	t.Run("danger", func(t *testing.T) {
+		ctx := testContext(t)
+		streamCtx, stop := context.WithCancel(ctx)
+		defer stop()
+		received := make(chan danger.Alert, 1)
+		streamDone := make(chan error, 1)
+		var lastEventId uint64
+		go func() {
+			streamDone <- smlr.StreamAlerts(streamCtx, StreamOptions{LastEventId: &lastEventId, DangerTypes: []string{danger.DangerSOS}},
+				func(alert danger.Alert) error {
+					received <- alert
+					return errors.New("got one")
+				})
+		}()
+
+		reading := &userinfo.RawWorkerInfo{GroundNumber: "G2", HelmetNumber: "H7", DangerType: danger.DangerSOS, Sequence: 1}
+		alert, replayed, err := smlr.RaiseAlert(ctx, reading)
+		if err != nil || replayed {
+			t.Fatalf("raise: replayed %v, %v", replayed, err)
+		}
+		again, replayed, err := smlr.RaiseAlert(ctx, reading)
+		if err != nil || !replayed || again.Id != alert.Id {
+			t.Fatalf("raise again: replayed %v, id %v, %v", replayed, again.Id, err)
+		}
+
+		select {
+		case streamed := <-received:
+			if streamed.Id != alert.Id {
+				t.Fatalf("streamed alert %v, raised %v", streamed.Id, alert.Id)
+			}
+		case <-ctx.Done():
+			t.Fatal("no alert on the stream")
+		}
+		if err = <-streamDone; err == nil || err.Error() != "got one" {
+			t.Fatalf("stream should end with the handler's error, got %v", err)
+		}
+
+		if alert, err = smlr.AcknowledgeAlert(ctx, alert.Id, "supervisor"); err != nil || alert.State != danger.StateAcknowledged {
+			t.Fatalf("want acknowledged, got %v, %v", alert.State, err)
+		}
+		_, err = smlr.AcknowledgeAlert(ctx, alert.Id, "supervisor")
+		expectStatus(t, err, http.StatusConflict)
+		_, err = smlr.ResolveAlert(ctx, alert.Id+1000, "supervisor")
+		expectStatus(t, err, http.StatusNotFound)
+		if alert, err = smlr.ResolveAlert(ctx, alert.Id, "supervisor"); err != nil {
+			t.Fatal(err)
+		}
+		alertList, err := smlr.Alerts(ctx, danger.StateResolved)
+		if err != nil || len(alertList) != 1 || alertList[0].Id != alert.Id {
+			t.Fatalf("want the resolved alert, got %v, %v", alertList, err)
+		}
+	})
+
+	t.Run("table", func(t *testing.T) {
+		err := smlr.RunTableOperation(testContext(t), table.TableOperation{Name: "Contact", Operation: "create"})
+		expectStatus(t, err, http.StatusNotImplemented)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		ctx := testContext(t)
+		// Neither range nor dates
+		_, err := smlr.ListWorkerInfo(ctx, ListOptions{})
+		expectStatus(t, err, http.StatusBadRequest)
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.Code != "bad_request" || apiErr.RequestId == "" {
+			t.Fatalf("want a bad_request envelope with a request id, got %#v", err)
+		}
+		_, err = smlr.WorkerInfo(ctx, "G9_H9")
+		expectStatus(t, err, http.StatusNotFound)
+		_, err = smlr.WorkerInfoPage(ctx, ListOptions{Range: "today"}, 0, "forged")
+		expectStatus(t, err, http.StatusBadRequest)
+	})
+
+	t.Run("no retry", func(t *testing.T) {
+		ctx := testContext(t)
+		once := NewClient(server.URL)
+		once.MaxRetries = 0
+		// The flaky server fails every third GET, one of these three gets its 503
+		var err error
+		for i := 0; i < 3 && err == nil; i++ {
+			_, err = once.Contacts(ctx)
+		}
+		expectStatus(t, err, http.StatusServiceUnavailable)
+	})
+}
+
+// startUnavailable Answers 503 with retryAfter to the first failures requests, then an empty list
+func startUnavailable(t *testing.T, failures int64, retryAfter string) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= failures {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			http.Error(w, "restarting", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, "[]")
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestRetries(t *testing.T) {
+	t.Run("until success", func(t *testing.T) {
+		server, requests := startUnavailable(t, 2, "")
+		retrying := NewClient(server.URL)
+		retrying.RetryWait = time.Millisecond
+		if _, err := retrying.Contacts(testContext(t)); err != nil || requests.Load() != 3 {
+			t.Fatalf("got %v after %v requests", err, requests.Load())
+		}
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		server, requests := startUnavailable(t, 2, "")
+		retrying := NewClient(server.URL)
+		retrying.RetryWait = time.Millisecond
+		retrying.MaxRetries = 1
+		_, err := retrying.Contacts(testContext(t))
+		expectStatus(t, err, http.StatusServiceUnavailable)
+		if requests.Load() != 2 {
+			t.Errorf("sent %v requests, want 2", requests.Load())
+		}
+	})
+
+	t.Run("unsafe POST", func(t *testing.T) {
+		server, requests := startUnavailable(t, 1, "")
+		retrying := NewClient(server.URL)
+		retrying.RetryWait = time.Millisecond
+		err := retrying.RunTableOperation(testContext(t), table.TableOperation{Name: "Contact", Operation: "CREATE"})
+		expectStatus(t, err, http.StatusServiceUnavailable)
+		if requests.Load() != 1 {
+			t.Errorf("a table operation was sent %v times", requests.Load())
+		}
+	})
+
+	t.Run("Retry-After 0 keeps the backoff", func(t *testing.T) {
+		server, _ := startUnavailable(t, 3, "0")
+		retrying := NewClient(server.URL)
+		retrying.RetryWait = 20 * time.Millisecond
+		start := time.Now()
+		if _, err := retrying.Contacts(testContext(t)); err != nil {
+			t.Fatal(err)
+		}
+		// 20ms, 40ms then 80ms between the attempts
+		if elapsed := time.Since(start); elapsed < 140*time.Millisecond {
+			t.Errorf("3 retries took %v, want at least the 140ms of backoff", elapsed)
+		}
+	})
+
+	t.Run("Retry-After doesn't reset the backoff", func(t *testing.T) {
+		server, _ := startUnavailable(t, 2, "1")
+		retrying := NewClient(server.URL)
+		retrying.RetryWait = 300 * time.Millisecond
+		start := time.Now()
+		if _, err := retrying.Contacts(testContext(t)); err != nil {
+			t.Fatal(err)
+		}
+		// 1s from the header both times, the 300ms and 600ms of backoff stay under it
+		if elapsed := time.Since(start); elapsed < 2*time.Second || elapsed > 4*time.Second {
+			t.Errorf("2 retries took %v, want about 2s", elapsed)
+		}
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"0", 0},
+		{"-5", 0},
+		{"120", 2 * time.Minute},
+		{"Fri, 01 Mar 2024 12:00:30 GMT", 30 * time.Second},
+		{"Friday, 01-Mar-24 12:01:00 GMT", time.Minute},
+		{"Fri, 01 Mar 2024 11:59:00 GMT", 0}, // Already past
+		{"soon", 0},
+	}
+	for _, test := range tests {
+		if got := parseRetryAfter(test.value, now); got != test.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", test.value, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"go_backend/routes/danger"
+	"go_backend/routes/userinfo"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Alerts Returns the alerts in states, pending and acknowledged ones when none are given
+func (c *Client) Alerts(ctx context.Context, states ...string) ([]danger.Alert, error) {
+	var alertList []danger.Alert
+	req := newRequest(http.MethodGet, "/danger")
+	for _, state := range states {
+		req.query.Add("State", state)
+	}
+	_, err := c.call(ctx, req, &alertList)
+	return alertList, err
+}
+
+// RaiseAlert Raises an alert for a helmet reading, returns true with the first alert when the reading was raised already
+func (c *Client) RaiseAlert(ctx context.Context, rawWorkInfo *userinfo.RawWorkerInfo) (danger.Alert, bool, error) {
+	var alert danger.Alert
+	req := newRequest(http.MethodPost, "/danger")
+	req.body = rawWorkInfo
+	idempotent(req, rawWorkInfo)
+	header, err := c.call(ctx, req, &alert)
+	if err != nil {
+		return alert, false, err
+	}
+	return alert, header.Get(userinfo.ReplayedHeader) == "true", nil
+}
+
+// AcknowledgeAlert Moves a pending alert to acknowledged, not retried as the second attempt would get 409
+func (c *Client) AcknowledgeAlert(ctx context.Context, id uint64, by string) (danger.Alert, error) {
+	return c.changeState(ctx, id, "ack", by)
+}
+
+// ResolveAlert Moves a pending or acknowledged alert to resolved, not retried as the second attempt would get 409
+func (c *Client) ResolveAlert(ctx context.Context, id uint64, by string) (danger.Alert, error) {
+	return c.changeState(ctx, id, "resolve", by)
+}
+
+func (c *Client) changeState(ctx context.Context, id uint64, action, by string) (danger.Alert, error) {
+	var alert danger.Alert
+	req := newRequest(http.MethodPost, "/danger/"+strconv.FormatUint(id, 10)+"/"+action)
+	req.body = danger.AlertAction{By: by}
+	_, err := c.call(ctx, req, &alert)
+	return alert, err
+}
+
+// StreamOptions filters StreamAlerts, empty fields match everything
+type StreamOptions struct {
+	Grounds     []string
+	DangerTypes []string
+	LastEventId *uint64 // Replays the alerts after this id first, nil for new alerts only
+}
+
+// StreamAlerts Calls handle with every new alert until ctx is done or handle returns an error
+// A dropped connection is opened again with the id of the last alert received, so none are missed
+// Returns ctx.Err() when ctx is done, the error of handle, or an APIError the server answered with
+func (c *Client) StreamAlerts(ctx context.Context, options StreamOptions, handle func(alert danger.Alert) error) error {
+	lastEventId := options.LastEventId
+	wait := c.RetryWait
+	for {
+		req := newRequest(http.MethodGet, "/danger/stream")
+		for _, ground := range options.Grounds {
+			req.query.Add("Ground", ground)
+		}
+		for _, dangerType := range options.DangerTypes {
+			req.query.Add("DangerType", dangerType)
+		}
+		if lastEventId != nil {
+			req.header.Set("Last-Event-ID", strconv.FormatUint(*lastEventId, 10))
+		}
+
+		resp, err := c.send(ctx, req)
+		if err == nil {
+			wait = c.RetryWait // Connected, start over with the short wait
+			err = readEvents(resp.Body, func(alert danger.Alert) error {
+				id := alert.Id
+				lastEventId = &id
+				return handle(alert)
+			})
+			resp.Body.Close()
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var streamErr *streamError
+		if errors.As(err, &streamErr) {
+			return streamErr.err // handle gave up, or an alert couldn't be decoded
+		}
+		if err != nil && !isRetryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		if wait *= 2; wait > MaxRetryWait {
+			wait = MaxRetryWait
+		}
+	}
+}
+
+// streamError wraps the error of the StreamAlerts handler, to tell it from a dropped connection
+type streamError struct {
+	err error
+}
+
+func (err *streamError) Error() string {
+	return err.err.Error()
+}
+
+// readEvents Parses Server-Sent Events until body ends, comments (keep-alives) and other event types are skipped
+func readEvents(body io.Reader, handle func(alert danger.Alert) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	event, data := "", ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if event == "danger" && data != "" {
+				alert := danger.Alert{}
+				if err := json.Unmarshal([]byte(data), &alert); err != nil {
+					return &streamError{err: err} // Reconnecting would replay it
+				}
+				if err := handle(alert); err != nil {
+					return &streamError{err: err}
+				}
+			}
+			event, data = "", ""
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			if data != "" {
+				data += "\n"
+			}
+			data += value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.ErrUnexpectedEOF // The server never ends a stream, the connection dropped
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"go_backend/routes/contacts"
+	"go_backend/routes/hospital"
+	"go_backend/routes/table"
+	"go_backend/util"
+	"net/http"
+)
+
+func (c *Client) Contacts(ctx context.Context) ([]contacts.Contact, error) {
+	var contactList []contacts.Contact
+	_, err := c.call(ctx, newRequest(http.MethodGet, "/contacts"), &contactList)
+	return contactList, err
+}
+
+// ContactPage Returns up to limit contacts (util.DefaultPageLimit when 0) after cursor, use with EachPage or All
+func (c *Client) ContactPage(ctx context.Context, limit int, cursor string) (util.Page[contacts.Contact], error) {
+	var page util.Page[contacts.Contact]
+	req := newRequest(http.MethodGet, "/contacts")
+	pageQuery(req, limit, cursor)
+	_, err := c.call(ctx, req, &page)
+	return page, err
+}
+
+// InsertContact Adds a contact or replaces the one with the same PhoneNumber
+func (c *Client) InsertContact(ctx context.Context, contact *contacts.Contact) error {
+	req := newRequest(http.MethodPost, "/contacts")
+	req.body = contact
+	req.retry = true // Keyed by PhoneNumber, sending it twice stores it once
+	_, err := c.call(ctx, req, nil)
+	return err
+}
+
+func (c *Client) DeleteContact(ctx context.Context, phoneNumber string) error {
+	req := newRequest(http.MethodDelete, "/contacts")
+	req.query.Set("PhoneNumber", phoneNumber)
+	_, err := c.call(ctx, req, nil)
+	return err
+}
+
+func (c *Client) Hospitals(ctx context.Context) ([]hospital.Hospital, error) {
+	var hospitalList []hospital.Hospital
+	_, err := c.call(ctx, newRequest(http.MethodGet, "/hospital"), &hospitalList)
+	return hospitalList, err
+}
+
+// HospitalPage Returns up to limit hospitals (util.DefaultPageLimit when 0) after cursor, use with EachPage or All
+func (c *Client) HospitalPage(ctx context.Context, limit int, cursor string) (util.Page[hospital.Hospital], error) {
+	var page util.Page[hospital.Hospital]
+	req := newRequest(http.MethodGet, "/hospital")
+	pageQuery(req, limit, cursor)
+	_, err := c.call(ctx, req, &page)
+	return page, err
+}
+
+// InsertHospital Adds a hospital or replaces the one with the same PhoneNumber
+func (c *Client) InsertHospital(ctx context.Context, hospitalInfo *hospital.Hospital) error {
+	req := newRequest(http.MethodPost, "/hospital")
+	req.body = hospitalInfo
+	req.retry = true // Keyed by PhoneNumber, sending it twice stores it once
+	_, err := c.call(ctx, req, nil)
+	return err
+}
+
+func (c *Client) DeleteHospital(ctx context.Context, phoneNumber string) error {
+	req := newRequest(http.MethodDelete, "/hospital")
+	req.query.Set("PhoneNumber", phoneNumber)
+	_, err := c.call(ctx, req, nil)
+	return err
+}
+
+// RunTableOperation Creates, updates or deletes a DynamoDB table, answered with 501 unless the server uses dynamodb
+// Not retried, a create sent twice fails the second time
+func (c *Client) RunTableOperation(ctx context.Context, tableOps table.TableOperation) error {
+	req := newRequest(http.MethodPost, "/table")
+	req.body = tableOps
+	_, err := c.call(ctx, req, nil)
+	return err
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"go_backend/util"
+	"strconv"
+)
+
+// PageFunc Reads one page of a list endpoint, cursor is "" for the first page
+type PageFunc[T any] func(ctx context.Context, limit int, cursor string) (util.Page[T], error)
+
+// EachPage Calls handle with every page read follows until the last one or until handle returns an error
+func EachPage[T any](ctx context.Context, limit int, read PageFunc[T], handle func(items []T) error) error {
+	cursor := ""
+	for {
+		page, err := read(ctx, limit, cursor)
+		if err != nil {
+			return err
+		}
+		if err = handle(page.Items); err != nil {
+			return err
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// All Returns the items of every page, limit items per request
+func All[T any](ctx context.Context, limit int, read PageFunc[T]) ([]T, error) {
+	var items []T
+	err := EachPage(ctx, limit, read, func(page []T) error {
+		items = append(items, page...)
+		return nil
+	})
+	return items, err
+}
+
+// pageQuery Sets limit and cursor, the server answers with a page when either is set
+func pageQuery(req *request, limit int, cursor string) {
+	if limit == 0 {
+		limit = util.DefaultPageLimit
+	}
+	req.query.Set("limit", strconv.Itoa(limit))
+	if cursor != "" {
+		req.query.Set("cursor", cursor)
+	}
+}
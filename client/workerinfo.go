@@ -0,0 +1,178 @@
+package client
+
+import (
+	"context"
+	"go_backend/routes/userinfo"
+	"go_backend/util"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ListOptions selects the workers of ListWorkerInfo, Range or both Sdate and Edate are required
+type ListOptions struct {
+	Sdate       string
+	Edate       string
+	Range       string // today, yesterday or lastNd, wins over Sdate and Edate
+	Ground      string
+	MinScore    *int
+	Risks       []string // low, low-medium, medium or high
+	SortByScore bool     // Highest score first, not available with pages
+}
+
+func (options *ListOptions) query(req *request) {
+	setQuery(req.query, "sdate", options.Sdate)
+	setQuery(req.query, "edate", options.Edate)
+	setQuery(req.query, "range", options.Range)
+	setQuery(req.query, "ground", options.Ground)
+	if options.MinScore != nil {
+		req.query.Set("minScore", strconv.Itoa(*options.MinScore))
+	}
+	for _, risk := range options.Risks {
+		req.query.Add("risk", risk)
+	}
+	if options.SortByScore {
+		req.query.Set("sort", "score")
+	}
+}
+
+func setQuery(query url.Values, name, value string) {
+	if value != "" {
+		query.Set(name, value)
+	}
+}
+
+func readingsQuery(req *request, query *userinfo.ReadingsQuery) {
+	setQuery(req.query, "from", query.From)
+	setQuery(req.query, "to", query.To)
+	setQuery(req.query, "range", query.Range)
+}
+
+// WorkerInfo Returns the latest reading of a worker
+func (c *Client) WorkerInfo(ctx context.Context, id string) (userinfo.WorkerInfo, error) {
+	var workerInfo userinfo.WorkerInfo
+	req := newRequest(http.MethodGet, "/userinfo")
+	req.query.Set("Id", id)
+	_, err := c.call(ctx, req, &workerInfo)
+	return workerInfo, err
+}
+
+// ListWorkerInfo Returns the latest reading of every worker options selects
+func (c *Client) ListWorkerInfo(ctx context.Context, options ListOptions) ([]userinfo.WorkerInfo, error) {
+	var workerInfoList []userinfo.WorkerInfo
+	req := newRequest(http.MethodGet, "/userinfo")
+	options.query(req)
+	_, err := c.call(ctx, req, &workerInfoList)
+	return workerInfoList, err
+}
+
+// WorkerInfoPage Returns one page of ListWorkerInfo, filters apply per page so it may hold fewer than limit workers
+func (c *Client) WorkerInfoPage(ctx context.Context, options ListOptions, limit int, cursor string) (util.Page[userinfo.WorkerInfo], error) {
+	var page util.Page[userinfo.WorkerInfo]
+	req := newRequest(http.MethodGet, "/userinfo")
+	options.query(req)
+	pageQuery(req, limit, cursor)
+	_, err := c.call(ctx, req, &page)
+	return page, err
+}
+
+// WorkerInfoPages Returns the PageFunc of WorkerInfoPage for options, for EachPage and All
+func (c *Client) WorkerInfoPages(options ListOptions) PageFunc[userinfo.WorkerInfo] {
+	return func(ctx context.Context, limit int, cursor string) (util.Page[userinfo.WorkerInfo], error) {
+		return c.WorkerInfoPage(ctx, options, limit, cursor)
+	}
+}
+
+// InsertWorkerInfo Stores a helmet reading, returns true when the server had it already (a replayed retry)
+// A reading without Sequence or IdempotencyKey is sent with a new Idempotency-Key, so it is safe to retry
+func (c *Client) InsertWorkerInfo(ctx context.Context, rawWorkInfo *userinfo.RawWorkerInfo) (bool, error) {
+	req := newRequest(http.MethodPost, "/userinfo")
+	req.body = rawWorkInfo
+	idempotent(req, rawWorkInfo)
+	header, err := c.call(ctx, req, nil)
+	if err != nil {
+		return false, err
+	}
+	return header.Get(userinfo.ReplayedHeader) == "true", nil
+}
+
+// idempotent Lets a reading POST be retried, the server stores it once per dedup key
+func idempotent(req *request, rawWorkInfo *userinfo.RawWorkerInfo) {
+	if rawWorkInfo.DedupKey() == "" {
+		req.header.Set(userinfo.IdempotencyHeader, NewIdempotencyKey())
+	}
+	req.retry = true
+}
+
+// UpdateWorkerInfo Replaces a worker's latest reading, EarlyWarningScore is computed again by the server
+func (c *Client) UpdateWorkerInfo(ctx context.Context, workerInfo *userinfo.WorkerInfo) error {
+	req := newRequest(http.MethodPut, "/userinfo")
+	req.body = workerInfo
+	_, err := c.call(ctx, req, nil)
+	return err
+}
+
+func (c *Client) DeleteWorkerInfo(ctx context.Context, id string) error {
+	req := newRequest(http.MethodDelete, "/userinfo")
+	req.query.Set("Id", id)
+	_, err := c.call(ctx, req, nil)
+	return err
+}
+
+// InsertBatch Stores up to userinfo.MaxBatchItems readings, each accepted, rejected or reported duplicate on its own
+// Retried only when every reading has a Sequence or IdempotencyKey
+func (c *Client) InsertBatch(ctx context.Context, rawWorkInfoList []userinfo.RawWorkerInfo) (userinfo.BatchReport, error) {
+	var report userinfo.BatchReport
+	req := newRequest(http.MethodPost, "/userinfo/batch")
+	req.body = rawWorkInfoList
+	req.retry = true
+	for i := range rawWorkInfoList {
+		if rawWorkInfoList[i].DedupKey() == "" {
+			req.retry = false
+			break
+		}
+	}
+	_, err := c.call(ctx, req, &report)
+	return report, err
+}
+
+// Gaps Returns the missing sequence numbers of a helmet, of every helmet when id is ""
+func (c *Client) Gaps(ctx context.Context, id string) ([]userinfo.Gap, error) {
+	var gaps []userinfo.Gap
+	req := newRequest(http.MethodGet, "/userinfo/gaps")
+	setQuery(req.query, "Id", id)
+	_, err := c.call(ctx, req, &gaps)
+	return gaps, err
+}
+
+// Readings Returns the raw readings of a worker, the last hour when query is empty
+func (c *Client) Readings(ctx context.Context, id string, query userinfo.ReadingsQuery) ([]userinfo.WorkerInfo, error) {
+	var readings []userinfo.WorkerInfo
+	req := newRequest(http.MethodGet, "/userinfo/"+url.PathEscape(id)+"/readings")
+	readingsQuery(req, &query)
+	_, err := c.call(ctx, req, &readings)
+	return readings, err
+}
+
+// AggregateReadings Returns per bucket statistics of a worker's readings, bucket is 1m, 5m or 1h ("" for 5m)
+func (c *Client) AggregateReadings(ctx context.Context, id, bucket string, query userinfo.ReadingsQuery) ([]userinfo.Bucket, error) {
+	var buckets []userinfo.Bucket
+	req := newRequest(http.MethodGet, "/userinfo/"+url.PathEscape(id)+"/readings/aggregate")
+	setQuery(req.query, "bucket", bucket)
+	readingsQuery(req, &query)
+	_, err := c.call(ctx, req, &buckets)
+	return buckets, err
+}
+
+// DownsampleReadings Returns at most points points per field (0 for the server default), of every vital sign when field is ""
+func (c *Client) DownsampleReadings(ctx context.Context, id string, points int, field string, query userinfo.ReadingsQuery) (map[string][]userinfo.Point, error) {
+	var series map[string][]userinfo.Point
+	req := newRequest(http.MethodGet, "/userinfo/"+url.PathEscape(id)+"/readings/downsample")
+	if points > 0 {
+		req.query.Set("points", strconv.Itoa(points))
+	}
+	setQuery(req.query, "field", field)
+	readingsQuery(req, &query)
+	_, err := c.call(ctx, req, &series)
+	return series, err
+}
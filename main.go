@@ -2,12 +2,14 @@ package main
 
 import (
 	"fmt"
-	"go_backend/routes/about"
-	"go_backend/routes/contacts"
+	"go_backend/routes"
 	"go_backend/routes/danger"
-	"go_backend/routes/hospital"
-	"go_backend/routes/index"
-	"go_backend/routes/table"
+	"go_backend/routes/edgesync"
+	"go_backend/routes/exposure"
+	"go_backend/routes/mqttingest"
+	"go_backend/routes/rpc"
+	"go_backend/routes/rules"
+	"go_backend/routes/udpingest"
 	"go_backend/routes/userinfo"
 	"log"
 	"net/http"
@@ -30,10 +32,18 @@ var rootServer *http.Server
 func InitializeServerComponents() {
 	quitServer = make(chan os.Signal, 1)
 	quitSignal = make(chan struct{}, 1)
+
+	userinfo.AddIngestHook(rules.OnReading)    // Vital-sign rules raise danger alerts
+	userinfo.AddIngestHook(exposure.OnReading) // Gas TWA / STEL limits raise danger alerts
+	danger.GetQueue()                          // Opens LOCAL_DB_FILE now rather than on the first alert
+	rules.GetEngine()                          // An invalid RULES_FILE exits now rather than on the first reading
+	userinfo.GetStore()                        // Connects to STORAGE_BACKEND now rather than on the first request
+	util.GetGroundLocation("")                 // An invalid GROUND_TIME_ZONES exits now rather than on a request
 }
 
 func CreateServer(pLog *log.Logger, eLogger *log.Logger) {
-	serverEngine = gin.Default()
+	serverEngine = gin.New()
+	serverEngine.Use(gin.Logger()) // Recovery comes with the routes, see routes.Attach
 
 	pLog.Println("Server Created")
 
@@ -51,28 +61,7 @@ func CreateServer(pLog *log.Logger, eLogger *log.Logger) {
 }
 
 func InitializeGinEngine() {
-	serverEngine.GET("/", index.Get)
-	serverEngine.GET("/about", about.Get)
-	serverEngine.POST("/about", about.Post)
-
-	serverEngine.GET("/contacts", contacts.Get)
-	serverEngine.POST("/contacts", contacts.Post)
-	serverEngine.DELETE("/contacts", contacts.Delete)
-
-	serverEngine.GET("/danger", danger.Get)
-	serverEngine.POST("/danger", danger.Post)
-
-	serverEngine.GET("/hospital", hospital.Get)
-	serverEngine.POST("/hospital", hospital.Post)
-	serverEngine.DELETE("/hospital", hospital.Delete)
-
-	serverEngine.GET("/table", table.Get)
-	serverEngine.POST("/table", table.Post)
-
-	serverEngine.GET("/userinfo", userinfo.Get)
-	serverEngine.POST("/userinfo", userinfo.Post)
-	serverEngine.PUT("/userinfo", userinfo.Update)
-	serverEngine.DELETE("/userinfo", userinfo.Delete)
+	routes.Attach(serverEngine)
 }
 
 func StartServer(pLog *log.Logger, eLog *log.Logger) {
@@ -107,6 +96,7 @@ func WatchCloseOS(pLog *log.Logger, eLog *log.Logger) {
 }
 
 func main() {
+	util.RequireConfig()
 	pLogger := util.GetLogger()
 	eLogger := util.GetErrorLogger()
 
@@ -114,6 +104,18 @@ func main() {
 	CreateServer(pLogger, eLogger) // Creates server , server gin engine , initializes close channels
 	InitializeGinEngine()          // Attaches routes to server gin engine
 	go StartServer(pLogger, eLogger)
+	if util.GetStorageBackend() == util.StorageEdge {
+		go edgesync.Start(pLogger, eLogger) // Forwards locally written readings to DynamoDB
+	}
+	if util.GetMQTTBroker() != "" {
+		mqttingest.Start(pLogger, eLogger) // Subscribes to helmet gateways
+	}
+	if util.GetUDPListenAddr() != "" {
+		go udpingest.Start(pLogger, eLogger) // Receives binary frames from radio gateways
+	}
+	if util.GetGRPCListenAddr() != "" {
+		go rpc.Start(pLogger, eLogger) // Serves the gRPC API next to the REST routes
+	}
 	go WatchCloseManually(pLogger, eLogger)
 	go WatchCloseManually(pLogger, eLogger)
 
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"go_backend/util"
-	"log"
 	"net/http"
 	"time"
 )
@@ -25,12 +24,6 @@ func init() {
 	about = CreateAbout()
 }
 
-func CheckError(err error) {
-	if err != nil {
-		log.Fatalln(err.Error())
-	}
-}
-
 func CreateAbout() About {
 	return About{Name: "Smlr", CreatedDate: fmt.Sprintf("%v", civil.DateOf(time.Now())), Creators: []string{
 		"Nishanth", "Swami", "Venki", "Chirag", " Arpit", "Ano-1", "Ano-2",
@@ -45,6 +38,10 @@ func Get(ctx *gin.Context) {
 
 func Post(ctx *gin.Context) {
 	util.DebugPrint(FILENAME, "POST", "Just Test")
-	err := ctx.BindJSON(&about)
-	CheckError(err)
+	update := about
+	if err := ctx.ShouldBindJSON(&update); err != nil {
+		util.WriteError(ctx, http.StatusBadRequest, "Body must be a JSON About")
+		return
+	}
+	about = update
 }
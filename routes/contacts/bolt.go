@@ -0,0 +1,96 @@
+package contacts
+
+import (
+	"encoding/json"
+	"errors"
+	"go_backend/util"
+	"log"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltContactStore keeps Contacts in the embedded on-disk database, keyed by PhoneNumber
+type BoltContactStore struct {
+	DB         *bolt.DB
+	BucketName string
+}
+
+func NewBoltContactStore(db *bolt.DB) *BoltContactStore {
+	util.CreateBucket(db, TABLENAME)
+	return &BoltContactStore{DB: db, BucketName: TABLENAME}
+}
+
+func (store *BoltContactStore) GetAllContactInfo() ([]Contact, error) {
+	var contactList []Contact
+	err := store.DB.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(store.BucketName)).ForEach(func(key, value []byte) error {
+			contact := Contact{}
+			if err := json.Unmarshal(value, &contact); err != nil {
+				return err
+			}
+			contactList = append(contactList, contact)
+			return nil
+		})
+	})
+	if err != nil {
+		log.Printf("Couldn't read contacts. Here's why: %v\n", err)
+	}
+	return contactList, err
+}
+
+// GetContactPage Walks the bucket in PhoneNumber order starting after the PhoneNumber after
+func (store *BoltContactStore) GetContactPage(after string, limit int) ([]Contact, string, error) {
+	var contactList []Contact
+	next := ""
+	err := store.DB.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket([]byte(store.BucketName)).Cursor()
+		key, value := cursor.Seek([]byte(after))
+		if key != nil && string(key) == after {
+			key, value = cursor.Next()
+		}
+		for ; key != nil; key, value = cursor.Next() {
+			if limit > 0 && len(contactList) == limit {
+				next = contactList[limit-1].PhoneNumber
+				return nil
+			}
+			item := Contact{}
+			if err := json.Unmarshal(value, &item); err != nil {
+				return err
+			}
+			contactList = append(contactList, item)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Couldn't read a page of contacts. Here's why: %v\n", err)
+	}
+	return contactList, next, err
+}
+
+func (store *BoltContactStore) InsertContact(contact *Contact) error {
+	item, err := json.Marshal(contact)
+	if err != nil {
+		return err
+	}
+	err = store.DB.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(store.BucketName)).Put([]byte(contact.PhoneNumber), item)
+	})
+	if err != nil {
+		log.Printf("Couldn't add item to local database. Reason => %v\n", err)
+	}
+	return err
+}
+
+func (store *BoltContactStore) DeleteContact(info *Contact) error {
+	err := store.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(store.BucketName))
+		if bucket.Get([]byte(info.PhoneNumber)) == nil {
+			return errNoContact(info)
+		}
+		return bucket.Delete([]byte(info.PhoneNumber))
+	})
+	if err != nil && !errors.Is(err, util.ErrNotFound) {
+		log.Printf("Couldn't delete %v from the local database. Here's why: %v\n", info, err)
+	}
+	return err
+}
@@ -0,0 +1,125 @@
+package contacts
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"go_backend/util"
+	"log"
+)
+
+// TClientUserInfo is the DynamoDB backed ContactStore
+type TClientUserInfo struct {
+	DynamoDbClient *dynamodb.Client
+	TableName      string
+}
+
+func NewTClientUserInfo() *TClientUserInfo {
+	return &TClientUserInfo{DynamoDbClient: GetClientFromEnv(), TableName: TABLENAME}
+}
+
+func GetClientFromEnv() *dynamodb.Client {
+	cfg, err := config.LoadDefaultConfig(context.Background(), func(l *config.LoadOptions) error {
+		return nil
+	})
+
+	if err != nil {
+		log.Fatalf("Couldn't load the AWS config. Reason => %v\n", err)
+	}
+
+	client := dynamodb.NewFromConfig(cfg)
+	return client
+}
+
+// GetAllContactInfo Reads every page of the table
+func (tClient *TClientUserInfo) GetAllContactInfo() ([]Contact, error) {
+	var contactList []Contact
+	after := ""
+	for {
+		page, next, err := tClient.GetContactPage(after, 0)
+		contactList = append(contactList, page...)
+		if err != nil || next == "" {
+			return contactList, err
+		}
+		after = next
+	}
+}
+
+// GetContactPage Scans one page of up to limit items, the store cursor is the encoded LastEvaluatedKey
+func (tClient *TClientUserInfo) GetContactPage(after string, limit int) ([]Contact, string, error) {
+	var contactList []Contact
+	projEx := expression.NamesList(
+		expression.Name("Name"), expression.Name("PhoneNumber"), expression.Name("Specification"))
+	expr, err := expression.NewBuilder().WithProjection(projEx).Build()
+	if err != nil {
+		log.Printf("Couldn't build expressions for scan. Here's why: %v\n", err)
+		return contactList, "", err
+	}
+	startKey, err := util.DecodeKey(after)
+	if err != nil {
+		log.Printf("Couldn't decode cursor %v. Here's why: %v\n", after, err)
+		return contactList, "", err
+	}
+	input := &dynamodb.ScanInput{
+		TableName:                 aws.String(tClient.TableName),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ProjectionExpression:      expr.Projection(),
+		ExclusiveStartKey:         startKey,
+	}
+	if limit > 0 {
+		input.Limit = aws.Int32(int32(limit))
+	}
+	response, err := tClient.DynamoDbClient.Scan(context.Background(), input)
+	if err != nil {
+		log.Printf("Couldn't scan %v. Here's why: %v\n", tClient.TableName, err)
+		return contactList, "", util.ClassifyStoreError(err)
+	}
+	err = attributevalue.UnmarshalListOfMaps(response.Items, &contactList)
+	if err != nil {
+		log.Printf("Couldn't unmarshal query response. Here's why: %v\n", err)
+		return contactList, "", err
+	}
+	return contactList, util.EncodeKey(response.LastEvaluatedKey), nil
+}
+
+// DeleteContact Deletes the item only when it exists, so a wrong PhoneNumber is reported
+func (tClient *TClientUserInfo) DeleteContact(info *Contact) error {
+	expr, err := expression.NewBuilder().WithCondition(expression.AttributeExists(expression.Name("Id"))).Build()
+	if err != nil {
+		return err
+	}
+	_, err = tClient.DynamoDbClient.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
+		TableName:                aws.String(tClient.TableName),
+		Key:                      info.GetKey(),
+		ConditionExpression:      expr.Condition(),
+		ExpressionAttributeNames: expr.Names(),
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return errNoContact(info)
+	}
+	if err != nil {
+		log.Printf("Couldn't delete %v from the table. Here's why: %v\n", info, err)
+	}
+	return util.ClassifyStoreError(err)
+}
+
+func (tClient *TClientUserInfo) InsertContact(workerInfo *Contact) error {
+	item, err := attributevalue.MarshalMap(workerInfo)
+	if err != nil {
+		return err
+	}
+	_, err = tClient.DynamoDbClient.PutItem(context.Background(), &dynamodb.PutItemInput{
+		TableName: aws.String(tClient.TableName), Item: item,
+	})
+	if err != nil {
+		log.Printf("Couldn't add item to table. Reason => %v\n", err)
+	}
+	return util.ClassifyStoreError(err)
+}
@@ -1,51 +1,18 @@
 package contacts
 
 import (
-	"context"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/gin-gonic/gin"
 	"go_backend/util"
-	"log"
 	"net/http"
+	"sync"
 )
 
 const FILENAME = "contact/index.go"
 const TABLENAME string = "Contact"
 
-var tClient *TClientUserInfo
-
-type TClientUserInfo struct {
-	DynamoDbClient *dynamodb.Client
-	TableName      string
-}
-
-func init() {
-	tClient = &TClientUserInfo{}
-	tClient.TableName = TABLENAME
-	tClient.DynamoDbClient = GetClientFromEnv()
-}
-
-func GetClientFromEnv() *dynamodb.Client {
-	cfg, err := config.LoadDefaultConfig(context.Background(), func(l *config.LoadOptions) error {
-		return nil
-	})
-
-	CheckError(err)
-
-	client := dynamodb.NewFromConfig(cfg)
-	return client
-}
-
-func CheckError(err error) {
-	if err != nil {
-		log.Fatalln(err.Error())
-	}
-}
+var tClient ContactStore
+var tClientOnce sync.Once
 
 type Contact struct {
 	Name          string // Secondary Key
@@ -57,79 +24,41 @@ func (ctt *Contact) GetKey() map[string]types.AttributeValue {
 	return map[string]types.AttributeValue{"Id": &types.AttributeValueMemberS{Value: ctt.PhoneNumber}}
 }
 
-func (tClient *TClientUserInfo) GetAllContactInfo() []Contact {
-	var contactList []Contact
-	var err error
-	var response *dynamodb.ScanOutput
-	projEx := expression.NamesList(
-		expression.Name("Name"), expression.Name("PhoneNumber"), expression.Name("Specification"))
-	expr, err := expression.NewBuilder().WithProjection(projEx).Build()
-	if err != nil {
-		log.Printf("Couldn't build expressions for scan. Here's why: %v\n", err)
-	} else {
-		response, err = tClient.DynamoDbClient.Scan(context.Background(), &dynamodb.ScanInput{
-			TableName:                 aws.String(tClient.TableName),
-			ExpressionAttributeNames:  expr.Names(),
-			ExpressionAttributeValues: expr.Values(),
-			FilterExpression:          expr.Filter(),
-			ProjectionExpression:      expr.Projection(),
-		})
-		if err != nil {
-			CheckError(err)
-		} else {
-			err = attributevalue.UnmarshalListOfMaps(response.Items, &contactList)
-			if err != nil {
-				log.Printf("Couldn't unmarshal query response. Here's why: %v\n", err)
-			}
-		}
-	}
-	return contactList
-}
-
-func (tClient *TClientUserInfo) DeleteContact(info *Contact) error {
-	_, err := tClient.DynamoDbClient.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
-		TableName: aws.String(tClient.TableName), Key: info.GetKey(),
-	})
-	if err != nil {
-		log.Printf("Couldn't delete %v from the table. Here's why: %v\n", info, err)
+// Get Returns every entry, or pages of them with limit and cursor (format=ndjson streams them all)
+func Get(ctx *gin.Context) {
+	util.DebugPrint(FILENAME, "GET", "Just Test")
+	if util.IsPaged(ctx) {
+		util.WritePage(ctx, "contacts", GetStore().GetContactPage)
+		return
 	}
-	return err
-}
-
-func (tClient *TClientUserInfo) InsertContact(workerInfo *Contact) error {
-	item, err := attributevalue.MarshalMap(workerInfo)
-	CheckError(err)
-	_, err = tClient.DynamoDbClient.PutItem(context.Background(), &dynamodb.PutItemInput{
-		TableName: aws.String(tClient.TableName), Item: item,
-	})
+	contactList, err := GetStore().GetAllContactInfo()
 	if err != nil {
-		log.Printf("Couldn't add item to table. Reason => %v\n", err)
+		util.WriteStoreError(ctx, err)
+		return
 	}
-	return err
-}
-
-func Get(ctx *gin.Context) {
-	util.DebugPrint(FILENAME, "GET", "Just Test")
-	contactList := tClient.GetAllContactInfo()
 	ctx.JSON(http.StatusOK, contactList)
 }
 
 func Post(ctx *gin.Context) {
 	util.DebugPrint(FILENAME, "POST", "Just Test")
 	contact := Contact{}
-	err := ctx.BindJSON(contact)
-	CheckError(err)
-	err = tClient.InsertContact(&contact)
-	CheckError(err)
+	if err := ctx.ShouldBindJSON(&contact); err != nil {
+		util.WriteError(ctx, http.StatusBadRequest, "Body must be a JSON contact")
+		return
+	}
+	if err := GetStore().InsertContact(&contact); err != nil {
+		util.WriteStoreError(ctx, err)
+	}
 }
 
 func Delete(ctx *gin.Context) {
 	id, isFound := ctx.GetQuery("PhoneNumber")
 
 	if isFound {
-		err := tClient.DeleteContact(&Contact{PhoneNumber: id})
-		CheckError(err)
+		if err := GetStore().DeleteContact(&Contact{PhoneNumber: id}); err != nil {
+			util.WriteStoreError(ctx, err)
+		}
 	} else {
-		ctx.String(http.StatusBadRequest, "PhoneNumber not provided")
+		util.WriteError(ctx, http.StatusBadRequest, "PhoneNumber not provided")
 	}
 }
@@ -0,0 +1,58 @@
+package contacts
+
+import (
+	"go_backend/util"
+	"sort"
+	"sync"
+)
+
+// MemoryContactStore keeps Contacts in process memory, Contents are lost on restart
+type MemoryContactStore struct {
+	mu    sync.RWMutex
+	items map[string]Contact
+}
+
+func NewMemoryContactStore() *MemoryContactStore {
+	return &MemoryContactStore{items: make(map[string]Contact)}
+}
+
+func (store *MemoryContactStore) GetAllContactInfo() ([]Contact, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	var contactList []Contact
+	for _, contact := range store.items {
+		contactList = append(contactList, contact)
+	}
+	sort.Slice(contactList, func(i, j int) bool {
+		return contactList[i].PhoneNumber < contactList[j].PhoneNumber
+	})
+	return contactList, nil
+}
+
+func (store *MemoryContactStore) GetContactPage(after string, limit int) ([]Contact, string, error) {
+	contactList, _ := store.GetAllContactInfo()
+	page, next := util.PageSorted(contactList, func(item *Contact) string {
+		return item.PhoneNumber
+	}, after, limit)
+	return page, next, nil
+}
+
+func (store *MemoryContactStore) InsertContact(contact *Contact) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.items[contact.PhoneNumber] = *contact
+	return nil
+}
+
+func (store *MemoryContactStore) DeleteContact(info *Contact) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, isFound := store.items[info.PhoneNumber]; !isFound {
+		return errNoContact(info)
+	}
+	delete(store.items, info.PhoneNumber)
+	return nil
+}
@@ -0,0 +1,44 @@
+package contacts
+
+import (
+	"go_backend/util"
+	"log"
+)
+
+// ContactStore is implemented by every storage backend able to hold Contacts
+// Contacts are keyed by PhoneNumber
+type ContactStore interface {
+	GetAllContactInfo() ([]Contact, error)
+	GetContactPage(after string, limit int) ([]Contact, string, error) // Ordered by the store, see util.PageReader
+	InsertContact(contact *Contact) error
+	DeleteContact(info *Contact) error // util.ErrNotFound when no contact has the PhoneNumber
+}
+
+// NewContactStore Returns the store for the backend named in config.env (STORAGE_BACKEND)
+func NewContactStore(backend string) ContactStore {
+	switch backend {
+	case util.StorageMemory:
+		return NewMemoryContactStore()
+	case util.StorageBolt, util.StorageEdge:
+		return NewBoltContactStore(util.GetLocalDB())
+	case util.StorageDynamoDB:
+		return NewTClientUserInfo()
+	default:
+		log.Fatalf("Unknown storage backend %v\n", backend)
+	}
+	return nil
+}
+
+// errNoContact Is returned by DeleteContact when nothing is stored under info's PhoneNumber
+func errNoContact(info *Contact) error {
+	return util.NewStoreError(util.ErrNotFound, "No contact with PhoneNumber "+info.PhoneNumber)
+}
+
+// GetStore Returns the store used by the route handlers
+// Built from STORAGE_BACKEND on first use, so programs importing only the types (the client package) don't connect to a store
+func GetStore() ContactStore {
+	tClientOnce.Do(func() {
+		tClient = NewContactStore(util.GetStorageBackend())
+	})
+	return tClient
+}
@@ -1,37 +1,164 @@
 package danger
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"go_backend/routes/userinfo"
+	"go_backend/telemetry"
 	"go_backend/util"
-	"log"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 )
 
 const FILENAME = "danger/index.go"
 
-func CheckError(err error) {
-	if err != nil {
-		log.Fatalln(err)
-	}
-}
+// Danger types, SOS and Water come from the helmet, the rest are raised by the rules engine
+const (
+	DangerSOS         = "SOS"
+	DangerWater       = "Water"
+	DangerHypoxia     = "Hypoxia"
+	DangerGasExposure = "GasExposure"
+	DangerHeatStress  = "HeatStress"
+	DangerTachycardia = "Tachycardia"
+	DangerBradycardia = "Bradycardia"
+	DangerGasTWA      = "GasTWA"  // 8 hour time weighted average limit exceeded
+	DangerGasSTEL     = "GasSTEL" // 15 minute short term exposure limit exceeded
+)
 
-var workerInfoList []*userinfo.WorkerInfo
+var alertQueue *AlertQueue
+var alertQueueOnce sync.Once
+var broker *Broker
+var postDedup *userinfo.Deduplicator // Alerts already raised for a retried Post, replayed instead of paging twice
 
 func init() {
-	workerInfoList = make([]*userinfo.WorkerInfo, 10)
+	broker = NewBroker()
+	postDedup = userinfo.NewDeduplicator(util.GetDedupWindow())
 }
 
+// GetQueue Returns the alert queue used by the route handlers
+// Opened on first use, so programs importing only the types (the client package) don't open LOCAL_DB_FILE
+func GetQueue() *AlertQueue {
+	alertQueueOnce.Do(func() {
+		alertQueue = NewAlertQueue(util.GetLocalDB())
+	})
+	return alertQueue
+}
+
+// Raise Queues a danger alert for a worker reading and pushes it to stream clients
+func Raise(workerInfo *userinfo.WorkerInfo) (Alert, error) {
+	return GetQueue().Push(workerInfo, broker.Publish)
+}
+
+// Get Lists alerts without removing them, State query param filters (pending, acknowledged, resolved)
+// By default every alert that isn't resolved is returned
 func Get(ctx *gin.Context) {
 	util.DebugPrint(FILENAME, "GET", "Just Test")
-	ctx.JSON(http.StatusOK, workerInfoList)
-	workerInfoList = workerInfoList[:0]
+	states := ctx.QueryArray("State")
+	if len(states) == 0 {
+		states = []string{StatePending, StateAcknowledged}
+	}
+
+	alertList, err := GetQueue().List(states...)
+	if err != nil {
+		util.WriteStoreError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, alertList)
 }
 
 func Post(ctx *gin.Context) {
 	util.DebugPrint(FILENAME, "POST", "Just Test")
-	rawWorkInfo := userinfo.RawWorkerInfo{}
-	err := ctx.BindJSON(&rawWorkInfo)
-	CheckError(err)
-	workerInfoList = append(workerInfoList, rawWorkInfo.ConvertToWorkInfo())
+	rawWorkInfo := &userinfo.RawWorkerInfo{}
+	var err error
+	if userinfo.IsFrame(ctx) {
+		if rawWorkInfo, err = userinfo.ReadFrame(ctx, telemetry.KindDanger); err != nil {
+			util.WriteError(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+	} else if err = ctx.ShouldBindJSON(rawWorkInfo); err != nil {
+		util.WriteError(ctx, http.StatusBadRequest, "Body must be a JSON reading")
+		return
+	}
+	rawWorkInfo.ReadIdempotencyHeader(ctx)
+
+	alert, isNew, err := RaiseReading(rawWorkInfo)
+	switch {
+	case errors.Is(err, ErrInProgress):
+		util.WriteError(ctx, http.StatusConflict, err.Error())
+	case err != nil:
+		util.WriteStoreError(ctx, err)
+	case !isNew:
+		ctx.Header(userinfo.ReplayedHeader, "true")
+		ctx.JSON(http.StatusOK, alert)
+	default:
+		ctx.JSON(http.StatusCreated, alert)
+	}
+}
+
+// ErrInProgress is returned for a retried reading while the first one is still being raised
+var ErrInProgress = errors.New("the first request with this key is still being processed")
+
+// RaiseReading Raises the alert of a helmet reading and hands the reading to the ingest hooks,
+// shared by POST /danger and the MQTT subscriber. A retried reading returns the alert raised the first time and false
+func RaiseReading(rawWorkInfo *userinfo.RawWorkerInfo) (Alert, bool, error) {
+	dedupKey := rawWorkInfo.DedupKey()
+	if previous, isNew := postDedup.Claim(dedupKey, time.Now()); !isNew {
+		if previous == nil {
+			return Alert{}, false, ErrInProgress
+		}
+		return previous.(Alert), false, nil
+	}
+
+	workInfo := rawWorkInfo.ConvertToWorkInfo()
+	alert, err := Raise(workInfo)
+	if err != nil {
+		postDedup.Release(dedupKey)
+		return alert, true, err
+	}
+	postDedup.Complete(dedupKey, alert)
+	userinfo.TrackSequence(workInfo, time.Now())
+	userinfo.RunIngestHooks(workInfo, workInfo.MeasuredTime())
+	return alert, true, nil
+}
+
+// AlertAction is the body of /danger/:id/ack and /danger/:id/resolve
+type AlertAction struct {
+	By string // Who acknowledged or resolved the alert
+}
+
+func Ack(ctx *gin.Context) {
+	util.DebugPrint(FILENAME, "ACK", "Just Test")
+	changeState(ctx, GetQueue().Acknowledge)
+}
+
+func Resolve(ctx *gin.Context) {
+	util.DebugPrint(FILENAME, "RESOLVE", "Just Test")
+	changeState(ctx, GetQueue().Resolve)
+}
+
+func changeState(ctx *gin.Context, operation func(id uint64, by string) (Alert, error)) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		util.WriteError(ctx, http.StatusBadRequest, "Invalid alert id")
+		return
+	}
+	action := AlertAction{}
+	if err = ctx.ShouldBindJSON(&action); err != nil || action.By == "" {
+		util.WriteError(ctx, http.StatusBadRequest, "By not provided")
+		return
+	}
+
+	alert, err := operation(id, action.By)
+	switch {
+	case errors.Is(err, ErrAlertNotFound):
+		util.WriteError(ctx, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrInvalidTransition):
+		util.WriteError(ctx, http.StatusConflict, err.Error())
+	case err != nil:
+		util.WriteStoreError(ctx, err)
+	default:
+		ctx.JSON(http.StatusOK, alert)
+	}
 }
@@ -0,0 +1,29 @@
+package danger
+
+import (
+	"go_backend/routes/userinfo"
+	"testing"
+	"time"
+)
+
+// An SOS sent again after the helmet rebooted reuses its sequence number, it must page again
+func TestRaiseReadingAfterHelmetReboot(t *testing.T) {
+	useTestQueue(t)
+	now := time.Now().UTC()
+	sos := func(measuredAt time.Time) *userinfo.RawWorkerInfo {
+		return &userinfo.RawWorkerInfo{GroundNumber: "6", HelmetNumber: "1", DangerType: DangerSOS, Sequence: 1,
+			MeasuredAt: measuredAt.Format(time.RFC3339)}
+	}
+
+	first, isNew, err := RaiseReading(sos(now.Add(-time.Minute)))
+	if err != nil || !isNew {
+		t.Fatalf("RaiseReading = %v, %v, %v", first, isNew, err)
+	}
+	if retried, isNew, err := RaiseReading(sos(now.Add(-time.Minute))); err != nil || isNew || retried.Id != first.Id {
+		t.Errorf("retried RaiseReading = %v, %v, %v, want alert %v replayed", retried, isNew, err, first.Id)
+	}
+	second, isNew, err := RaiseReading(sos(now))
+	if err != nil || !isNew || second.Id == first.Id {
+		t.Errorf("RaiseReading after the reboot = %v, %v, %v, want a new alert", second, isNew, err)
+	}
+}
@@ -0,0 +1,192 @@
+package danger
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"go_backend/routes/userinfo"
+	"go_backend/util"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const BUCKETNAME string = "DangerAlert"
+
+// Alert states, an alert only moves forward pending -> acknowledged -> resolved
+const (
+	StatePending      = "pending"
+	StateAcknowledged = "acknowledged"
+	StateResolved     = "resolved"
+)
+
+var ErrAlertNotFound = errors.New("alert not found")
+var ErrInvalidTransition = errors.New("alert can't move to that state")
+
+type Alert struct {
+	Id             uint64
+	State          string
+	WorkerInfo     userinfo.WorkerInfo
+	CreatedAt      time.Time
+	AcknowledgedBy string     `json:",omitempty"`
+	AcknowledgedAt *time.Time `json:",omitempty"`
+	ResolvedBy     string     `json:",omitempty"`
+	ResolvedAt     *time.Time `json:",omitempty"`
+}
+
+// AlertQueue keeps alerts in the embedded database so they survive restarts
+// Every operation runs in a bolt transaction, which also makes it safe to use from concurrent handlers
+type AlertQueue struct {
+	DB         *bolt.DB
+	BucketName string
+	pushMu     sync.Mutex // Held from assigning an Id until the alert is published, see Push
+}
+
+func NewAlertQueue(db *bolt.DB) *AlertQueue {
+	util.CreateBucket(db, BUCKETNAME)
+	return &AlertQueue{DB: db, BucketName: BUCKETNAME}
+}
+
+func alertKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+	return key
+}
+
+// Push Stores a new pending alert and returns it with its Id
+// publish (when not nil) gets the stored alert before the next Push can assign an Id, so alerts are
+// published in Id order and a stream skipping Ids it already sent never skips a new one
+func (queue *AlertQueue) Push(workerInfo *userinfo.WorkerInfo, publish func(Alert)) (Alert, error) {
+	queue.pushMu.Lock()
+	defer queue.pushMu.Unlock()
+
+	alert := Alert{State: StatePending, WorkerInfo: *workerInfo, CreatedAt: time.Now()}
+	err := queue.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(queue.BucketName))
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		alert.Id = id
+		item, err := json.Marshal(alert)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(alertKey(id), item)
+	})
+	if err == nil && publish != nil {
+		publish(alert)
+	}
+	return alert, err
+}
+
+// List Returns alerts in creation order, filtered by state when states are given
+func (queue *AlertQueue) List(states ...string) ([]Alert, error) {
+	alertList := []Alert{}
+	err := queue.DB.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(queue.BucketName)).ForEach(func(key, value []byte) error {
+			alert := Alert{}
+			if err := json.Unmarshal(value, &alert); err != nil {
+				return err
+			}
+			if len(states) == 0 || contains(states, alert.State) {
+				alertList = append(alertList, alert)
+			}
+			return nil
+		})
+	})
+	return alertList, err
+}
+
+// ListAfter Returns every alert created after the given Id, used to resume a stream
+func (queue *AlertQueue) ListAfter(id uint64) ([]Alert, error) {
+	var alertList []Alert
+	err := queue.DB.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket([]byte(queue.BucketName)).Cursor()
+		for key, value := cursor.Seek(alertKey(id + 1)); key != nil; key, value = cursor.Next() {
+			alert := Alert{}
+			if err := json.Unmarshal(value, &alert); err != nil {
+				return err
+			}
+			alertList = append(alertList, alert)
+		}
+		return nil
+	})
+	return alertList, err
+}
+
+func (queue *AlertQueue) Get(id uint64) (Alert, error) {
+	alert := Alert{}
+	err := queue.DB.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket([]byte(queue.BucketName)).Get(alertKey(id))
+		if value == nil {
+			return ErrAlertNotFound
+		}
+		return json.Unmarshal(value, &alert)
+	})
+	return alert, err
+}
+
+// Acknowledge Marks a pending alert as seen by someone
+func (queue *AlertQueue) Acknowledge(id uint64, by string) (Alert, error) {
+	return queue.transition(id, func(alert *Alert, now time.Time) error {
+		if alert.State != StatePending {
+			return ErrInvalidTransition
+		}
+		alert.State = StateAcknowledged
+		alert.AcknowledgedBy = by
+		alert.AcknowledgedAt = &now
+		return nil
+	})
+}
+
+// Resolve Closes an alert, pending alerts are acknowledged by the same person on the way
+func (queue *AlertQueue) Resolve(id uint64, by string) (Alert, error) {
+	return queue.transition(id, func(alert *Alert, now time.Time) error {
+		switch alert.State {
+		case StatePending:
+			alert.AcknowledgedBy = by
+			alert.AcknowledgedAt = &now
+		case StateAcknowledged:
+		default:
+			return ErrInvalidTransition
+		}
+		alert.State = StateResolved
+		alert.ResolvedBy = by
+		alert.ResolvedAt = &now
+		return nil
+	})
+}
+
+func (queue *AlertQueue) transition(id uint64, apply func(alert *Alert, now time.Time) error) (Alert, error) {
+	alert := Alert{}
+	err := queue.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(queue.BucketName))
+		value := bucket.Get(alertKey(id))
+		if value == nil {
+			return ErrAlertNotFound
+		}
+		if err := json.Unmarshal(value, &alert); err != nil {
+			return err
+		}
+		if err := apply(&alert, time.Now()); err != nil {
+			return err
+		}
+		item, err := json.Marshal(alert)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(alertKey(id), item)
+	})
+	return alert, err
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
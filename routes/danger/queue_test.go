@@ -0,0 +1,95 @@
+package danger
+
+import (
+	"errors"
+	"go_backend/routes/userinfo"
+	"path/filepath"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func openTestQueue(t *testing.T) (*AlertQueue, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "alerts.db")
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewAlertQueue(db), path
+}
+
+func TestAlertQueueLifecycle(t *testing.T) {
+	queue, _ := openTestQueue(t)
+	first, err := queue.Push(&userinfo.WorkerInfo{Id: "1_2", DangerType: DangerSOS}, nil)
+	if err != nil || first.Id != 1 || first.State != StatePending {
+		t.Fatalf("Push = %v, %v, want pending alert 1", first, err)
+	}
+	second, _ := queue.Push(&userinfo.WorkerInfo{Id: "1_3", DangerType: DangerWater}, nil)
+
+	acked, err := queue.Acknowledge(first.Id, "supervisor")
+	if err != nil || acked.State != StateAcknowledged || acked.AcknowledgedBy != "supervisor" || acked.AcknowledgedAt == nil {
+		t.Fatalf("Acknowledge = %v, %v", acked, err)
+	}
+	if _, err = queue.Acknowledge(first.Id, "supervisor"); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("second Acknowledge error = %v, want ErrInvalidTransition", err)
+	}
+
+	// Resolving a pending alert acknowledges it on the way
+	resolved, err := queue.Resolve(second.Id, "medic")
+	if err != nil || resolved.State != StateResolved || resolved.AcknowledgedBy != "medic" || resolved.ResolvedBy != "medic" {
+		t.Fatalf("Resolve = %v, %v", resolved, err)
+	}
+	if _, err = queue.Resolve(second.Id, "medic"); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("second Resolve error = %v, want ErrInvalidTransition", err)
+	}
+	if _, err = queue.Acknowledge(99, "nobody"); !errors.Is(err, ErrAlertNotFound) {
+		t.Errorf("Acknowledge(99) error = %v, want ErrAlertNotFound", err)
+	}
+
+	open, _ := queue.List(StatePending, StateAcknowledged)
+	if len(open) != 1 || open[0].Id != first.Id {
+		t.Errorf("List(pending, acknowledged) = %v, want alert %v only", open, first.Id)
+	}
+	all, _ := queue.List()
+	if len(all) != 2 {
+		t.Errorf("List() returned %v alerts, want 2", len(all))
+	}
+}
+
+func TestAlertQueueListAfter(t *testing.T) {
+	queue, _ := openTestQueue(t)
+	for i := 0; i < 5; i++ {
+		if _, err := queue.Push(&userinfo.WorkerInfo{Id: "1_2"}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	alertList, err := queue.ListAfter(3)
+	if err != nil || len(alertList) != 2 || alertList[0].Id != 4 || alertList[1].Id != 5 {
+		t.Fatalf("ListAfter(3) = %v, %v, want alerts 4 and 5", alertList, err)
+	}
+	if alertList, _ = queue.ListAfter(5); len(alertList) != 0 {
+		t.Errorf("ListAfter(5) = %v, want none", alertList)
+	}
+}
+
+func TestAlertQueueSurvivesRestart(t *testing.T) {
+	queue, path := openTestQueue(t)
+	alert, _ := queue.Push(&userinfo.WorkerInfo{Id: "1_2", DangerType: DangerSOS}, nil)
+	queue.DB.Close()
+
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	reopened := NewAlertQueue(db)
+	stored, err := reopened.Get(alert.Id)
+	if err != nil || stored.WorkerInfo.DangerType != DangerSOS || stored.State != StatePending {
+		t.Fatalf("Get after reopen = %v, %v", stored, err)
+	}
+	if next, _ := reopened.Push(&userinfo.WorkerInfo{Id: "1_2"}, nil); next.Id != alert.Id+1 {
+		t.Errorf("Id after reopen = %v, want %v", next.Id, alert.Id+1)
+	}
+}
@@ -0,0 +1,187 @@
+package danger
+
+import (
+	"go_backend/routes/userinfo"
+	"go_backend/util"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// SubscriberBuffer is how many alerts a slow client may lag behind before it is dropped.
+// A dropped client reconnects with Last-Event-ID and catches up from the queue
+const SubscriberBuffer = 64
+
+const KeepAliveInterval = 15 * time.Second
+
+// AlertFilter selects which alerts a stream client receives, empty fields match everything
+type AlertFilter struct {
+	Grounds     []string
+	DangerTypes []string
+}
+
+func (filter *AlertFilter) Match(alert *Alert) bool {
+	if len(filter.Grounds) > 0 && !contains(filter.Grounds, userinfo.GetSite(alert.WorkerInfo.Id)) {
+		return false
+	}
+	if len(filter.DangerTypes) > 0 && !contains(filter.DangerTypes, alert.WorkerInfo.DangerType) {
+		return false
+	}
+	return true
+}
+
+// Broker fans new alerts out to every connected stream client
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[chan Alert]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{subscribers: make(map[chan Alert]struct{})}
+}
+
+func (broker *Broker) Subscribe() chan Alert {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	subscriber := make(chan Alert, SubscriberBuffer)
+	broker.subscribers[subscriber] = struct{}{}
+	return subscriber
+}
+
+func (broker *Broker) Unsubscribe(subscriber chan Alert) {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	if _, isFound := broker.subscribers[subscriber]; isFound {
+		delete(broker.subscribers, subscriber)
+		close(subscriber)
+	}
+}
+
+// Publish Never blocks, subscribers that can't keep up are disconnected
+func (broker *Broker) Publish(alert Alert) {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	for subscriber := range broker.subscribers {
+		select {
+		case subscriber <- alert:
+		default:
+			delete(broker.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
+
+// Subscription feeds one stream client: the alerts it missed since its last event id, then live ones,
+// each once and in Id order. Shared by /danger/stream and the gRPC StreamAlerts
+type Subscription struct {
+	Alerts <-chan Alert // Closed when the client fell SubscriberBuffer alerts behind
+	filter AlertFilter
+	lastId uint64
+	live   chan Alert
+}
+
+// Subscribe Starts a subscription, with resume the alerts created after lastId are returned as backlog
+func Subscribe(filter AlertFilter, lastId uint64, resume bool) (*Subscription, []Alert, error) {
+	// Subscribe before reading the backlog so nothing raised in between is missed
+	live := broker.Subscribe()
+	subscription := &Subscription{Alerts: live, filter: filter, lastId: lastId, live: live}
+	if !resume {
+		return subscription, nil, nil
+	}
+
+	alertList, err := GetQueue().ListAfter(lastId)
+	if err != nil {
+		subscription.Close()
+		return nil, nil, err
+	}
+	var backlog []Alert
+	for i := range alertList {
+		if subscription.Accept(&alertList[i]) {
+			backlog = append(backlog, alertList[i])
+		}
+	}
+	return subscription, backlog, nil
+}
+
+// Accept Reports whether a live alert goes to the client, false for alerts already sent with the
+// backlog and for ones the filter rejects. Relies on alerts being published in Id order (AlertQueue.Push)
+func (subscription *Subscription) Accept(alert *Alert) bool {
+	if alert.Id <= subscription.lastId {
+		return false
+	}
+	subscription.lastId = alert.Id
+	return subscription.filter.Match(alert)
+}
+
+func (subscription *Subscription) Close() {
+	broker.Unsubscribe(subscription.live)
+}
+
+// Stream Pushes new alerts as Server-Sent Events
+// Query params Ground and DangerType filter the feed, Last-Event-ID header (or lastEventId param) replays missed alerts
+func Stream(ctx *gin.Context) {
+	util.DebugPrint(FILENAME, "STREAM", "Just Test")
+	filter := AlertFilter{Grounds: ctx.QueryArray("Ground"), DangerTypes: ctx.QueryArray("DangerType")}
+
+	lastEventId := ctx.GetHeader("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = ctx.Query("lastEventId")
+	}
+	var lastId uint64
+	if lastEventId != "" {
+		var err error
+		if lastId, err = strconv.ParseUint(lastEventId, 10, 64); err != nil {
+			util.WriteError(ctx, http.StatusBadRequest, "Invalid Last-Event-ID")
+			return
+		}
+	}
+
+	subscription, backlog, err := Subscribe(filter, lastId, lastEventId != "")
+	if err != nil {
+		util.WriteStoreError(ctx, err)
+		return
+	}
+	defer subscription.Close()
+
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	for i := range backlog {
+		renderAlert(ctx, &backlog[i])
+	}
+	ctx.Writer.Flush()
+
+	keepAlive := time.NewTicker(KeepAliveInterval)
+	defer keepAlive.Stop()
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Request.Context().Done():
+			return false
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		case alert, isOpen := <-subscription.Alerts:
+			if !isOpen {
+				return false
+			}
+			if subscription.Accept(&alert) {
+				renderAlert(ctx, &alert)
+			}
+			return true
+		}
+	})
+}
+
+func renderAlert(ctx *gin.Context, alert *Alert) {
+	ctx.Render(-1, sse.Event{Id: strconv.FormatUint(alert.Id, 10), Event: "danger", Data: alert})
+}
@@ -0,0 +1,115 @@
+package danger
+
+import (
+	"go_backend/routes/userinfo"
+	"sync"
+	"testing"
+	"time"
+)
+
+// useTestQueue Points GetQueue at a queue in a temporary database instead of LOCAL_DB_FILE
+func useTestQueue(t *testing.T) *AlertQueue {
+	t.Helper()
+	queue, _ := openTestQueue(t)
+	alertQueueOnce.Do(func() {})
+	alertQueue = queue
+	return queue
+}
+
+// A publish slower for lower Ids would reorder alerts if Push let the next Id be assigned meanwhile
+func TestPushPublishesInIdOrder(t *testing.T) {
+	queue, _ := openTestQueue(t)
+	var mu sync.Mutex
+	var published []uint64
+	publish := func(alert Alert) {
+		time.Sleep(time.Duration(20-alert.Id%20) * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		published = append(published, alert.Id)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := queue.Push(&userinfo.WorkerInfo{Id: "1_2"}, publish); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	for i := 1; i < len(published); i++ {
+		if published[i] < published[i-1] {
+			t.Fatalf("alerts published out of Id order: %v", published)
+		}
+	}
+}
+
+func TestConcurrentRaiseReachesSubscriber(t *testing.T) {
+	useTestQueue(t)
+	subscription, _, err := Subscribe(AlertFilter{}, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer subscription.Close()
+
+	const raised = SubscriberBuffer
+	var wg sync.WaitGroup
+	for i := 0; i < raised; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := Raise(&userinfo.WorkerInfo{Id: "1_2", DangerType: DangerSOS}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	seen := map[uint64]bool{}
+	for len(seen) < raised {
+		alert, isOpen := <-subscription.Alerts
+		if !isOpen {
+			t.Fatalf("subscriber dropped after %v alerts", len(seen))
+		}
+		if !subscription.Accept(&alert) {
+			t.Fatalf("alert %v dropped after %v alerts were accepted", alert.Id, len(seen))
+		}
+		seen[alert.Id] = true
+	}
+}
+
+func TestSubscribeResumesAfterLastId(t *testing.T) {
+	useTestQueue(t)
+	for _, info := range []userinfo.WorkerInfo{
+		{Id: "1_2", DangerType: DangerSOS},
+		{Id: "2_2", DangerType: DangerSOS},
+		{Id: "1_3", DangerType: DangerWater},
+		{Id: "1_4", DangerType: DangerSOS},
+	} {
+		info := info
+		if _, err := Raise(&info); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	filter := AlertFilter{Grounds: []string{"1"}, DangerTypes: []string{DangerSOS}}
+	subscription, backlog, err := Subscribe(filter, 1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer subscription.Close()
+	if len(backlog) != 1 || backlog[0].Id != 4 {
+		t.Fatalf("backlog = %v, want alert 4 only", backlog)
+	}
+
+	// Already sent with the backlog, a repeat from the broker is skipped
+	if repeat := backlog[0]; subscription.Accept(&repeat) {
+		t.Error("alert 4 accepted twice")
+	}
+	live, _ := Raise(&userinfo.WorkerInfo{Id: "1_5", DangerType: DangerSOS})
+	if alert := <-subscription.Alerts; alert.Id != live.Id || !subscription.Accept(&alert) {
+		t.Errorf("live alert %v not accepted", alert.Id)
+	}
+}
@@ -0,0 +1,237 @@
+/*
+Edgesync Package forwards readings written on an edge gateway to the central WorkerInfo and WorkerReading tables
+Only active when STORAGE_BACKEND=edge
+*/
+
+package edgesync
+
+import (
+	"errors"
+	"fmt"
+	"go_backend/routes/userinfo"
+	"go_backend/util"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/gin-gonic/gin"
+)
+
+const FILENAME = "edgesync/index.go"
+
+// SiteStatus reports how far a ground's local writes are behind the central table
+type SiteStatus struct {
+	Site          string
+	Pending       int
+	OldestPending *time.Time
+	LagSeconds    float64
+	LastSyncedAt  *time.Time
+}
+
+type SyncStatus struct {
+	Enabled      bool
+	LastRunAt    *time.Time
+	LastError    string
+	PendingTotal int
+	DeadLettered int // Entries moved to the dead-letter bucket after SYNC_MAX_ATTEMPTS rejections
+	Sites        []SiteStatus
+}
+
+var mu sync.Mutex
+var lastRunAt *time.Time
+var lastError string
+var lastSyncedAt = map[string]time.Time{}
+
+// Start Replays the journal every SYNC_INTERVAL seconds, Blocks so run it in its own goroutine
+func Start(pLog *log.Logger, eLog *log.Logger) {
+	edgeStore, isEdge := userinfo.GetStore().(*userinfo.EdgeWorkerInfoStore)
+	if !isEdge {
+		eLog.Println("Edge sync needs STORAGE_BACKEND=edge")
+		return
+	}
+	remote := userinfo.NewTClientUserInfo()
+
+	pLog.Println("Edge sync Started")
+	ticker := time.NewTicker(time.Duration(util.GetSyncInterval()) * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		uploaded, err := SyncOnce(edgeStore, remote)
+		if err != nil {
+			eLog.Printf("Edge sync failed, will retry. Reason => %v\n", err)
+		} else if uploaded > 0 {
+			pLog.Printf("Edge sync uploaded %v journal entries\n", uploaded)
+		}
+	}
+}
+
+// SyncOnce Uploads one batch of journal entries and removes them from the journal once DynamoDB accepted them
+// Entries DynamoDB rejects are retried next round and dead-lettered after SYNC_MAX_ATTEMPTS rejections,
+// throttling and lost connectivity don't count as a rejection, the journal is there to wait them out
+func SyncOnce(edgeStore *userinfo.EdgeWorkerInfoStore, remote *userinfo.TClientUserInfo) (int, error) {
+	entries, err := edgeStore.PendingJournal(util.GetSyncBatchLimit())
+	var synced []userinfo.JournalEntry
+	if err == nil && len(entries) > 0 {
+		var done, rejected []uint64
+		for i, uploadErr := range upload(entries, remote) {
+			switch {
+			case uploadErr == nil:
+				done = append(done, entries[i].Seq)
+				synced = append(synced, entries[i])
+			case isTransient(uploadErr):
+				err = uploadErr
+			default:
+				err = uploadErr
+				rejected = append(rejected, entries[i].Seq)
+			}
+		}
+		if ackErr := edgeStore.AckJournal(done); ackErr != nil {
+			err = ackErr
+			synced = nil
+		}
+		moved, failErr := edgeStore.FailJournal(rejected, util.GetSyncMaxAttempts())
+		if failErr != nil {
+			err = failErr
+		}
+		if moved > 0 {
+			log.Printf("Edge sync moved %v journal entries to the dead-letter bucket\n", moved)
+		}
+	}
+
+	now := time.Now()
+	mu.Lock()
+	defer mu.Unlock()
+	lastRunAt = &now
+	lastError = ""
+	if err != nil {
+		lastError = err.Error()
+	}
+	for _, entry := range synced {
+		lastSyncedAt[entry.Site] = now
+	}
+	return len(synced), err
+}
+
+// isTransient Reports errors that say nothing about the entry itself
+func isTransient(err error) bool {
+	return errors.Is(err, util.ErrThrottled) || errors.Is(err, util.ErrUnavailable) || errors.Is(err, userinfo.ErrUnprocessed)
+}
+
+// upload Coalesces entries on their table key (Id + Date, or Id + Timestamp for readings) before
+// sending them, the newest local write wins. BatchWriteItem rejects two requests for the same key
+// in one call, and replaying an overwritten row would only be undone by the later one anyway
+// WorkerInfo rows are put one by one on the condition that the central row isn't newer, another
+// gateway or a direct ingest may have written a later reading meanwhile. Such rows are superseded
+// and dropped. Returns the outcome of each entry, nil once it needs no further upload
+func upload(entries []userinfo.JournalEntry, remote *userinfo.TClientUserInfo) []error {
+	errs := make([]error, len(entries))
+	latest := make(map[string]userinfo.JournalEntry)
+	owners := make(map[string][]int)
+	var order []string
+	for i, entry := range entries {
+		if entry.Table == "" {
+			entry.Table = userinfo.TABLENAME
+		}
+		key := entry.Table + "#" + entry.Item.Id + "#" + entry.Item.Date
+		if entry.Table == userinfo.READINGTABLENAME {
+			key = entry.Table + "#" + entry.Item.Id + "#" + entry.Item.Timestamp
+		}
+		if _, isFound := latest[key]; !isFound {
+			order = append(order, key)
+		}
+		latest[key] = entry
+		owners[key] = append(owners[key], i)
+	}
+	setErr := func(key string, err error) {
+		for _, i := range owners[key] {
+			errs[i] = err
+		}
+	}
+
+	writeReqs := make(map[string][]types.WriteRequest)
+	writeKeys := make(map[string][]string)
+	for _, key := range order {
+		entry := latest[key]
+		switch {
+		case entry.Operation == userinfo.JournalPut && entry.Table == userinfo.TABLENAME:
+			if err := remote.ReplaceWorkerInfo(&entry.Item); err != nil && !errors.Is(err, userinfo.ErrSuperseded) {
+				setErr(key, err)
+			}
+		case entry.Operation == userinfo.JournalPut:
+			item, err := attributevalue.MarshalMap(entry.Item)
+			if err != nil {
+				setErr(key, err)
+				continue
+			}
+			writeReqs[entry.Table] = append(writeReqs[entry.Table], types.WriteRequest{PutRequest: &types.PutRequest{Item: item}})
+			writeKeys[entry.Table] = append(writeKeys[entry.Table], key)
+		case entry.Operation == userinfo.JournalDelete:
+			writeReqs[entry.Table] = append(writeReqs[entry.Table], types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: entry.Item.GetKey()}})
+			writeKeys[entry.Table] = append(writeKeys[entry.Table], key)
+		default:
+			setErr(key, fmt.Errorf("unknown journal operation %v in entry %v", entry.Operation, entry.Seq))
+		}
+	}
+	for _, table := range []string{userinfo.TABLENAME, userinfo.READINGTABLENAME} {
+		for at, err := range remote.WriteBatchItems(table, writeReqs[table]) {
+			setErr(writeKeys[table][at], err)
+		}
+	}
+	return errs
+}
+
+// Get Reports sync lag per site
+func Get(ctx *gin.Context) {
+	util.DebugPrint(FILENAME, "GET", "Just Test")
+	edgeStore, isEdge := userinfo.GetStore().(*userinfo.EdgeWorkerInfoStore)
+	if !isEdge {
+		ctx.JSON(http.StatusOK, SyncStatus{Enabled: false})
+		return
+	}
+
+	entries, err := edgeStore.PendingJournal(0)
+	if err != nil {
+		util.WriteStoreError(ctx, err)
+		return
+	}
+	deadLetters, err := edgeStore.DeadLetters()
+	if err != nil {
+		util.WriteStoreError(ctx, err)
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	now := time.Now()
+	sites := make(map[string]*SiteStatus)
+	for site, syncedAt := range lastSyncedAt {
+		syncedAt := syncedAt
+		sites[site] = &SiteStatus{Site: site, LastSyncedAt: &syncedAt}
+	}
+	for _, entry := range entries {
+		status, isFound := sites[entry.Site]
+		if !isFound {
+			status = &SiteStatus{Site: entry.Site}
+			sites[entry.Site] = status
+		}
+		status.Pending++
+		if status.OldestPending == nil {
+			queuedAt := entry.QueuedAt
+			status.OldestPending = &queuedAt
+			status.LagSeconds = now.Sub(queuedAt).Seconds()
+		}
+	}
+
+	response := SyncStatus{Enabled: true, LastRunAt: lastRunAt, LastError: lastError, PendingTotal: len(entries), DeadLettered: len(deadLetters)}
+	for _, status := range sites {
+		response.Sites = append(response.Sites, *status)
+	}
+	sort.Slice(response.Sites, func(i, j int) bool {
+		return response.Sites[i].Site < response.Sites[j].Site
+	})
+	ctx.JSON(http.StatusOK, response)
+}
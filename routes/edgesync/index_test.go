@@ -0,0 +1,147 @@
+package edgesync
+
+import (
+	"context"
+	"go_backend/routes/userinfo"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	bolt "go.etcd.io/bbolt"
+)
+
+// fakeDynamo keeps the WorkerInfo table in a map and checks the put condition the way DynamoDB would
+type fakeDynamo struct {
+	userinfo.DynamoDBAPI
+	mu       sync.Mutex
+	rows     map[string]userinfo.WorkerInfo // Id#Date
+	readings int
+	putErr   map[string]error // Id -> error PutItem fails with
+}
+
+func newFakeDynamo() *fakeDynamo {
+	return &fakeDynamo{rows: map[string]userinfo.WorkerInfo{}, putErr: map[string]error{}}
+}
+
+func (fake *fakeDynamo) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	item := userinfo.WorkerInfo{}
+	if err := attributevalue.UnmarshalMap(params.Item, &item); err != nil {
+		return nil, err
+	}
+	if err := fake.putErr[item.Id]; err != nil {
+		return nil, err
+	}
+	if params.ConditionExpression == nil {
+		panic("WorkerInfo put without a condition")
+	}
+	if stored, isFound := fake.rows[item.Id+"#"+item.Date]; isFound && stored.Timestamp > item.Timestamp {
+		return nil, &types.ConditionalCheckFailedException{}
+	}
+	fake.rows[item.Id+"#"+item.Date] = item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (fake *fakeDynamo) BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	for _, writeReqs := range params.RequestItems {
+		fake.readings += len(writeReqs)
+	}
+	return &dynamodb.BatchWriteItemOutput{}, nil
+}
+
+func newTestStores(t *testing.T) (*userinfo.EdgeWorkerInfoStore, *fakeDynamo, *userinfo.TClientUserInfo) {
+	t.Helper()
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "edge.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	fake := newFakeDynamo()
+	remote := &userinfo.TClientUserInfo{DynamoDbClient: fake, TableName: userinfo.TABLENAME, ReadingTableName: userinfo.READINGTABLENAME}
+	return userinfo.NewEdgeWorkerInfoStore(db), fake, remote
+}
+
+func insert(t *testing.T, store *userinfo.EdgeWorkerInfoStore, workerInfo userinfo.WorkerInfo) {
+	t.Helper()
+	if err := store.InsertReading(&workerInfo); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.InsertWorkerInfo(&workerInfo); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSyncOnceDropsSupersededRows(t *testing.T) {
+	edgeStore, fake, remote := newTestStores(t)
+	fake.rows["1_2#2024-01-01"] = userinfo.WorkerInfo{Id: "1_2", Date: "2024-01-01", Timestamp: "2024-01-01T12:00:00Z", Name: "central"}
+	insert(t, edgeStore, userinfo.WorkerInfo{Id: "1_2", Date: "2024-01-01", Timestamp: "2024-01-01T11:00:00Z", Name: "edge"})
+	insert(t, edgeStore, userinfo.WorkerInfo{Id: "1_3", Date: "2024-01-01", Timestamp: "2024-01-01T11:00:00Z", Name: "edge"})
+
+	uploaded, err := SyncOnce(edgeStore, remote)
+	if err != nil || uploaded != 4 {
+		t.Fatalf("SyncOnce = %v, %v, want 4 entries uploaded", uploaded, err)
+	}
+	if got := fake.rows["1_2#2024-01-01"].Name; got != "central" {
+		t.Errorf("older edge row replaced the newer central one, Name = %v", got)
+	}
+	if got := fake.rows["1_3#2024-01-01"].Name; got != "edge" {
+		t.Errorf("missing central row not written, Name = %v", got)
+	}
+	if fake.readings != 2 {
+		t.Errorf("readings written = %v, want 2", fake.readings)
+	}
+	if pending, _ := edgeStore.PendingJournal(0); len(pending) != 0 {
+		t.Errorf("journal still holds %v entries", len(pending))
+	}
+}
+
+func TestSyncOnceDeadLettersRejectedEntries(t *testing.T) {
+	edgeStore, fake, remote := newTestStores(t)
+	fake.putErr["1_2"] = &smithy.GenericAPIError{Code: "ValidationException", Message: "Item size has exceeded the maximum allowed size"}
+	if err := edgeStore.InsertWorkerInfo(&userinfo.WorkerInfo{Id: "1_2", Date: "2024-01-01", Timestamp: "2024-01-01T11:00:00Z"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for attempt := 1; attempt < 5; attempt++ {
+		if _, err := SyncOnce(edgeStore, remote); err == nil {
+			t.Fatal("SyncOnce reported no error for a rejected entry")
+		}
+		pending, _ := edgeStore.PendingJournal(0)
+		if len(pending) != 1 || pending[0].Attempts != attempt {
+			t.Fatalf("after attempt %v the journal holds %v", attempt, pending)
+		}
+	}
+	SyncOnce(edgeStore, remote)
+	if pending, _ := edgeStore.PendingJournal(0); len(pending) != 0 {
+		t.Errorf("journal still holds %v after 5 rejections", pending)
+	}
+	if deadLetters, _ := edgeStore.DeadLetters(); len(deadLetters) != 1 || deadLetters[0].Item.Id != "1_2" {
+		t.Errorf("DeadLetters = %v, want the 1_2 entry", deadLetters)
+	}
+}
+
+func TestSyncOnceKeepsEntriesWhileThrottled(t *testing.T) {
+	edgeStore, fake, remote := newTestStores(t)
+	fake.putErr["1_2"] = &types.ProvisionedThroughputExceededException{}
+	if err := edgeStore.InsertWorkerInfo(&userinfo.WorkerInfo{Id: "1_2", Date: "2024-01-01", Timestamp: "2024-01-01T11:00:00Z"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for attempt := 0; attempt < 10; attempt++ {
+		SyncOnce(edgeStore, remote)
+	}
+	pending, _ := edgeStore.PendingJournal(0)
+	if len(pending) != 1 || pending[0].Attempts != 0 {
+		t.Errorf("journal = %v, want the entry kept without counted attempts", pending)
+	}
+	if deadLetters, _ := edgeStore.DeadLetters(); len(deadLetters) != 0 {
+		t.Errorf("throttled entry dead-lettered: %v", deadLetters)
+	}
+}
@@ -0,0 +1,264 @@
+/*
+Exposure Package tracks cumulative gas exposure per worker and gas over a shift
+TWA is the 8 hour time weighted average, STEL the 15 minute short term exposure
+Each reading is assumed to hold until the next one (at most EXPOSURE_SAMPLE_HOLD)
+The tracker lives in memory, a worker's shift is rebuilt from the stored readings on its first reading after a restart
+*/
+
+package exposure
+
+import (
+	"go_backend/routes/danger"
+	"go_backend/routes/userinfo"
+	"go_backend/util"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const FILENAME = "exposure/index.go"
+
+const (
+	TWAPeriod  = 8 * time.Hour
+	STELPeriod = 15 * time.Minute
+)
+
+type sample struct {
+	at    time.Time
+	level float64
+}
+
+// shift holds one worker's readings of one gas since the shift began
+type shift struct {
+	id           string
+	gasType      string
+	start        time.Time
+	samples      []sample
+	twaExceeded  bool // alert raised for this shift
+	stelExceeded bool // alert raised, re-armed when STEL drops below the limit
+}
+
+type Status struct {
+	Id           string
+	GasType      string
+	ShiftStart   time.Time
+	Readings     int
+	TWA          float64
+	TWALimit     float64 `json:",omitempty"`
+	TWAExceeded  bool
+	STEL         float64
+	STELLimit    float64 `json:",omitempty"`
+	STELExceeded bool
+}
+
+// Tracker computes exposure for every worker and gas seen since the server started
+// A helmet switching between sensors keeps a separate TWA and STEL for each gas
+type Tracker struct {
+	mu         sync.Mutex
+	shifts     map[string]*shift // Id#GasType
+	twaLimits  map[string]float64
+	stelLimits map[string]float64
+	shiftGap   time.Duration
+	sampleHold time.Duration
+	history    func(id string, from, to time.Time) ([]userinfo.WorkerInfo, error) // stored readings, oldest first
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{
+		shifts:     make(map[string]*shift),
+		twaLimits:  util.GetConfigLimits("GAS_TWA_LIMITS"),
+		stelLimits: util.GetConfigLimits("GAS_STEL_LIMITS"),
+		shiftGap:   util.GetConfigDuration("EXPOSURE_SHIFT_GAP", 4*time.Hour),
+		sampleHold: util.GetConfigDuration("EXPOSURE_SAMPLE_HOLD", 5*time.Minute),
+		history:    storedReadings,
+	}
+}
+
+// storedReadings Reads a worker's readings between from and to from the userinfo store
+func storedReadings(id string, from, to time.Time) ([]userinfo.WorkerInfo, error) {
+	return userinfo.GetStore().GetReadings(id, userinfo.FormatTimestamp(from), userinfo.FormatTimestamp(to))
+}
+
+var tracker *Tracker
+var trackerOnce sync.Once
+
+// getTracker Returns the tracker fed by OnReading, its limits are read from config.env on first use
+func getTracker() *Tracker {
+	trackerOnce.Do(func() {
+		tracker = NewTracker()
+	})
+	return tracker
+}
+
+// dose Integrates the step function of the readings over [from, to] in ppm*seconds
+func (tracker *Tracker) dose(samples []sample, from, to time.Time) float64 {
+	total := 0.0
+	for i, current := range samples {
+		end := current.at.Add(tracker.sampleHold)
+		if i+1 < len(samples) && samples[i+1].at.Before(end) {
+			end = samples[i+1].at
+		}
+		if end.After(to) {
+			end = to
+		}
+		begin := current.at
+		if begin.Before(from) {
+			begin = from
+		}
+		if end.After(begin) {
+			total += current.level * end.Sub(begin).Seconds()
+		}
+	}
+	return total
+}
+
+func (tracker *Tracker) status(workerShift *shift, now time.Time) Status {
+	status := Status{Id: workerShift.id, GasType: workerShift.gasType, ShiftStart: workerShift.start, Readings: len(workerShift.samples)}
+	// TWA always divides by 8 hours, a shorter shift simply had no exposure for the rest
+	status.TWA = tracker.dose(workerShift.samples, now.Add(-TWAPeriod), now) / TWAPeriod.Seconds()
+	status.STEL = tracker.dose(workerShift.samples, now.Add(-STELPeriod), now) / STELPeriod.Seconds()
+	if limit, isFound := tracker.twaLimits[workerShift.gasType]; isFound {
+		status.TWALimit = limit
+		status.TWAExceeded = status.TWA > limit
+	}
+	if limit, isFound := tracker.stelLimits[workerShift.gasType]; isFound {
+		status.STELLimit = limit
+		status.STELExceeded = status.STEL > limit
+	}
+	return status
+}
+
+// restore Rebuilds the shift of a worker and gas from the readings stored in the TWA period before at
+// The limits exceeded at its last reading count as already raised
+func (tracker *Tracker) restore(id, gasType string, at time.Time) *shift {
+	if tracker.history == nil {
+		return nil
+	}
+	readings, err := tracker.history(id, at.Add(-TWAPeriod), at.Add(-time.Nanosecond))
+	if err != nil {
+		log.Printf("Couldn't read the readings of %v to rebuild its shift. Reason => %v\n", id, err)
+		return nil
+	}
+	var samples []sample
+	for i := range readings {
+		if readings[i].GasType == gasType {
+			samples = append(samples, sample{at: readings[i].MeasuredTime(), level: float64(readings[i].GasLevel)})
+		}
+	}
+	// The shift began after the last break longer than the shift gap
+	first := 0
+	for i := 1; i < len(samples); i++ {
+		if samples[i].at.Sub(samples[i-1].at) > tracker.shiftGap {
+			first = i
+		}
+	}
+	if samples = samples[first:]; len(samples) == 0 {
+		return nil
+	}
+	workerShift := &shift{id: id, gasType: gasType, start: samples[0].at, samples: samples}
+	status := tracker.status(workerShift, samples[len(samples)-1].at)
+	workerShift.twaExceeded, workerShift.stelExceeded = status.TWAExceeded, status.STELExceeded
+	return workerShift
+}
+
+// insert Adds a reading in timestamp order, false when it is from before the shift
+func (workerShift *shift) insert(reading sample, shiftGap time.Duration) bool {
+	if reading.at.Before(workerShift.start) {
+		if workerShift.start.Sub(reading.at) > shiftGap {
+			return false
+		}
+		workerShift.start = reading.at
+	}
+	at := sort.Search(len(workerShift.samples), func(i int) bool { return workerShift.samples[i].at.After(reading.at) })
+	workerShift.samples = append(workerShift.samples, sample{})
+	copy(workerShift.samples[at+1:], workerShift.samples[at:])
+	workerShift.samples[at] = reading
+	return true
+}
+
+// Record Adds a reading and returns the danger types newly exceeded by it
+// A late reading is put in timestamp order and the exposure computed again at the latest one
+func (tracker *Tracker) Record(workerInfo *userinfo.WorkerInfo, at time.Time) []string {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	key := workerInfo.Id + "#" + workerInfo.GasType
+	workerShift, isFound := tracker.shifts[key]
+	if !isFound {
+		workerShift = tracker.restore(workerInfo.Id, workerInfo.GasType, at)
+	}
+	if workerShift == nil || at.Sub(workerShift.samples[len(workerShift.samples)-1].at) > tracker.shiftGap {
+		workerShift = &shift{id: workerInfo.Id, gasType: workerInfo.GasType, start: at}
+	}
+	tracker.shifts[key] = workerShift
+	if !workerShift.insert(sample{at: at, level: float64(workerInfo.GasLevel)}, tracker.shiftGap) {
+		return nil // from an earlier shift
+	}
+
+	// Readings older than the TWA period no longer count
+	now := workerShift.samples[len(workerShift.samples)-1].at
+	keep := 0
+	for keep < len(workerShift.samples)-1 && now.Sub(workerShift.samples[keep+1].at) > TWAPeriod {
+		keep++
+	}
+	workerShift.samples = workerShift.samples[keep:]
+
+	var exceeded []string
+	status := tracker.status(workerShift, now)
+	if status.TWAExceeded && !workerShift.twaExceeded {
+		exceeded = append(exceeded, danger.DangerGasTWA)
+	}
+	workerShift.twaExceeded = workerShift.twaExceeded || status.TWAExceeded
+	if status.STELExceeded && !workerShift.stelExceeded {
+		exceeded = append(exceeded, danger.DangerGasSTEL)
+	}
+	workerShift.stelExceeded = status.STELExceeded
+	return exceeded
+}
+
+// Status Returns current exposure to each gas for one worker, or every worker when id is empty
+func (tracker *Tracker) Status(id string, now time.Time) []Status {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	statusList := []Status{}
+	for _, workerShift := range tracker.shifts {
+		if id == "" || id == workerShift.id {
+			statusList = append(statusList, tracker.status(workerShift, now))
+		}
+	}
+	sort.Slice(statusList, func(i, j int) bool {
+		if statusList[i].Id != statusList[j].Id {
+			return statusList[i].Id < statusList[j].Id
+		}
+		return statusList[i].GasType < statusList[j].GasType
+	})
+	return statusList
+}
+
+// OnReading Is registered as a userinfo ingest hook, exceeded limits become danger alerts
+func OnReading(workerInfo *userinfo.WorkerInfo, at time.Time) {
+	for _, dangerType := range getTracker().Record(workerInfo, at) {
+		alertInfo := *workerInfo
+		alertInfo.DangerType = dangerType
+		if _, err := danger.Raise(&alertInfo); err != nil {
+			log.Printf("Couldn't raise %v for %v. Reason => %v\n", dangerType, alertInfo.Id, err)
+		}
+	}
+}
+
+// Get Current exposure status per worker and gas, Id query param selects one worker
+func Get(ctx *gin.Context) {
+	util.DebugPrint(FILENAME, "GET", "Just Test")
+	id := ctx.Query("Id")
+	statusList := getTracker().Status(id, time.Now())
+	if id != "" && len(statusList) == 0 {
+		util.WriteError(ctx, http.StatusNotFound, "No readings for "+id)
+		return
+	}
+	ctx.JSON(http.StatusOK, statusList)
+}
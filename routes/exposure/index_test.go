@@ -0,0 +1,151 @@
+package exposure
+
+import (
+	"go_backend/routes/danger"
+	"go_backend/routes/userinfo"
+	"math"
+	"sort"
+	"testing"
+	"time"
+)
+
+func newTestTracker() *Tracker {
+	return &Tracker{
+		shifts:     make(map[string]*shift),
+		twaLimits:  map[string]float64{"CO": 25, "H2S": 1},
+		stelLimits: map[string]float64{"CO": 100, "H2S": 5},
+		shiftGap:   4 * time.Hour,
+		sampleHold: 5 * time.Minute,
+	}
+}
+
+var shiftStart = time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+
+func TestRecordKeepsOneShiftPerGas(t *testing.T) {
+	tracker := newTestTracker()
+	var raised []string
+	// A helmet reporting CO and H2S in turn, one reading a minute
+	for minute := 0; minute <= 14; minute++ {
+		reading := &userinfo.WorkerInfo{Id: "1_2", GasType: "CO", GasLevel: 200}
+		if minute%2 == 1 {
+			reading = &userinfo.WorkerInfo{Id: "1_2", GasType: "H2S", GasLevel: 10}
+		}
+		raised = append(raised, tracker.Record(reading, shiftStart.Add(time.Duration(minute)*time.Minute))...)
+	}
+
+	if len(raised) != 2 || raised[0] != danger.DangerGasSTEL || raised[1] != danger.DangerGasSTEL {
+		t.Errorf("raised %v, want one GasSTEL per gas", raised)
+	}
+	statusList := tracker.Status("1_2", shiftStart.Add(14*time.Minute))
+	if len(statusList) != 2 {
+		t.Fatalf("Status = %v, want one entry per gas", statusList)
+	}
+	co, h2s := statusList[0], statusList[1]
+	if co.GasType != "CO" || co.Readings != 8 || !co.ShiftStart.Equal(shiftStart) {
+		t.Errorf("CO status = %+v, want 8 readings since %v", co, shiftStart)
+	}
+	if h2s.GasType != "H2S" || h2s.Readings != 7 || !h2s.ShiftStart.Equal(shiftStart.Add(time.Minute)) {
+		t.Errorf("H2S status = %+v, want 7 readings since %v", h2s, shiftStart.Add(time.Minute))
+	}
+	// CO held 200 ppm for 14 of the last 15 minutes, H2S 10 ppm for 13
+	if math.Abs(co.STEL-200*14/15.0) > 0.01 || math.Abs(h2s.STEL-10*13/15.0) > 0.01 {
+		t.Errorf("STEL CO %v, H2S %v", co.STEL, h2s.STEL)
+	}
+}
+
+func TestRecordTWA(t *testing.T) {
+	tracker := newTestTracker()
+	var raised []string
+	for minute := 0; minute <= 8*60; minute += 5 {
+		raised = append(raised, tracker.Record(&userinfo.WorkerInfo{Id: "1_2", GasType: "CO", GasLevel: 50}, shiftStart.Add(time.Duration(minute)*time.Minute))...)
+	}
+	if len(raised) != 1 || raised[0] != danger.DangerGasTWA {
+		t.Errorf("raised %v, want GasTWA once", raised)
+	}
+	status := tracker.Status("1_2", shiftStart.Add(8*time.Hour))[0]
+	if math.Abs(status.TWA-50) > 0.01 || !status.TWAExceeded || status.STELExceeded {
+		t.Errorf("status = %+v, want TWA 50 over the 25 limit", status)
+	}
+}
+
+func TestRecordStartsNewShiftAfterGap(t *testing.T) {
+	tracker := newTestTracker()
+	tracker.Record(&userinfo.WorkerInfo{Id: "1_2", GasType: "CO", GasLevel: 10}, shiftStart)
+	tracker.Record(&userinfo.WorkerInfo{Id: "1_2", GasType: "CO", GasLevel: 10}, shiftStart.Add(time.Minute))
+	// A reading from before the previous shift ended belongs to no shift
+	tracker.Record(&userinfo.WorkerInfo{Id: "1_2", GasType: "CO", GasLevel: 10}, shiftStart.Add(-5*time.Hour))
+	if status := tracker.Status("1_2", shiftStart.Add(time.Minute))[0]; status.Readings != 2 || !status.ShiftStart.Equal(shiftStart) {
+		t.Errorf("status = %+v, want 2 readings since %v", status, shiftStart)
+	}
+
+	next := shiftStart.Add(5 * time.Hour)
+	tracker.Record(&userinfo.WorkerInfo{Id: "1_2", GasType: "CO", GasLevel: 10}, next)
+	if status := tracker.Status("1_2", next)[0]; status.Readings != 1 || !status.ShiftStart.Equal(next) {
+		t.Errorf("status after a 5h gap = %+v, want a new shift", status)
+	}
+	if statusList := tracker.Status("9_9", next); len(statusList) != 0 {
+		t.Errorf("Status(9_9) = %v, want none", statusList)
+	}
+}
+
+func TestRecordPutsLateReadingsInOrder(t *testing.T) {
+	tracker := newTestTracker()
+	tracker.Record(&userinfo.WorkerInfo{Id: "1_2", GasType: "CO", GasLevel: 0}, shiftStart)
+	tracker.Record(&userinfo.WorkerInfo{Id: "1_2", GasType: "CO", GasLevel: 0}, shiftStart.Add(10*time.Minute))
+	// Relayed late, 1500 ppm held from minute 2 to 7 puts STEL at 500
+	raised := tracker.Record(&userinfo.WorkerInfo{Id: "1_2", GasType: "CO", GasLevel: 1500}, shiftStart.Add(2*time.Minute))
+	if len(raised) != 1 || raised[0] != danger.DangerGasSTEL {
+		t.Errorf("raised %v, want GasSTEL for the late reading", raised)
+	}
+	status := tracker.Status("1_2", shiftStart.Add(10*time.Minute))[0]
+	if status.Readings != 3 || math.Abs(status.STEL-500) > 0.01 {
+		t.Errorf("status = %+v, want 3 readings and STEL 500", status)
+	}
+
+	// A little before the shift began moves its start back
+	tracker.Record(&userinfo.WorkerInfo{Id: "1_2", GasType: "CO", GasLevel: 0}, shiftStart.Add(-time.Minute))
+	if status = tracker.Status("1_2", shiftStart.Add(10*time.Minute))[0]; status.Readings != 4 ||
+		!status.ShiftStart.Equal(shiftStart.Add(-time.Minute)) {
+		t.Errorf("status = %+v, want 4 readings since a minute before %v", status, shiftStart)
+	}
+}
+
+func TestRecordRestoresShiftAfterRestart(t *testing.T) {
+	stored := func(minute int, gasType string, level int32) userinfo.WorkerInfo {
+		return userinfo.WorkerInfo{Id: "1_2", GasType: gasType, GasLevel: level,
+			Timestamp: userinfo.FormatTimestamp(shiftStart.Add(time.Duration(minute) * time.Minute))}
+	}
+	var asked [2]time.Time
+	tracker := newTestTracker()
+	tracker.history = func(id string, from, to time.Time) ([]userinfo.WorkerInfo, error) {
+		asked = [2]time.Time{from, to}
+		// The reading 5h before is from the previous shift, the H2S one from another sensor
+		readings := []userinfo.WorkerInfo{stored(-300, "CO", 40), stored(1, "H2S", 9)}
+		for minute := 0; minute < 8*60; minute += 5 {
+			readings = append(readings, stored(minute, "CO", 40))
+		}
+		sort.Slice(readings, func(i, j int) bool { return readings[i].Timestamp < readings[j].Timestamp })
+		return readings, nil
+	}
+
+	now := shiftStart.Add(8 * time.Hour)
+	raised := tracker.Record(&userinfo.WorkerInfo{Id: "1_2", GasType: "CO", GasLevel: 40}, now)
+	if !asked[0].Equal(now.Add(-TWAPeriod)) || !asked[1].Before(now) {
+		t.Errorf("history asked for %v, want the TWA period before %v", asked, now)
+	}
+	// TWA was already over the limit before the restart, it isn't raised again
+	if len(raised) != 0 {
+		t.Errorf("raised %v after a restart, want nothing new", raised)
+	}
+	status := tracker.Status("1_2", now)[0]
+	if status.Readings != 97 || !status.ShiftStart.Equal(shiftStart) || math.Abs(status.TWA-40) > 0.01 || !status.TWAExceeded {
+		t.Errorf("status = %+v, want the 97 CO readings since %v at TWA 40", status, shiftStart)
+	}
+
+	// Nothing stored, the shift starts with the reading
+	tracker.history = func(id string, from, to time.Time) ([]userinfo.WorkerInfo, error) { return nil, nil }
+	tracker.Record(&userinfo.WorkerInfo{Id: "3_4", GasType: "CO", GasLevel: 1}, now)
+	if status = tracker.Status("3_4", now)[0]; status.Readings != 1 || !status.ShiftStart.Equal(now) {
+		t.Errorf("status of 3_4 = %+v, want a shift starting at %v", status, now)
+	}
+}
@@ -0,0 +1,96 @@
+package hospital
+
+import (
+	"encoding/json"
+	"errors"
+	"go_backend/util"
+	"log"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltHospitalStore keeps Hospitals in the embedded on-disk database, keyed by PhoneNumber
+type BoltHospitalStore struct {
+	DB         *bolt.DB
+	BucketName string
+}
+
+func NewBoltHospitalStore(db *bolt.DB) *BoltHospitalStore {
+	util.CreateBucket(db, TABLENAME)
+	return &BoltHospitalStore{DB: db, BucketName: TABLENAME}
+}
+
+func (store *BoltHospitalStore) GetAllHospitalInfo() ([]Hospital, error) {
+	var hospitalList []Hospital
+	err := store.DB.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(store.BucketName)).ForEach(func(key, value []byte) error {
+			hospital := Hospital{}
+			if err := json.Unmarshal(value, &hospital); err != nil {
+				return err
+			}
+			hospitalList = append(hospitalList, hospital)
+			return nil
+		})
+	})
+	if err != nil {
+		log.Printf("Couldn't read hospitals. Here's why: %v\n", err)
+	}
+	return hospitalList, err
+}
+
+// GetHospitalPage Walks the bucket in PhoneNumber order starting after the PhoneNumber after
+func (store *BoltHospitalStore) GetHospitalPage(after string, limit int) ([]Hospital, string, error) {
+	var hospitalList []Hospital
+	next := ""
+	err := store.DB.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket([]byte(store.BucketName)).Cursor()
+		key, value := cursor.Seek([]byte(after))
+		if key != nil && string(key) == after {
+			key, value = cursor.Next()
+		}
+		for ; key != nil; key, value = cursor.Next() {
+			if limit > 0 && len(hospitalList) == limit {
+				next = hospitalList[limit-1].PhoneNumber
+				return nil
+			}
+			item := Hospital{}
+			if err := json.Unmarshal(value, &item); err != nil {
+				return err
+			}
+			hospitalList = append(hospitalList, item)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Couldn't read a page of hospital. Here's why: %v\n", err)
+	}
+	return hospitalList, next, err
+}
+
+func (store *BoltHospitalStore) InsertHospital(hospital *Hospital) error {
+	item, err := json.Marshal(hospital)
+	if err != nil {
+		return err
+	}
+	err = store.DB.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(store.BucketName)).Put([]byte(hospital.PhoneNumber), item)
+	})
+	if err != nil {
+		log.Printf("Couldn't add item to local database. Reason => %v\n", err)
+	}
+	return err
+}
+
+func (store *BoltHospitalStore) DeleteHospital(info *Hospital) error {
+	err := store.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(store.BucketName))
+		if bucket.Get([]byte(info.PhoneNumber)) == nil {
+			return errNoHospital(info)
+		}
+		return bucket.Delete([]byte(info.PhoneNumber))
+	})
+	if err != nil && !errors.Is(err, util.ErrNotFound) {
+		log.Printf("Couldn't delete %v from the local database. Here's why: %v\n", info, err)
+	}
+	return err
+}
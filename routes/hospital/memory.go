@@ -0,0 +1,58 @@
+package hospital
+
+import (
+	"go_backend/util"
+	"sort"
+	"sync"
+)
+
+// MemoryHospitalStore keeps Hospitals in process memory, Contents are lost on restart
+type MemoryHospitalStore struct {
+	mu    sync.RWMutex
+	items map[string]Hospital
+}
+
+func NewMemoryHospitalStore() *MemoryHospitalStore {
+	return &MemoryHospitalStore{items: make(map[string]Hospital)}
+}
+
+func (store *MemoryHospitalStore) GetAllHospitalInfo() ([]Hospital, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	var hospitalList []Hospital
+	for _, hospital := range store.items {
+		hospitalList = append(hospitalList, hospital)
+	}
+	sort.Slice(hospitalList, func(i, j int) bool {
+		return hospitalList[i].PhoneNumber < hospitalList[j].PhoneNumber
+	})
+	return hospitalList, nil
+}
+
+func (store *MemoryHospitalStore) GetHospitalPage(after string, limit int) ([]Hospital, string, error) {
+	hospitalList, _ := store.GetAllHospitalInfo()
+	page, next := util.PageSorted(hospitalList, func(item *Hospital) string {
+		return item.PhoneNumber
+	}, after, limit)
+	return page, next, nil
+}
+
+func (store *MemoryHospitalStore) InsertHospital(hospital *Hospital) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.items[hospital.PhoneNumber] = *hospital
+	return nil
+}
+
+func (store *MemoryHospitalStore) DeleteHospital(info *Hospital) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, isFound := store.items[info.PhoneNumber]; !isFound {
+		return errNoHospital(info)
+	}
+	delete(store.items, info.PhoneNumber)
+	return nil
+}
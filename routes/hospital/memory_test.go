@@ -0,0 +1,30 @@
+package hospital
+
+import "testing"
+
+func TestMemoryHospitalStore(t *testing.T) {
+	store := NewMemoryHospitalStore()
+	for _, hospital := range []Hospital{
+		{Name: "North", PhoneNumber: "300"},
+		{Name: "General", PhoneNumber: "100"},
+		{Name: "South", PhoneNumber: "200"},
+		{Name: "General Annex", PhoneNumber: "100"}, // Same key, replaces General
+	} {
+		hospital := hospital
+		if err := store.InsertHospital(&hospital); err != nil {
+			t.Fatalf("insert %v error = %v", hospital.PhoneNumber, err)
+		}
+	}
+
+	hospitalList, _ := store.GetAllHospitalInfo()
+	if len(hospitalList) != 3 || hospitalList[0].Name != "General Annex" || hospitalList[1].PhoneNumber != "200" || hospitalList[2].PhoneNumber != "300" {
+		t.Errorf("hospitals = %+v, want 3 ordered by PhoneNumber", hospitalList)
+	}
+
+	if err := store.DeleteHospital(&Hospital{PhoneNumber: "200"}); err != nil {
+		t.Fatalf("delete error = %v", err)
+	}
+	if hospitalList, _ = store.GetAllHospitalInfo(); len(hospitalList) != 2 || hospitalList[1].PhoneNumber != "300" {
+		t.Errorf("hospitals after the delete = %+v", hospitalList)
+	}
+}
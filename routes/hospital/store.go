@@ -0,0 +1,44 @@
+package hospital
+
+import (
+	"go_backend/util"
+	"log"
+)
+
+// HospitalStore is implemented by every storage backend able to hold Hospitals
+// Hospitals are keyed by PhoneNumber
+type HospitalStore interface {
+	GetAllHospitalInfo() ([]Hospital, error)
+	GetHospitalPage(after string, limit int) ([]Hospital, string, error) // Ordered by the store, see util.PageReader
+	InsertHospital(hospital *Hospital) error
+	DeleteHospital(info *Hospital) error // util.ErrNotFound when no hospital has the PhoneNumber
+}
+
+// NewHospitalStore Returns the store for the backend named in config.env (STORAGE_BACKEND)
+func NewHospitalStore(backend string) HospitalStore {
+	switch backend {
+	case util.StorageMemory:
+		return NewMemoryHospitalStore()
+	case util.StorageBolt, util.StorageEdge:
+		return NewBoltHospitalStore(util.GetLocalDB())
+	case util.StorageDynamoDB:
+		return NewTClientUserInfo()
+	default:
+		log.Fatalf("Unknown storage backend %v\n", backend)
+	}
+	return nil
+}
+
+// errNoHospital Is returned by DeleteHospital when nothing is stored under info's PhoneNumber
+func errNoHospital(info *Hospital) error {
+	return util.NewStoreError(util.ErrNotFound, "No hospital with PhoneNumber "+info.PhoneNumber)
+}
+
+// GetStore Returns the store used by the route handlers
+// Built from STORAGE_BACKEND on first use, so programs importing only the types (the client package) don't connect to a store
+func GetStore() HospitalStore {
+	tClientOnce.Do(func() {
+		tClient = NewHospitalStore(util.GetStorageBackend())
+	})
+	return tClient
+}
@@ -0,0 +1,44 @@
+package hospital
+
+import (
+	"errors"
+	"go_backend/util"
+	"path/filepath"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func openTestDB(t *testing.T) *bolt.DB {
+	t.Helper()
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestDeleteHospitalReportsMissing(t *testing.T) {
+	stores := map[string]HospitalStore{
+		"memory": NewMemoryHospitalStore(),
+		"bolt":   NewBoltHospitalStore(openTestDB(t)),
+	}
+	for name, store := range stores {
+		if err := store.DeleteHospital(&Hospital{PhoneNumber: "100"}); !errors.Is(err, util.ErrNotFound) {
+			t.Errorf("%v: delete of a missing hospital error = %v, want ErrNotFound", name, err)
+		}
+		if err := store.InsertHospital(&Hospital{Name: "General", PhoneNumber: "100"}); err != nil {
+			t.Fatalf("%v: insert error = %v", name, err)
+		}
+		if err := store.DeleteHospital(&Hospital{PhoneNumber: "100"}); err != nil {
+			t.Errorf("%v: delete error = %v", name, err)
+		}
+		if stored, _ := store.GetAllHospitalInfo(); len(stored) != 0 {
+			t.Errorf("%v: %v left after the delete", name, stored)
+		}
+		if err := store.DeleteHospital(&Hospital{PhoneNumber: "100"}); !errors.Is(err, util.ErrNotFound) {
+			t.Errorf("%v: second delete error = %v, want ErrNotFound", name, err)
+		}
+	}
+}
@@ -0,0 +1,409 @@
+/*
+Hub Package keeps WebSocket connections to helmets, gateways and dashboards
+and routes messages between them by helmet Id (GroundNumber_HelmetNumber) or ground
+*/
+
+package hub
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"go_backend/routes/userinfo"
+	"go_backend/util"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const FILENAME = "hub/index.go"
+
+// Client roles, passed as the Role query param when connecting
+const (
+	RoleHelmet    = "helmet"    // Id is the helmet Id
+	RoleGateway   = "gateway"   // Ground is the ground the gateway relays for
+	RoleDashboard = "dashboard" // Id names the control room screen
+)
+
+// Message types
+const (
+	TypeCommand = "command" // dashboard -> helmet or ground
+	TypeReply   = "reply"   // helmet/gateway -> dashboards
+	TypeAck     = "ack"     // helmet/gateway confirms it handled a command
+	TypeReceipt = "receipt" // hub -> sender, delivery report
+)
+
+// Receipt statuses
+const (
+	StatusDelivered     = "delivered"
+	StatusUndeliverable = "undeliverable"
+	StatusAcknowledged  = "acknowledged"
+	StatusExpired       = "expired" // no client the command reached acked it within ackWait
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = (pongWait * 9) / 10
+	maxMessageSize = 8192
+	sendBuffer     = 32
+	ackWait        = 2 * time.Minute
+)
+
+type Message struct {
+	MessageId   string // Set by the hub, an ack carries the MessageId of its command
+	Type        string
+	From        string          `json:",omitempty"` // Filled by the hub
+	To          string          `json:",omitempty"` // helmet Id
+	Ground      string          `json:",omitempty"` // every helmet and gateway on the ground
+	Body        json.RawMessage `json:",omitempty"`
+	Status      string          `json:",omitempty"` // receipts only
+	DeliveredTo int             `json:",omitempty"` // receipts only
+}
+
+type ClientInfo struct {
+	Role        string
+	Id          string
+	Ground      string
+	ConnectedAt time.Time
+}
+
+type Client struct {
+	ClientInfo
+	conn *websocket.Conn
+	send chan Message
+}
+
+// pendingCommand A delivered command, only the clients it reached may ack it before the deadline
+type pendingCommand struct {
+	sender   *Client
+	targets  map[*Client]struct{}
+	deadline time.Time
+}
+
+// Hub tracks connected clients and commands still waiting for an ack
+type Hub struct {
+	mu      sync.RWMutex
+	clients map[*Client]struct{}
+	pending map[string]*pendingCommand // MessageId -> command
+	counter uint64
+}
+
+func NewHub() *Hub {
+	return &Hub{clients: make(map[*Client]struct{}), pending: make(map[string]*pendingCommand)}
+}
+
+var hub *Hub
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     checkOrigin,
+}
+
+// checkOrigin Lets in clients that send no Origin (helmets and gateways aren't browsers), pages served
+// from this host and the dashboards listed in HUB_ALLOWED_ORIGINS
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if parsed, err := url.Parse(origin); err == nil && strings.EqualFold(parsed.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range util.GetHubAllowedOrigins() {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	hub = NewHub()
+}
+
+func (hub *Hub) register(client *Client) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	hub.clients[client] = struct{}{}
+}
+
+func (hub *Hub) unregister(client *Client) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	if _, isFound := hub.clients[client]; isFound {
+		delete(hub.clients, client)
+		close(client.send)
+	}
+	for messageId, command := range hub.pending {
+		delete(command.targets, client)
+		if command.sender == client || len(command.targets) == 0 {
+			delete(hub.pending, messageId)
+		}
+	}
+}
+
+// expire Drops the commands past their deadline and tells their senders
+func (hub *Hub) expire(now time.Time) {
+	for messageId, command := range hub.pending {
+		if now.After(command.deadline) {
+			delete(hub.pending, messageId)
+			hub.deliver(command.sender, Message{MessageId: messageId, Type: TypeReceipt, Status: StatusExpired})
+		}
+	}
+}
+
+// targets Returns the clients a message from sender must reach
+func (hub *Hub) targets(sender *Client, message *Message) []*Client {
+	var clientList []*Client
+	for client := range hub.clients {
+		if client == sender {
+			continue
+		}
+		switch message.Type {
+		case TypeCommand:
+			if client.Role == RoleDashboard {
+				continue
+			}
+			if message.To != "" && client.Role == RoleHelmet && client.Id == message.To {
+				clientList = append(clientList, client)
+			} else if message.To != "" && client.Role == RoleGateway && client.Ground == userinfo.GetSite(message.To) {
+				clientList = append(clientList, client)
+			} else if message.To == "" && message.Ground != "" && client.Ground == message.Ground {
+				clientList = append(clientList, client)
+			}
+		case TypeReply:
+			if client.Role == RoleDashboard && (message.To == "" || client.Id == message.To) {
+				clientList = append(clientList, client)
+			}
+		}
+	}
+	return clientList
+}
+
+// Route Delivers a message and reports back to the sender with a receipt
+func (hub *Hub) Route(sender *Client, message Message) Message {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	now := time.Now()
+	hub.expire(now)
+	if sender != nil {
+		message.From = sender.Id
+	}
+
+	if message.Type == TypeAck {
+		command, isFound := hub.pending[message.MessageId]
+		if isFound {
+			_, isFound = command.targets[sender]
+		}
+		if isFound {
+			delete(hub.pending, message.MessageId)
+			hub.deliver(command.sender, Message{MessageId: message.MessageId, Type: TypeReceipt, From: message.From,
+				Status: StatusAcknowledged, Body: message.Body})
+		}
+		return Message{MessageId: message.MessageId, Type: TypeReceipt, Status: receiptStatus(isFound, 1)}
+	}
+
+	// Never the sender's, a reused MessageId would take over another command's acks
+	message.MessageId = fmt.Sprintf("%d-%d", now.UnixNano(), atomic.AddUint64(&hub.counter, 1))
+	reached := make(map[*Client]struct{})
+	for _, client := range hub.targets(sender, &message) {
+		if hub.deliver(client, message) {
+			reached[client] = struct{}{}
+		}
+	}
+	delivered := len(reached)
+	if message.Type == TypeCommand && delivered > 0 && sender != nil {
+		hub.pending[message.MessageId] = &pendingCommand{sender: sender, targets: reached, deadline: now.Add(ackWait)}
+	}
+	return Message{MessageId: message.MessageId, Type: TypeReceipt, Status: receiptStatus(delivered > 0, delivered), DeliveredTo: delivered}
+}
+
+// deliver Never blocks the hub, a client with a full buffer misses the message
+func (hub *Hub) deliver(client *Client, message Message) bool {
+	select {
+	case client.send <- message:
+		return true
+	default:
+		return false
+	}
+}
+
+func receiptStatus(isDelivered bool, count int) string {
+	if isDelivered && count > 0 {
+		return StatusDelivered
+	}
+	return StatusUndeliverable
+}
+
+func (hub *Hub) List() []ClientInfo {
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+
+	clientList := []ClientInfo{}
+	for client := range hub.clients {
+		clientList = append(clientList, client.ClientInfo)
+	}
+	sort.Slice(clientList, func(i, j int) bool {
+		if clientList[i].Role != clientList[j].Role {
+			return clientList[i].Role < clientList[j].Role
+		}
+		return clientList[i].Id < clientList[j].Id
+	})
+	return clientList
+}
+
+func (client *Client) readPump() {
+	defer func() {
+		hub.unregister(client)
+		client.conn.Close()
+	}()
+	client.conn.SetReadLimit(maxMessageSize)
+	client.conn.SetReadDeadline(time.Now().Add(pongWait))
+	client.conn.SetPongHandler(func(string) error {
+		return client.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		message := Message{}
+		if err := client.conn.ReadJSON(&message); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Printf("Websocket of %v %v closed. Reason => %v\n", client.Role, client.Id, err)
+			}
+			return
+		}
+		if client.Role == RoleDashboard && message.Type != TypeCommand {
+			message.Type = TypeCommand
+		} else if client.Role != RoleDashboard && message.Type != TypeAck {
+			message.Type = TypeReply
+		}
+		receipt := hub.Route(client, message)
+		if message.Type != TypeAck {
+			hub.mu.RLock()
+			hub.deliver(client, receipt)
+			hub.mu.RUnlock()
+		}
+	}
+}
+
+func (client *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		client.conn.Close()
+	}()
+
+	for {
+		select {
+		case message, isOpen := <-client.send:
+			client.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !isOpen {
+				client.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := client.conn.WriteJSON(message); err != nil {
+				return
+			}
+		case <-ticker.C:
+			client.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := client.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// isAuthorized Checks the token of a role, HUB_<ROLE>_TOKEN in config.env, sent as Authorization: Bearer
+// or as the Token query param (browsers can't set headers on a WebSocket). A role without a token can't connect
+func isAuthorized(r *http.Request, role string) bool {
+	want := util.GetHubToken(role)
+	if want == "" {
+		log.Printf("Refused a hub %v, HUB_%v_TOKEN isn't set\n", role, strings.ToUpper(role))
+		return false
+	}
+	token := r.URL.Query().Get("Token")
+	if bearer := r.Header.Get("Authorization"); strings.HasPrefix(bearer, "Bearer ") {
+		token = strings.TrimPrefix(bearer, "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1
+}
+
+// Get Upgrades to a WebSocket
+// helmet:    /hub?Role=helmet&Id=<GroundNumber_HelmetNumber>&Token=<HUB_HELMET_TOKEN>
+// gateway:   /hub?Role=gateway&Id=<name>&Ground=<GroundNumber>&Token=<HUB_GATEWAY_TOKEN>
+// dashboard: /hub?Role=dashboard&Id=<name>&Token=<HUB_DASHBOARD_TOKEN>
+func Get(ctx *gin.Context) {
+	util.DebugPrint(FILENAME, "GET", "Just Test")
+	info := ClientInfo{Role: ctx.Query("Role"), Id: ctx.Query("Id"), Ground: ctx.Query("Ground"), ConnectedAt: time.Now()}
+
+	switch info.Role {
+	case RoleHelmet:
+		info.Ground = userinfo.GetSite(info.Id)
+	case RoleGateway:
+		if info.Ground == "" {
+			util.WriteError(ctx, http.StatusBadRequest, "Ground not provided")
+			return
+		}
+	case RoleDashboard:
+	default:
+		util.WriteError(ctx, http.StatusBadRequest, "Role must be helmet, gateway or dashboard")
+		return
+	}
+	if info.Id == "" {
+		util.WriteError(ctx, http.StatusBadRequest, "Id not provided")
+		return
+	}
+	if !isAuthorized(ctx.Request, info.Role) {
+		util.WriteError(ctx, http.StatusUnauthorized, "Token missing or wrong for "+info.Role)
+		return
+	}
+
+	if !checkOrigin(ctx.Request) {
+		util.WriteError(ctx, http.StatusForbidden, "Origin not allowed, add it to HUB_ALLOWED_ORIGINS")
+		return
+	}
+
+	conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		log.Printf("Couldn't upgrade websocket. Reason => %v\n", err)
+		return
+	}
+
+	client := &Client{ClientInfo: info, conn: conn, send: make(chan Message, sendBuffer)}
+	hub.register(client)
+	go client.writePump()
+	go client.readPump()
+}
+
+// Clients Lists connected helmets, gateways and dashboards
+func Clients(ctx *gin.Context) {
+	util.DebugPrint(FILENAME, "CLIENTS", "Just Test")
+	ctx.JSON(http.StatusOK, hub.List())
+}
+
+// Post Sends a command from a plain HTTP client holding the dashboard token, the response is the delivery receipt
+func Post(ctx *gin.Context) {
+	util.DebugPrint(FILENAME, "POST", "Just Test")
+	if !isAuthorized(ctx.Request, RoleDashboard) {
+		util.WriteError(ctx, http.StatusUnauthorized, "Token missing or wrong for "+RoleDashboard)
+		return
+	}
+	message := Message{}
+	if err := ctx.ShouldBindJSON(&message); err != nil || (message.To == "" && message.Ground == "") {
+		util.WriteError(ctx, http.StatusBadRequest, "To or Ground not provided")
+		return
+	}
+	message.Type = TypeCommand
+	ctx.JSON(http.StatusOK, hub.Route(nil, message))
+}
@@ -0,0 +1,201 @@
+package hub
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/spf13/viper"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	viper.Set("HUB_ALLOWED_ORIGINS", "https://dashboard.example.com/, http://localhost:3000")
+	defer viper.Set("HUB_ALLOWED_ORIGINS", "")
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"", true},
+		{"http://hub.example.com", true},
+		{"https://dashboard.example.com", true},
+		{"http://localhost:3000", true},
+		{"https://evil.example.com", false},
+		{"http://localhost:3001", false},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "http://hub.example.com/hub", nil)
+		if test.origin != "" {
+			r.Header.Set("Origin", test.origin)
+		}
+		if got := checkOrigin(r); got != test.want {
+			t.Errorf("checkOrigin(%q) = %v, want %v", test.origin, got, test.want)
+		}
+	}
+}
+
+func newTestServer(t *testing.T) string {
+	t.Helper()
+	for _, key := range []string{"HUB_HELMET_TOKEN", "HUB_GATEWAY_TOKEN", "HUB_DASHBOARD_TOKEN"} {
+		viper.Set(key, strings.ToLower(key))
+		t.Cleanup(func() { viper.Set(key, "") })
+	}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/hub", Get)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/hub"
+}
+
+func dial(t *testing.T, url string, header http.Header) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+func TestGetRejectsForeignOrigin(t *testing.T) {
+	url := newTestServer(t)
+	_, response, err := websocket.DefaultDialer.Dial(url+"?Role=dashboard&Id=screen&Token=hub_dashboard_token",
+		http.Header{"Origin": {"https://evil.example.com"}})
+	if err == nil || response == nil || response.StatusCode != http.StatusForbidden {
+		t.Fatalf("Dial from a foreign origin = %v, %v, want 403", response, err)
+	}
+}
+
+func TestGetNeedsTheRoleToken(t *testing.T) {
+	url := newTestServer(t)
+	tests := []struct {
+		query  string
+		header http.Header
+		want   int
+	}{
+		{"?Role=dashboard&Id=screen", nil, http.StatusUnauthorized},
+		{"?Role=dashboard&Id=screen&Token=wrong", nil, http.StatusUnauthorized},
+		{"?Role=dashboard&Id=screen&Token=hub_helmet_token", nil, http.StatusUnauthorized},
+		{"?Role=helmet&Id=1_2", http.Header{"Authorization": {"Bearer hub_dashboard_token"}}, http.StatusUnauthorized},
+		{"?Role=helmet&Id=1_2", http.Header{"Authorization": {"Bearer hub_helmet_token"}}, http.StatusSwitchingProtocols},
+		{"?Role=gateway&Id=relay&Ground=1&Token=hub_gateway_token", nil, http.StatusSwitchingProtocols},
+	}
+	for _, test := range tests {
+		conn, response, _ := websocket.DefaultDialer.Dial(url+test.query, test.header)
+		if conn != nil {
+			conn.Close()
+		}
+		if response == nil || response.StatusCode != test.want {
+			t.Errorf("Dial %v = %v, want %v", test.query, response, test.want)
+		}
+	}
+
+	viper.Set("HUB_GATEWAY_TOKEN", "")
+	if _, response, _ := websocket.DefaultDialer.Dial(url+"?Role=gateway&Id=relay&Ground=1&Token=", nil); response == nil ||
+		response.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Dial as a gateway without HUB_GATEWAY_TOKEN = %v, want 401", response)
+	}
+	for len(hub.List()) > 0 { // The closed connections leave before the next test counts clients
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPostNeedsTheDashboardToken(t *testing.T) {
+	newTestServer(t)
+	router := gin.New()
+	router.POST("/hub", Post)
+	for token, want := range map[string]int{"": http.StatusUnauthorized, "hub_helmet_token": http.StatusUnauthorized,
+		"hub_dashboard_token": http.StatusOK} {
+		req := httptest.NewRequest(http.MethodPost, "/hub", strings.NewReader(`{"To":"9_9"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		if recorder.Code != want {
+			t.Errorf("POST /hub with token %q = %v, want %v", token, recorder.Code, want)
+		}
+	}
+}
+
+func TestCommandReachesHelmetAndAckReachesDashboard(t *testing.T) {
+	url := newTestServer(t)
+	helmet := dial(t, url+"?Role=helmet&Id=1_2&Token=hub_helmet_token", nil)
+	other := dial(t, url+"?Role=helmet&Id=1_3&Token=hub_helmet_token", nil)
+	for len(hub.List()) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	dashboard := dial(t, url+"?Role=dashboard&Id=screen&Token=hub_dashboard_token", nil)
+	for len(hub.List()) < 3 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := dashboard.WriteJSON(Message{MessageId: "m1", To: "1_2", Body: []byte(`"evacuate"`)}); err != nil {
+		t.Fatal(err)
+	}
+	receipt := Message{}
+	if err := dashboard.ReadJSON(&receipt); err != nil || receipt.Status != StatusDelivered || receipt.DeliveredTo != 1 {
+		t.Fatalf("receipt = %v, %v, want delivered to 1", receipt, err)
+	}
+	if receipt.MessageId == "m1" || receipt.MessageId == "" {
+		t.Errorf("MessageId = %q, want one made by the hub", receipt.MessageId)
+	}
+	command := Message{}
+	if err := helmet.ReadJSON(&command); err != nil || command.Type != TypeCommand || command.From != "screen" ||
+		command.MessageId != receipt.MessageId {
+		t.Fatalf("helmet got %v, %v", command, err)
+	}
+
+	// 1_3 wasn't sent the command, its ack is refused and the command stays pending
+	if err := other.WriteJSON(Message{MessageId: command.MessageId, Type: TypeAck}); err != nil {
+		t.Fatal(err)
+	}
+	if err := helmet.WriteJSON(Message{MessageId: command.MessageId, Type: TypeAck}); err != nil {
+		t.Fatal(err)
+	}
+	ack := Message{}
+	if err := dashboard.ReadJSON(&ack); err != nil || ack.Status != StatusAcknowledged || ack.From != "1_2" {
+		t.Fatalf("dashboard got %v, %v, want the ack of 1_2", ack, err)
+	}
+}
+
+func newTestClient(role, id string) *Client {
+	return &Client{ClientInfo: ClientInfo{Role: role, Id: id, Ground: "1"}, send: make(chan Message, sendBuffer)}
+}
+
+func TestPendingCommands(t *testing.T) {
+	hub := NewHub()
+	dashboard, helmet := newTestClient(RoleDashboard, "screen"), newTestClient(RoleHelmet, "1_2")
+	hub.register(dashboard)
+	hub.register(helmet)
+
+	receipt := hub.Route(dashboard, Message{Type: TypeCommand, To: "1_2"})
+	if receipt.Status != StatusDelivered || len(hub.pending) != 1 {
+		t.Fatalf("receipt = %v with %v pending, want delivered and 1 pending", receipt, len(hub.pending))
+	}
+	<-helmet.send
+
+	// Past its deadline the command is dropped on the next message and the sender hears it expired
+	hub.pending[receipt.MessageId].deadline = time.Now().Add(-time.Second)
+	hub.Route(dashboard, Message{Type: TypeCommand, To: "9_9"})
+	if len(hub.pending) != 0 {
+		t.Errorf("%v commands still pending after the deadline", len(hub.pending))
+	}
+	if expired := <-dashboard.send; expired.MessageId != receipt.MessageId || expired.Status != StatusExpired {
+		t.Errorf("sender got %v, want the expiry of %v", expired, receipt.MessageId)
+	}
+	if ack := hub.Route(helmet, Message{Type: TypeAck, MessageId: receipt.MessageId}); ack.Status != StatusUndeliverable {
+		t.Errorf("late ack = %v, want undeliverable", ack)
+	}
+
+	// A command whose every target left can't be acked any more
+	receipt = hub.Route(dashboard, Message{Type: TypeCommand, To: "1_2"})
+	hub.unregister(helmet)
+	if _, isFound := hub.pending[receipt.MessageId]; isFound {
+		t.Error("command still pending after its only target left")
+	}
+}
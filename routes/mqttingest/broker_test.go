@@ -0,0 +1,243 @@
+package mqttingest
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// MQTT 3.1.1 control packet types
+const (
+	packetConnect     = 1
+	packetConnack     = 2
+	packetPublish     = 3
+	packetPuback      = 4
+	packetSubscribe   = 8
+	packetSuback      = 9
+	packetUnsubscribe = 10
+	packetUnsuback    = 11
+	packetPingreq     = 12
+	packetPingresp    = 13
+	packetDisconnect  = 14
+)
+
+// testBroker is just enough of an MQTT 3.1.1 broker for the subscriber: CONNECT, SUBSCRIBE with + and # filters,
+// PUBLISH at QoS 0 and 1, PINGREQ and DISCONNECT. Nothing is retained or queued for offline clients
+type testBroker struct {
+	listener net.Listener
+	mu       sync.Mutex
+	sessions map[*brokerSession]bool
+	wg       sync.WaitGroup
+}
+
+type brokerSession struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+	filters map[string]byte // Topic filter -> granted QoS
+	nextId  uint16
+}
+
+// startTestBroker Listens on a free localhost port until the test ends
+func startTestBroker(t *testing.T) *testBroker {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker := &testBroker{listener: listener, sessions: make(map[*brokerSession]bool)}
+	broker.wg.Add(1)
+	go broker.serve()
+	t.Cleanup(broker.close)
+	return broker
+}
+
+func (broker *testBroker) URL() string {
+	return "tcp://" + broker.listener.Addr().String()
+}
+
+func (broker *testBroker) serve() {
+	defer broker.wg.Done()
+	for {
+		conn, err := broker.listener.Accept()
+		if err != nil {
+			return
+		}
+		session := &brokerSession{conn: conn, filters: make(map[string]byte)}
+		broker.mu.Lock()
+		broker.sessions[session] = true
+		broker.mu.Unlock()
+		broker.wg.Add(1)
+		go func() {
+			defer broker.wg.Done()
+			broker.handle(session)
+			broker.mu.Lock()
+			delete(broker.sessions, session)
+			broker.mu.Unlock()
+			conn.Close()
+		}()
+	}
+}
+
+func (broker *testBroker) close() {
+	broker.listener.Close()
+	broker.mu.Lock()
+	for session := range broker.sessions {
+		session.conn.Close()
+	}
+	broker.mu.Unlock()
+	broker.wg.Wait()
+}
+
+// handle Answers one client's packets until it disconnects
+func (broker *testBroker) handle(session *brokerSession) {
+	reader := bufio.NewReader(session.conn)
+	for {
+		header, body, err := readPacket(reader)
+		if err != nil {
+			return
+		}
+		switch header >> 4 {
+		case packetConnect:
+			session.write(packetConnack<<4, []byte{0, 0}) // No session present, accepted
+		case packetSubscribe:
+			packetId, filters := body[:2], body[2:]
+			codes := []byte{}
+			for len(filters) > 0 {
+				filter, rest, err := readString(filters)
+				if err != nil || len(rest) == 0 {
+					return
+				}
+				qos := rest[0] & 3
+				if qos > 1 {
+					qos = 1
+				}
+				broker.mu.Lock()
+				session.filters[filter] = qos
+				broker.mu.Unlock()
+				codes = append(codes, qos)
+				filters = rest[1:]
+			}
+			session.write(packetSuback<<4, append(append([]byte{}, packetId...), codes...))
+		case packetUnsubscribe:
+			session.write(packetUnsuback<<4, body[:2])
+		case packetPublish:
+			qos := header >> 1 & 3
+			topic, rest, err := readString(body)
+			if err != nil {
+				return
+			}
+			if qos > 0 {
+				session.write(packetPuback<<4, rest[:2])
+				rest = rest[2:]
+			}
+			broker.publish(topic, qos, rest)
+		case packetPingreq:
+			session.write(packetPingresp<<4, nil)
+		case packetDisconnect:
+			return
+		}
+		// PUBACKs from subscribers need no answer, nothing is redelivered
+	}
+}
+
+// publish Forwards a message to every session with a matching filter, at the lower of both QoS
+func (broker *testBroker) publish(topic string, qos byte, payload []byte) {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	for session := range broker.sessions {
+		granted, isFound := byte(0), false
+		for filter, filterQoS := range session.filters {
+			if matchTopic(filter, topic) && (!isFound || filterQoS > granted) {
+				granted, isFound = filterQoS, true
+			}
+		}
+		if !isFound {
+			continue
+		}
+		if qos < granted {
+			granted = qos
+		}
+		body := appendString(nil, topic)
+		if granted > 0 {
+			session.nextId++
+			body = binary.BigEndian.AppendUint16(body, session.nextId)
+		}
+		session.write(packetPublish<<4|granted<<1, append(body, payload...))
+	}
+}
+
+func (session *brokerSession) write(header byte, body []byte) {
+	session.writeMu.Lock()
+	defer session.writeMu.Unlock()
+	packet := []byte{header}
+	length := len(body)
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 128
+		}
+		packet = append(packet, digit)
+		if length == 0 {
+			break
+		}
+	}
+	session.conn.Write(append(packet, body...))
+}
+
+// readPacket Reads the fixed header byte and the body of one control packet
+func readPacket(reader *bufio.Reader) (byte, []byte, error) {
+	header, err := reader.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		digit, err := reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		if i == 4 {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+		length += int(digit&127) * multiplier
+		multiplier *= 128
+		if digit&128 == 0 {
+			break
+		}
+	}
+	body := make([]byte, length)
+	_, err = io.ReadFull(reader, body)
+	return header, body, err
+}
+
+func readString(data []byte) (string, []byte, error) {
+	if len(data) < 2 || len(data) < 2+int(binary.BigEndian.Uint16(data)) {
+		return "", nil, errors.New("malformed string")
+	}
+	end := 2 + int(binary.BigEndian.Uint16(data))
+	return string(data[2:end]), data[end:], nil
+}
+
+func appendString(data []byte, value string) []byte {
+	return append(binary.BigEndian.AppendUint16(data, uint16(len(value))), value...)
+}
+
+// matchTopic Applies the + (one level) and # (every remaining level) wildcards of a filter
+func matchTopic(filter, topic string) bool {
+	filterLevels, topicLevels := strings.Split(filter, "/"), strings.Split(topic, "/")
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+		if i == len(topicLevels) || (level != "+" && level != topicLevels[i]) {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
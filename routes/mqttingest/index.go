@@ -0,0 +1,181 @@
+/*
+Mqttingest Package subscribes to helmet gateways on an MQTT broker
+Telemetry goes through the same path as POST /userinfo, SOS messages through POST /danger
+Topics are <MQTT_TOPIC_PREFIX>/{ground}/helmet/{helmet}/telemetry and .../sos, payloads are RawWorkerInfo JSON
+Only active when MQTT_BROKER is set
+*/
+
+package mqttingest
+
+import (
+	"encoding/json"
+	"fmt"
+	"go_backend/routes/danger"
+	"go_backend/routes/userinfo"
+	"go_backend/util"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/gin-gonic/gin"
+)
+
+const FILENAME = "mqttingest/index.go"
+
+// Message kinds, the last level of a helmet topic
+const (
+	KindTelemetry = "telemetry"
+	KindSOS       = "sos"
+)
+
+type Status struct {
+	Enabled       bool
+	Broker        string `json:",omitempty"`
+	Connected     bool
+	Received      uint64
+	Duplicates    uint64
+	Rejected      uint64
+	LastMessageAt *time.Time
+	LastError     string
+}
+
+var mu sync.Mutex
+var status Status
+
+// Where Handle sends decoded messages, tests point them elsewhere
+var ingest = userinfo.Ingest
+var raiseReading = danger.RaiseReading
+
+// ParseTopic Splits <prefix>/{ground}/helmet/{helmet}/{kind}, false for any other topic
+func ParseTopic(prefix, topic string) (ground, helmet, kind string, isValid bool) {
+	levels := strings.Split(strings.TrimPrefix(topic, prefix+"/"), "/")
+	if !strings.HasPrefix(topic, prefix+"/") || len(levels) != 4 || levels[1] != "helmet" ||
+		levels[0] == "" || levels[2] == "" {
+		return "", "", "", false
+	}
+	if levels[3] != KindTelemetry && levels[3] != KindSOS {
+		return "", "", "", false
+	}
+	return levels[0], levels[2], levels[3], true
+}
+
+// Handle Decodes one message and feeds it to the ingest or danger path
+// Ground and helmet numbers always come from the topic, a gateway can't write for another helmet
+func Handle(prefix, topic string, payload []byte) (bool, error) {
+	ground, helmet, kind, isValid := ParseTopic(prefix, topic)
+	if !isValid {
+		return false, fmt.Errorf("unexpected topic %v", topic)
+	}
+	rawWorkInfo := &userinfo.RawWorkerInfo{}
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, rawWorkInfo); err != nil {
+			return false, fmt.Errorf("invalid payload on %v: %v", topic, err)
+		}
+	}
+	rawWorkInfo.GroundNumber = ground
+	rawWorkInfo.HelmetNumber = helmet
+
+	if kind == KindSOS {
+		if rawWorkInfo.DangerType == "" {
+			rawWorkInfo.DangerType = danger.DangerSOS
+		}
+		_, isNew, err := raiseReading(rawWorkInfo)
+		if err == danger.ErrInProgress {
+			return false, nil
+		}
+		return isNew, err
+	}
+	_, isNew, err := ingest(rawWorkInfo)
+	return isNew, err
+}
+
+func record(isNew bool, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	now := time.Now()
+	status.LastMessageAt = &now
+	switch {
+	case err != nil:
+		status.Rejected++
+		status.LastError = err.Error()
+	case isNew:
+		status.Received++
+	default:
+		status.Duplicates++
+	}
+}
+
+func setConnected(connected bool, reason error) {
+	mu.Lock()
+	defer mu.Unlock()
+	status.Connected = connected
+	if reason != nil {
+		status.LastError = reason.Error()
+	}
+}
+
+// Start Connects to MQTT_BROKER and subscribes to every helmet topic, reconnecting on its own
+// Returns once the first connection attempt is made, messages are handled on the client's goroutines
+func Start(pLog *log.Logger, eLog *log.Logger) {
+	client := newClient(pLog, eLog)
+	client.Connect() // With ConnectRetry the token completes only once connected, nothing to wait for
+	pLog.Println("MQTT ingestion Started")
+}
+
+// newClient Builds the subscriber for MQTT_BROKER, it subscribes on every (re)connect
+func newClient(pLog *log.Logger, eLog *log.Logger) mqtt.Client {
+	prefix := util.GetMQTTTopicPrefix()
+	qos := util.GetMQTTQoS()
+	topics := map[string]byte{
+		prefix + "/+/helmet/+/" + KindTelemetry: qos,
+		prefix + "/+/helmet/+/" + KindSOS:       qos,
+	}
+	mu.Lock()
+	status.Enabled = true
+	status.Broker = util.GetMQTTBroker()
+	mu.Unlock()
+
+	onMessage := func(client mqtt.Client, message mqtt.Message) {
+		isNew, err := Handle(prefix, message.Topic(), message.Payload())
+		if err != nil {
+			eLog.Printf("Couldn't ingest MQTT message. Reason => %v\n", err)
+		}
+		record(isNew, err)
+	}
+
+	options := mqtt.NewClientOptions().
+		AddBroker(util.GetMQTTBroker()).
+		SetClientID(util.GetMQTTClientId()).
+		SetUsername(util.GetMQTTUsername()).
+		SetPassword(util.GetMQTTPassword()).
+		SetCleanSession(false). // The broker keeps QoS 1 messages for us while we are down
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(5 * time.Second).
+		SetOnConnectHandler(func(client mqtt.Client) {
+			// Subscriptions are renewed on every reconnect
+			if token := client.SubscribeMultiple(topics, onMessage); token.Wait() && token.Error() != nil {
+				eLog.Printf("Couldn't subscribe to helmet topics. Reason => %v\n", token.Error())
+				setConnected(true, token.Error())
+				return
+			}
+			pLog.Printf("MQTT subscribed to %v\n", util.GetMQTTBroker())
+			setConnected(true, nil)
+		}).
+		SetConnectionLostHandler(func(client mqtt.Client, err error) {
+			eLog.Printf("MQTT connection lost, reconnecting. Reason => %v\n", err)
+			setConnected(false, err)
+		})
+	return mqtt.NewClient(options)
+}
+
+// Get Reports the subscriber's connection and message counters
+func Get(ctx *gin.Context) {
+	util.DebugPrint(FILENAME, "GET", "Just Test")
+	mu.Lock()
+	defer mu.Unlock()
+	ctx.JSON(http.StatusOK, status)
+}
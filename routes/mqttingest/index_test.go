@@ -0,0 +1,214 @@
+package mqttingest
+
+import (
+	"errors"
+	"go_backend/routes/danger"
+	"go_backend/routes/userinfo"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/spf13/viper"
+)
+
+func TestParseTopic(t *testing.T) {
+	tests := []struct {
+		topic                string
+		ground, helmet, kind string
+		isValid              bool
+	}{
+		{"site/1/helmet/2/telemetry", "1", "2", KindTelemetry, true},
+		{"site/G1/helmet/H2/sos", "G1", "H2", KindSOS, true},
+		{"site/1/helmet/2/status", "", "", "", false},
+		{"site/1/helmet/2", "", "", "", false},
+		{"site/1/helmet/2/telemetry/extra", "", "", "", false},
+		{"site/1/vest/2/telemetry", "", "", "", false},
+		{"site//helmet/2/telemetry", "", "", "", false},
+		{"site/1/helmet//telemetry", "", "", "", false},
+		{"other/1/helmet/2/telemetry", "", "", "", false},
+		{"sites/1/helmet/2/telemetry", "", "", "", false},
+		{"1/helmet/2/telemetry", "", "", "", false},
+	}
+	for _, test := range tests {
+		ground, helmet, kind, isValid := ParseTopic("site", test.topic)
+		if isValid != test.isValid || ground != test.ground || helmet != test.helmet || kind != test.kind {
+			t.Errorf("ParseTopic(%v) = %v, %v, %v, %v", test.topic, ground, helmet, kind, isValid)
+		}
+	}
+}
+
+// handled is what Handle passed on, and on which path
+type handled struct {
+	kind string
+	raw  userinfo.RawWorkerInfo
+}
+
+// useFakePaths Records what Handle passes on instead of storing it, answering with isNew and err
+func useFakePaths(t *testing.T, isNew bool, err error) chan handled {
+	t.Helper()
+	received := make(chan handled, 10)
+	previousIngest, previousRaise := ingest, raiseReading
+	ingest = func(rawWorkInfo *userinfo.RawWorkerInfo) (*userinfo.WorkerInfo, bool, error) {
+		received <- handled{KindTelemetry, *rawWorkInfo}
+		return rawWorkInfo.ConvertToWorkInfo(), isNew, err
+	}
+	raiseReading = func(rawWorkInfo *userinfo.RawWorkerInfo) (danger.Alert, bool, error) {
+		received <- handled{KindSOS, *rawWorkInfo}
+		return danger.Alert{}, isNew, err
+	}
+	t.Cleanup(func() { ingest, raiseReading = previousIngest, previousRaise })
+	return received
+}
+
+func TestHandle(t *testing.T) {
+	received := useFakePaths(t, true, nil)
+	tests := []struct {
+		topic   string
+		payload string
+		want    *handled
+	}{
+		{"site/1/helmet/2/telemetry", `{"Spo2Level":97,"HeartRate":80}`,
+			&handled{KindTelemetry, userinfo.RawWorkerInfo{GroundNumber: "1", HelmetNumber: "2", Spo2Level: 97, HeartRate: 80}}},
+		// The topic names the helmet, the payload can't write for another one
+		{"site/1/helmet/2/telemetry", `{"GroundNumber":"9","HelmetNumber":"9"}`,
+			&handled{KindTelemetry, userinfo.RawWorkerInfo{GroundNumber: "1", HelmetNumber: "2"}}},
+		{"site/1/helmet/2/sos", ``,
+			&handled{KindSOS, userinfo.RawWorkerInfo{GroundNumber: "1", HelmetNumber: "2", DangerType: danger.DangerSOS}}},
+		{"site/1/helmet/2/sos", `{"DangerType":"Water"}`,
+			&handled{KindSOS, userinfo.RawWorkerInfo{GroundNumber: "1", HelmetNumber: "2", DangerType: "Water"}}},
+		{"site/1/helmet/2/telemetry", `{"Spo2Level":"high"}`, nil},
+		{"site/1/helmet/2/telemetry", `not json`, nil},
+		{"site/1/helmet/2/status", `{}`, nil},
+	}
+	for _, test := range tests {
+		isNew, err := Handle("site", test.topic, []byte(test.payload))
+		if test.want == nil {
+			if err == nil || isNew {
+				t.Errorf("Handle(%v, %q) = %v, %v, want an error", test.topic, test.payload, isNew, err)
+			}
+			continue
+		}
+		if err != nil || !isNew {
+			t.Errorf("Handle(%v, %q) = %v, %v", test.topic, test.payload, isNew, err)
+			continue
+		}
+		if got := <-received; got != *test.want {
+			t.Errorf("Handle(%v, %q) passed on %+v, want %+v", test.topic, test.payload, got, *test.want)
+		}
+	}
+	select {
+	case got := <-received:
+		t.Errorf("a rejected message was passed on: %+v", got)
+	default:
+	}
+}
+
+func TestHandleOutcomes(t *testing.T) {
+	useFakePaths(t, false, danger.ErrInProgress)
+	if isNew, err := Handle("site", "site/1/helmet/2/sos", nil); isNew || err != nil {
+		t.Errorf("SOS still being raised = %v, %v, want a duplicate", isNew, err)
+	}
+
+	storeErr := errors.New("store is down")
+	useFakePaths(t, false, storeErr)
+	if _, err := Handle("site", "site/1/helmet/2/telemetry", nil); !errors.Is(err, storeErr) {
+		t.Errorf("Handle error = %v, want the ingest error", err)
+	}
+}
+
+// useMQTTConfig Points the subscriber at broker, restoring the previous status when the test ends
+func useMQTTConfig(t *testing.T, broker *testBroker) {
+	t.Helper()
+	for key, value := range map[string]string{"MQTT_BROKER": broker.URL(), "MQTT_CLIENT_ID": "smlr-test", "MQTT_TOPIC_PREFIX": "site"} {
+		viper.Set(key, value)
+	}
+	mu.Lock()
+	previous := status
+	status = Status{}
+	mu.Unlock()
+	t.Cleanup(func() {
+		for _, key := range []string{"MQTT_BROKER", "MQTT_CLIENT_ID", "MQTT_TOPIC_PREFIX"} {
+			viper.Set(key, "")
+		}
+		mu.Lock()
+		status = previous
+		mu.Unlock()
+	})
+}
+
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %v", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRoundTripThroughBroker(t *testing.T) {
+	broker := startTestBroker(t)
+	useMQTTConfig(t, broker)
+	received := useFakePaths(t, true, nil)
+
+	quiet := log.New(io.Discard, "", 0)
+	subscriber := newClient(quiet, quiet)
+	if token := subscriber.Connect(); !token.WaitTimeout(5*time.Second) || token.Error() != nil {
+		t.Fatalf("subscriber couldn't connect: %v", token.Error())
+	}
+	defer subscriber.Disconnect(100)
+	waitFor(t, "the subscription", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return status.Connected
+	})
+
+	gateway := mqtt.NewClient(mqtt.NewClientOptions().AddBroker(broker.URL()).SetClientID("gateway"))
+	if token := gateway.Connect(); !token.WaitTimeout(5*time.Second) || token.Error() != nil {
+		t.Fatalf("gateway couldn't connect: %v", token.Error())
+	}
+	defer gateway.Disconnect(100)
+
+	messages := []struct {
+		topic   string
+		payload string
+	}{
+		{"site/3/helmet/7/telemetry", `{"Spo2Level":95}`},
+		{"site/3/helmet/7/sos", `{}`},
+		{"site/3/vest/7/telemetry", `{}`}, // Not subscribed to, never arrives
+		{"site/3/helmet/8/telemetry", `broken`},
+	}
+	for _, message := range messages {
+		if token := gateway.Publish(message.topic, 1, false, message.payload); !token.WaitTimeout(5*time.Second) || token.Error() != nil {
+			t.Fatalf("publish to %v failed: %v", message.topic, token.Error())
+		}
+	}
+
+	want := []handled{
+		{KindTelemetry, userinfo.RawWorkerInfo{GroundNumber: "3", HelmetNumber: "7", Spo2Level: 95}},
+		{KindSOS, userinfo.RawWorkerInfo{GroundNumber: "3", HelmetNumber: "7", DangerType: danger.DangerSOS}},
+	}
+	for _, expected := range want {
+		select {
+		case got := <-received:
+			if got != expected {
+				t.Errorf("received %+v, want %+v", got, expected)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%v message never arrived", expected.kind)
+		}
+	}
+	waitFor(t, "the broken message", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return status.Rejected == 1
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	if status.Received != 2 || status.Duplicates != 0 || status.LastMessageAt == nil {
+		t.Errorf("status = %+v, want 2 received and 1 rejected", status)
+	}
+}
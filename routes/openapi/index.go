@@ -0,0 +1,414 @@
+/*
+Openapi Package builds the OpenAPI 3 document of the REST API from the route table in routes/routes.go
+Schemas are reflected from the Go structs the handlers bind and return, so they follow the code
+The committed openapi.json is checked against it by the routes package tests
+*/
+
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const Version = "3.0.3"
+
+// Param is a query, header or path parameter, path parameters are added from the route path when missing
+type Param struct {
+	Name        string
+	In          string // query, header or path
+	Description string
+	Required    bool
+	Type        string // string, integer, number or boolean
+	Array       bool   // Repeated, ?risk=high&risk=medium
+}
+
+func Query(name, typ, description string) Param {
+	return Param{Name: name, In: "query", Type: typ, Description: description}
+}
+
+func QueryArray(name, description string) Param {
+	return Param{Name: name, In: "query", Type: "string", Array: true, Description: description}
+}
+
+func Header(name, description string) Param {
+	return Param{Name: name, In: "header", Type: "string", Description: description}
+}
+
+// Response is one documented status of an operation, Body is a value of the Go type written (nil for none)
+type Response struct {
+	Status      int
+	Description string
+	Body        interface{}
+	ContentType string // application/json when Body is set and this is empty
+}
+
+func JSON(status int, description string, body interface{}) Response {
+	return Response{Status: status, Description: description, Body: body}
+}
+
+// Text Documents a plain text body
+func Text(status int, description string) Response {
+	return Response{Status: status, Description: description, Body: "", ContentType: "text/plain"}
+}
+
+func Empty(status int, description string) Response {
+	return Response{Status: status, Description: description}
+}
+
+// OneOf Documents a body that is one of several Go types, depending on the request
+type OneOf []interface{}
+
+// Operation documents one route
+type Operation struct {
+	Method      string
+	Path        string // gin syntax, /userinfo/:id/readings
+	OperationId string
+	Summary     string
+	Params      []Param
+	Body        interface{} // Value of the Go type bound from the JSON body, nil for none
+	BodyTypes   []string    // Other accepted body content types, documented as binary
+	Responses   []Response
+}
+
+type Document struct {
+	OpenAPI    string                            `json:"openapi"`
+	Info       Info                              `json:"info"`
+	Paths      map[string]map[string]*Operation3 `json:"paths"`
+	Components Components                        `json:"components"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Operation3 is the OpenAPI form of an Operation
+type Operation3 struct {
+	OperationId string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response3 `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response3 struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// builder collects the component schemas while operations are converted
+type builder struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+	shared  map[string]bool // Type names used by structs of several packages
+}
+
+// Build Returns the document of operations, every path is prefixed with basePath
+func Build(title, version, basePath string, operations []Operation) *Document {
+	b := &builder{schemas: make(map[string]*Schema), names: make(map[reflect.Type]string), shared: sharedNames(operations)}
+	document := &Document{
+		OpenAPI:    Version,
+		Info:       Info{Title: title, Version: version},
+		Paths:      make(map[string]map[string]*Operation3),
+		Components: Components{Schemas: b.schemas},
+	}
+	for i := range operations {
+		path, operation := b.operation(basePath, &operations[i])
+		if document.Paths[path] == nil {
+			document.Paths[path] = make(map[string]*Operation3)
+		}
+		document.Paths[path][strings.ToLower(operations[i].Method)] = operation
+	}
+	return document
+}
+
+// MarshalIndent Returns the document as written to openapi.json
+func (document *Document) MarshalIndent() ([]byte, error) {
+	data, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// OpenAPIPath Converts a gin path (:id) to an OpenAPI one ({id}) and returns its parameter names
+func OpenAPIPath(path string) (string, []string) {
+	var names []string
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			names = append(names, segment[1:])
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/"), names
+}
+
+func (b *builder) operation(basePath string, operation *Operation) (string, *Operation3) {
+	path, pathParams := OpenAPIPath(basePath + operation.Path)
+	tag := strings.Split(strings.TrimPrefix(operation.Path, "/"), "/")[0]
+	converted := &Operation3{
+		OperationId: operation.OperationId,
+		Summary:     operation.Summary,
+		Responses:   make(map[string]*Response3),
+	}
+	if tag != "" {
+		converted.Tags = []string{tag}
+	}
+
+	params := operation.Params
+	for _, name := range pathParams {
+		if !hasParam(params, name, "path") {
+			params = append([]Param{{Name: name, In: "path", Type: "string"}}, params...)
+		}
+	}
+	for _, param := range params {
+		schema := &Schema{Type: param.Type}
+		if param.Array {
+			// Form style, the default for query params, repeats the name for every value
+			schema = &Schema{Type: "array", Items: &Schema{Type: param.Type}}
+		}
+		converted.Parameters = append(converted.Parameters, Parameter{
+			Name: param.Name, In: param.In, Description: param.Description,
+			Required: param.Required || param.In == "path", Schema: schema,
+		})
+	}
+
+	if operation.Body != nil || len(operation.BodyTypes) > 0 {
+		converted.RequestBody = &RequestBody{Required: true, Content: make(map[string]*MediaType)}
+		if operation.Body != nil {
+			converted.RequestBody.Content["application/json"] = &MediaType{Schema: b.schema(operation.Body)}
+		}
+		for _, contentType := range operation.BodyTypes {
+			converted.RequestBody.Content[contentType] = &MediaType{Schema: &Schema{Type: "string", Format: "binary"}}
+		}
+	}
+
+	for _, response := range operation.Responses {
+		status := fmt.Sprint(response.Status)
+		description := response.Description
+		if description == "" {
+			description = http.StatusText(response.Status)
+		}
+		converted.Responses[status] = &Response3{Description: description}
+		if response.Body == nil {
+			continue
+		}
+		contentType := response.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		converted.Responses[status].Content = map[string]*MediaType{contentType: {Schema: b.schema(response.Body)}}
+	}
+	return path, converted
+}
+
+func hasParam(params []Param, name, in string) bool {
+	for _, param := range params {
+		if param.Name == name && param.In == in {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *builder) schema(value interface{}) *Schema {
+	if oneOf, isOneOf := value.(OneOf); isOneOf {
+		schema := &Schema{}
+		for _, option := range oneOf {
+			schema.OneOf = append(schema.OneOf, b.schema(option))
+		}
+		return schema
+	}
+	return b.typeSchema(reflect.TypeOf(value))
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+func (b *builder) typeSchema(typ reflect.Type) *Schema {
+	zero := 0.0
+	switch {
+	case typ == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case typ == rawMessageType:
+		return &Schema{} // Any JSON value
+	}
+
+	switch typ.Kind() {
+	case reflect.Pointer:
+		element := b.typeSchema(typ.Elem())
+		if element.Ref != "" {
+			return &Schema{AllOf: []*Schema{element}, Nullable: true}
+		}
+		element.Nullable = true
+		return element
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32", Minimum: &zero}
+	case reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64", Minimum: &zero}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: b.typeSchema(typ.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.typeSchema(typ.Elem())}
+	case reflect.Struct:
+		return b.structRef(typ)
+	}
+	return &Schema{} // interface{} and anything else JSON can hold
+}
+
+// structRef Adds a named struct to the components and returns a reference to it
+func (b *builder) structRef(typ reflect.Type) *Schema {
+	if typ.Name() == "" {
+		return b.structSchema(typ)
+	}
+	name, isKnown := b.names[typ]
+	if !isKnown {
+		name = componentName(typ, b.shared)
+		b.names[typ] = name
+		b.schemas[name] = &Schema{} // Placeholder, a struct may refer to itself
+		*b.schemas[name] = *b.structSchema(typ)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// componentName Names a struct after its type, generic Page[pkg.WorkerInfo] becomes WorkerInfoPage
+// A name shared by several packages gets the package name in front (MqttingestStatus)
+func componentName(typ reflect.Type, shared map[string]bool) string {
+	name := typ.Name()
+	if base, argument, isGeneric := strings.Cut(name, "["); isGeneric {
+		argument = strings.TrimSuffix(argument, "]")
+		name = argument[strings.LastIndex(argument, ".")+1:] + base
+	}
+	if shared[name] {
+		pkg := typ.PkgPath()[strings.LastIndex(typ.PkgPath(), "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	return name
+}
+
+// sharedNames Finds the struct names used by more than one type across every body of operations
+func sharedNames(operations []Operation) map[string]bool {
+	seen := make(map[reflect.Type]bool)
+	owners := make(map[string]reflect.Type)
+	shared := make(map[string]bool)
+	var walk func(typ reflect.Type)
+	walk = func(typ reflect.Type) {
+		for typ.Kind() == reflect.Pointer || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array || typ.Kind() == reflect.Map {
+			typ = typ.Elem()
+		}
+		if typ.Kind() != reflect.Struct || seen[typ] {
+			return
+		}
+		seen[typ] = true
+		name := componentName(typ, nil)
+		if owner, isFound := owners[name]; isFound && owner != typ {
+			shared[name] = true
+		}
+		owners[name] = typ
+		for i := 0; i < typ.NumField(); i++ {
+			walk(typ.Field(i).Type)
+		}
+	}
+	var walkValue func(value interface{})
+	walkValue = func(value interface{}) {
+		if oneOf, isOneOf := value.(OneOf); isOneOf {
+			for _, option := range oneOf {
+				walkValue(option)
+			}
+		} else if value != nil {
+			walk(reflect.TypeOf(value))
+		}
+	}
+	for _, operation := range operations {
+		walkValue(operation.Body)
+		for _, response := range operation.Responses {
+			walkValue(response.Body)
+		}
+	}
+	return shared
+}
+
+func (b *builder) structSchema(typ reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	b.addFields(schema, typ)
+	return schema
+}
+
+// addFields Adds the properties encoding/json writes for a struct, embedded structs are flattened
+func (b *builder) addFields(schema *Schema, typ reflect.Type) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			b.addFields(schema, field.Type)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = b.typeSchema(field.Type)
+	}
+}
@@ -1 +1,416 @@
+/*
+Routes Package holds the table of every REST route, main.go registers it under /v1 and, for helmets already
+deployed, at the unversioned paths too. The same table builds the OpenAPI document served at /openapi.json
+Adding a route means adding an entry here, with the Go types its handler binds and writes
+*/
+
 package routes
+
+import (
+	"go_backend/routes/about"
+	"go_backend/routes/contacts"
+	"go_backend/routes/danger"
+	"go_backend/routes/edgesync"
+	"go_backend/routes/exposure"
+	"go_backend/routes/hospital"
+	"go_backend/routes/hub"
+	"go_backend/routes/index"
+	"go_backend/routes/mqttingest"
+	"go_backend/routes/openapi"
+	"go_backend/routes/rules"
+	"go_backend/routes/table"
+	"go_backend/routes/udpingest"
+	"go_backend/routes/userinfo"
+	"go_backend/telemetry"
+	"go_backend/util"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const FILENAME = "routes/routes.go"
+
+// Title and Version of the OpenAPI document, Version follows the /v1 prefix
+const Title = "Smlr-Backend"
+const Version = "1"
+const BasePath = "/v1"
+
+type Route struct {
+	openapi.Operation
+	Handler gin.HandlerFunc
+}
+
+var pageParams = []openapi.Param{
+	openapi.Query("limit", "integer", "Page size, returns a page object instead of the full list"),
+	openapi.Query("cursor", "string", "nextCursor of the previous page"),
+	openapi.Query("format", "string", "ndjson streams every entry, one JSON object per line"),
+}
+
+var readingsParams = []openapi.Param{
+	openapi.Query("from", "string", "RFC 3339 or the helmet's date format, defaults to 24 hours before to"),
+	openapi.Query("to", "string", "Defaults to now"),
+	openapi.Query("range", "string", "today, yesterday or lastNd, wins over from and to"),
+}
+
+// Table Every REST route, in the order main.go used to register them
+var Table = []Route{
+	{Operation: openapi.Operation{
+		Method: http.MethodGet, Path: "/", OperationId: "getIndex", Summary: "Liveness check",
+		Responses: []openapi.Response{openapi.Text(http.StatusOK, "")},
+	}, Handler: index.Get},
+
+	{Operation: openapi.Operation{
+		Method: http.MethodGet, Path: "/about", OperationId: "getAbout", Summary: "About the deployment",
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "", about.About{})},
+	}, Handler: about.Get},
+	{Operation: openapi.Operation{
+		Method: http.MethodPost, Path: "/about", OperationId: "updateAbout", Summary: "Replace the about entry",
+		Body: about.About{},
+		Responses: []openapi.Response{
+			openapi.Empty(http.StatusOK, ""),
+			errorResponse(http.StatusBadRequest, "Body isn't an About"),
+		},
+	}, Handler: about.Post},
+
+	{Operation: openapi.Operation{
+		Method: http.MethodGet, Path: "/contacts", OperationId: "listContacts", Summary: "Emergency contacts",
+		Params: pageParams,
+		Responses: append([]openapi.Response{
+			openapi.JSON(http.StatusOK, "Every contact, or one page with limit or cursor", openapi.OneOf{[]contacts.Contact{}, util.Page[contacts.Contact]{}}),
+			errorResponse(http.StatusBadRequest, "Invalid limit or cursor"),
+		}, storeErrors...),
+	}, Handler: contacts.Get},
+	{Operation: openapi.Operation{
+		Method: http.MethodPost, Path: "/contacts", OperationId: "insertContact", Summary: "Add an emergency contact",
+		Body: contacts.Contact{},
+		Responses: append([]openapi.Response{
+			openapi.Empty(http.StatusOK, ""),
+			errorResponse(http.StatusBadRequest, "Body isn't a Contact"),
+		}, storeErrors...),
+	}, Handler: contacts.Post},
+	{Operation: openapi.Operation{
+		Method: http.MethodDelete, Path: "/contacts", OperationId: "deleteContact", Summary: "Remove an emergency contact",
+		Params: []openapi.Param{{Name: "PhoneNumber", In: "query", Type: "string", Required: true}},
+		Responses: append([]openapi.Response{
+			openapi.Empty(http.StatusOK, ""),
+			errorResponse(http.StatusBadRequest, "PhoneNumber not provided"),
+			errorResponse(http.StatusNotFound, "No contact with that PhoneNumber"),
+		}, storeErrors...),
+	}, Handler: contacts.Delete},
+
+	{Operation: openapi.Operation{
+		Method: http.MethodGet, Path: "/danger", OperationId: "listAlerts", Summary: "Danger alerts, pending and acknowledged by default",
+		Params: []openapi.Param{openapi.QueryArray("State", "pending, acknowledged or resolved")},
+		Responses: []openapi.Response{
+			openapi.JSON(http.StatusOK, "", []danger.Alert{}),
+			errorResponse(http.StatusInternalServerError, queueFailed),
+		},
+	}, Handler: danger.Get},
+	{Operation: openapi.Operation{
+		Method: http.MethodPost, Path: "/danger", OperationId: "raiseAlert", Summary: "Raise an alert from a helmet reading",
+		Body: userinfo.RawWorkerInfo{}, BodyTypes: []string{telemetry.ContentType},
+		Params: []openapi.Param{openapi.Header(userinfo.IdempotencyHeader, "Retries with the same key return the first alert")},
+		Responses: []openapi.Response{
+			openapi.JSON(http.StatusCreated, "New alert", danger.Alert{}),
+			openapi.JSON(http.StatusOK, "Replayed, the "+userinfo.ReplayedHeader+" header is set", danger.Alert{}),
+			errorResponse(http.StatusBadRequest, "Invalid reading or frame"),
+			errorResponse(http.StatusConflict, "The same reading is still being raised"),
+			errorResponse(http.StatusInternalServerError, queueFailed),
+		},
+	}, Handler: danger.Post},
+	{Operation: openapi.Operation{
+		Method: http.MethodGet, Path: "/danger/stream", OperationId: "streamAlerts", Summary: "Server-sent events of new alerts",
+		Params: []openapi.Param{
+			openapi.QueryArray("Ground", "Only alerts of these grounds"),
+			openapi.QueryArray("DangerType", "Only alerts of these danger types"),
+			openapi.Query("lastEventId", "integer", "Replays alerts after this id, for clients that can't set Last-Event-ID"),
+			openapi.Header("Last-Event-ID", "Replays alerts after this id"),
+		},
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Description: "One event per alert, the data is an Alert", Body: "", ContentType: "text/event-stream"},
+			errorResponse(http.StatusBadRequest, "Invalid Last-Event-ID"),
+			errorResponse(http.StatusInternalServerError, queueFailed),
+		},
+	}, Handler: danger.Stream},
+	{Operation: openapi.Operation{
+		Method: http.MethodPost, Path: "/danger/:id/ack", OperationId: "acknowledgeAlert", Summary: "Acknowledge a pending alert",
+		Body:      danger.AlertAction{},
+		Responses: alertActionResponses,
+	}, Handler: danger.Ack},
+	{Operation: openapi.Operation{
+		Method: http.MethodPost, Path: "/danger/:id/resolve", OperationId: "resolveAlert", Summary: "Resolve an alert",
+		Body:      danger.AlertAction{},
+		Responses: alertActionResponses,
+	}, Handler: danger.Resolve},
+
+	{Operation: openapi.Operation{
+		Method: http.MethodGet, Path: "/hospital", OperationId: "listHospitals", Summary: "Nearby hospitals",
+		Params: pageParams,
+		Responses: append([]openapi.Response{
+			openapi.JSON(http.StatusOK, "Every hospital, or one page with limit or cursor", openapi.OneOf{[]hospital.Hospital{}, util.Page[hospital.Hospital]{}}),
+			errorResponse(http.StatusBadRequest, "Invalid limit or cursor"),
+		}, storeErrors...),
+	}, Handler: hospital.Get},
+	{Operation: openapi.Operation{
+		Method: http.MethodPost, Path: "/hospital", OperationId: "insertHospital", Summary: "Add a hospital",
+		Body: hospital.Hospital{},
+		Responses: append([]openapi.Response{
+			openapi.Empty(http.StatusOK, ""),
+			errorResponse(http.StatusBadRequest, "Body isn't a Hospital"),
+		}, storeErrors...),
+	}, Handler: hospital.Post},
+	{Operation: openapi.Operation{
+		Method: http.MethodDelete, Path: "/hospital", OperationId: "deleteHospital", Summary: "Remove a hospital",
+		Params: []openapi.Param{{Name: "PhoneNumber", In: "query", Type: "string", Required: true}},
+		Responses: append([]openapi.Response{
+			openapi.Empty(http.StatusOK, ""),
+			errorResponse(http.StatusBadRequest, "PhoneNumber not provided"),
+			errorResponse(http.StatusNotFound, "No hospital with that PhoneNumber"),
+		}, storeErrors...),
+	}, Handler: hospital.Delete},
+
+	{Operation: openapi.Operation{
+		Method: http.MethodGet, Path: "/table", OperationId: "listTableOperations", Summary: "Table operations run since start",
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "", table.TableOperationList{})},
+	}, Handler: table.Get},
+	{Operation: openapi.Operation{
+		Method: http.MethodPost, Path: "/table", OperationId: "runTableOperation", Summary: "Create, update or delete a DynamoDB table or index",
+		Body: table.TableOperation{},
+		Responses: append([]openapi.Response{
+			openapi.Empty(http.StatusOK, ""),
+			errorResponse(http.StatusBadRequest, "Invalid operation, IndexKeyName not provided, or DynamoDB rejected the keys"),
+			errorResponse(http.StatusNotFound, "No such table"),
+			errorResponse(http.StatusConflict, "The table exists already or is being changed"),
+			errorResponse(http.StatusNotImplemented, "Storage backend isn't dynamodb"),
+		}, storeErrors...),
+	}, Handler: table.Post},
+
+	{Operation: openapi.Operation{
+		Method: http.MethodGet, Path: "/userinfo", OperationId: "listWorkerInfo", Summary: "Latest reading of one worker, or of every worker in a date range",
+		Params: append([]openapi.Param{
+			openapi.Query("Id", "string", "Returns this worker's latest reading, other params are ignored"),
+			openapi.Query("sdate", "string", "First day, with edate when range is missing"),
+			openapi.Query("edate", "string", "Last day"),
+			openapi.Query("range", "string", "today, yesterday or lastNd, in the ground's time zone"),
+			openapi.Query("ground", "string", "Only this ground"),
+			openapi.Query("minScore", "integer", "Only early warning scores at least this"),
+			openapi.QueryArray("risk", "Only these risk levels"),
+			openapi.Query("sort", "string", "score sorts by early warning score, highest first"),
+		}, pageParams...),
+		Responses: append([]openapi.Response{
+			openapi.JSON(http.StatusOK, "One worker with Id, every worker, or one page with limit or cursor",
+				openapi.OneOf{userinfo.WorkerInfo{}, []userinfo.WorkerInfo{}, util.Page[userinfo.WorkerInfo]{}}),
+			errorResponse(http.StatusBadRequest, "Missing or invalid dates, filters, limit or cursor"),
+			errorResponse(http.StatusNotFound, "No worker info for Id"),
+		}, storeErrors...),
+	}, Handler: userinfo.Get},
+	{Operation: openapi.Operation{
+		Method: http.MethodPost, Path: "/userinfo", OperationId: "insertWorkerInfo", Summary: "Store a helmet reading",
+		Body: userinfo.RawWorkerInfo{}, BodyTypes: []string{telemetry.ContentType},
+		Params: []openapi.Param{openapi.Header(userinfo.IdempotencyHeader, "Retries with the same key are stored once")},
+		Responses: append([]openapi.Response{
+			openapi.Empty(http.StatusOK, "Stored, or replayed when the "+userinfo.ReplayedHeader+" header is set"),
+			errorResponse(http.StatusBadRequest, "Invalid reading or frame"),
+		}, storeErrors...),
+	}, Handler: userinfo.Post},
+	{Operation: openapi.Operation{
+		Method: http.MethodPut, Path: "/userinfo", OperationId: "updateWorkerInfo", Summary: "Replace a worker's latest reading",
+		Body: userinfo.WorkerInfo{},
+		Responses: append([]openapi.Response{
+			openapi.Empty(http.StatusOK, ""),
+			errorResponse(http.StatusBadRequest, "Body isn't a WorkerInfo"),
+			errorResponse(http.StatusNotFound, "The worker has no row on that Date, nothing is created"),
+		}, storeErrors...),
+	}, Handler: userinfo.Update},
+	{Operation: openapi.Operation{
+		Method: http.MethodDelete, Path: "/userinfo", OperationId: "deleteWorkerInfo", Summary: "Remove a worker",
+		Params: []openapi.Param{
+			{Name: "Id", In: "query", Type: "string", Required: true},
+			openapi.Query("Date", "string", "Only the row of this day, every row of the worker when left out"),
+		},
+		Responses: append([]openapi.Response{
+			openapi.Empty(http.StatusOK, ""),
+			errorResponse(http.StatusBadRequest, "Id not provided"),
+			errorResponse(http.StatusNotFound, "No worker info for Id (on Date)"),
+		}, storeErrors...),
+	}, Handler: userinfo.Delete},
+	{Operation: openapi.Operation{
+		Method: http.MethodPost, Path: "/userinfo/batch", OperationId: "insertWorkerInfoBatch", Summary: "Store many readings, each reported on its own",
+		Body: []userinfo.RawWorkerInfo{},
+		Responses: append([]openapi.Response{
+			openapi.JSON(http.StatusOK, "", userinfo.BatchReport{}),
+			errorResponse(http.StatusBadRequest, "Body isn't a JSON array"),
+			errorResponse(http.StatusRequestEntityTooLarge, "More than the batch limit"),
+		}, storeErrors...),
+	}, Handler: userinfo.PostBatch},
+	{Operation: openapi.Operation{
+		Method: http.MethodGet, Path: "/userinfo/gaps", OperationId: "listGaps", Summary: "Missing sequence numbers per helmet",
+		Params:    []openapi.Param{openapi.Query("Id", "string", "Only this helmet")},
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "", []userinfo.Gap{})},
+	}, Handler: userinfo.GetGaps},
+	{Operation: openapi.Operation{
+		Method: http.MethodGet, Path: "/userinfo/:id/readings", OperationId: "getReadings", Summary: "Raw readings of a worker",
+		Params: readingsParams,
+		Responses: append([]openapi.Response{
+			openapi.JSON(http.StatusOK, "", []userinfo.WorkerInfo{}),
+			errorResponse(http.StatusBadRequest, "Invalid from, to or range"),
+		}, storeErrors...),
+	}, Handler: userinfo.GetReadings},
+	{Operation: openapi.Operation{
+		Method: http.MethodGet, Path: "/userinfo/:id/readings/aggregate", OperationId: "aggregateReadings", Summary: "Min, max, average and last per time bucket",
+		Params: append([]openapi.Param{openapi.Query("bucket", "string", "1m, 5m (default) or 1h")}, readingsParams...),
+		Responses: append([]openapi.Response{
+			openapi.JSON(http.StatusOK, "", []userinfo.Bucket{}),
+			errorResponse(http.StatusBadRequest, "Invalid bucket, from, to or range"),
+		}, storeErrors...),
+	}, Handler: userinfo.GetAggregate},
+	{Operation: openapi.Operation{
+		Method: http.MethodGet, Path: "/userinfo/:id/readings/downsample", OperationId: "downsampleReadings", Summary: "Readings reduced to at most points per field, for charts",
+		Params: append([]openapi.Param{
+			openapi.Query("points", "integer", "Points per field, 500 by default"),
+			openapi.Query("field", "string", "Only this field, every vital sign by default"),
+		}, readingsParams...),
+		Responses: append([]openapi.Response{
+			openapi.JSON(http.StatusOK, "Points per field", map[string][]userinfo.Point{}),
+			errorResponse(http.StatusBadRequest, "Invalid points, field, from, to or range"),
+		}, storeErrors...),
+	}, Handler: userinfo.GetDownsample},
+
+	{Operation: openapi.Operation{
+		Method: http.MethodGet, Path: "/rules", OperationId: "getRules", Summary: "Loaded alert rules and their state",
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "", rules.RulesStatus{})},
+	}, Handler: rules.Get},
+	{Operation: openapi.Operation{
+		Method: http.MethodGet, Path: "/exposure", OperationId: "getExposure", Summary: "Gas exposure against TWA and STEL limits",
+		Params: []openapi.Param{openapi.Query("Id", "string", "Selects one worker, every worker when empty")},
+		Responses: []openapi.Response{
+			openapi.JSON(http.StatusOK, "One entry per worker and gas, ordered by Id and GasType", []exposure.Status{}),
+			errorResponse(http.StatusNotFound, "No readings for the worker"),
+		},
+	}, Handler: exposure.Get},
+
+	{Operation: openapi.Operation{
+		Method: http.MethodGet, Path: "/sync", OperationId: "getSync", Summary: "Edge gateway forwarding lag per site",
+		Responses: []openapi.Response{
+			openapi.JSON(http.StatusOK, "", edgesync.SyncStatus{}),
+			errorResponse(http.StatusInternalServerError, "Couldn't read the local journal"),
+		},
+	}, Handler: edgesync.Get},
+	{Operation: openapi.Operation{
+		Method: http.MethodGet, Path: "/mqtt", OperationId: "getMQTT", Summary: "MQTT subscriber state",
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "", mqttingest.Status{})},
+	}, Handler: mqttingest.Get},
+	{Operation: openapi.Operation{
+		Method: http.MethodGet, Path: "/udp", OperationId: "getUDP", Summary: "UDP frame listener state",
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "", udpingest.Status{})},
+	}, Handler: udpingest.Get},
+
+	{Operation: openapi.Operation{
+		Method: http.MethodGet, Path: "/hub", OperationId: "connectHub", Summary: "WebSocket connection to the message hub",
+		Params: []openapi.Param{
+			{Name: "Role", In: "query", Type: "string", Required: true, Description: "helmet, gateway or dashboard"},
+			{Name: "Id", In: "query", Type: "string", Required: true},
+			openapi.Query("Ground", "string", "Required for gateways, the ground they relay for"),
+			openapi.Query("Token", "string", "HUB_HELMET_TOKEN, HUB_GATEWAY_TOKEN or HUB_DASHBOARD_TOKEN by Role, or sent as Authorization: Bearer"),
+		},
+		Responses: []openapi.Response{
+			openapi.Empty(http.StatusSwitchingProtocols, "Messages are JSON Message objects"),
+			errorResponse(http.StatusBadRequest, "Missing or invalid Role, Id or Ground"),
+			errorResponse(http.StatusUnauthorized, "Token missing or wrong, or not set for the Role"),
+			errorResponse(http.StatusForbidden, "Browser Origin not in HUB_ALLOWED_ORIGINS"),
+		},
+	}, Handler: hub.Get},
+	{Operation: openapi.Operation{
+		Method: http.MethodPost, Path: "/hub", OperationId: "sendHubMessage", Summary: "Send a message to connected clients",
+		Params: []openapi.Param{openapi.Header("Authorization", "Bearer HUB_DASHBOARD_TOKEN, or send it as the Token query param")},
+		Body:   hub.Message{},
+		Responses: []openapi.Response{
+			openapi.JSON(http.StatusOK, "Delivery receipt", hub.Message{}),
+			errorResponse(http.StatusBadRequest, "To or Ground not provided"),
+			errorResponse(http.StatusUnauthorized, "Dashboard token missing or wrong"),
+		},
+	}, Handler: hub.Post},
+	{Operation: openapi.Operation{
+		Method: http.MethodGet, Path: "/hub/clients", OperationId: "listHubClients", Summary: "Connected hub clients",
+		Responses: []openapi.Response{openapi.JSON(http.StatusOK, "", []hub.ClientInfo{})},
+	}, Handler: hub.Clients},
+}
+
+// storeErrors Documents the failures of a DynamoDB call that aren't the caller's, see util.WriteStoreError
+var storeErrors = []openapi.Response{
+	errorResponse(http.StatusTooManyRequests, "DynamoDB is throttling, retry after the Retry-After header"),
+	errorResponse(http.StatusInternalServerError, "The store failed, the reason is logged under the request id"),
+	errorResponse(http.StatusServiceUnavailable, "DynamoDB can't be reached, retry after the Retry-After header"),
+}
+
+// queueFailed describes the 500 of a danger route whose alert queue (the local database) failed
+const queueFailed = "The alert queue failed, the reason is logged under the request id"
+
+// errorResponse Documents a util.ErrorBody, how the handlers report errors
+func errorResponse(status int, description string) openapi.Response {
+	return openapi.JSON(status, description, util.ErrorBody{})
+}
+
+var alertActionResponses = []openapi.Response{
+	openapi.JSON(http.StatusOK, "", danger.Alert{}),
+	errorResponse(http.StatusBadRequest, "Invalid alert id or By not provided"),
+	errorResponse(http.StatusNotFound, "No such alert"),
+	errorResponse(http.StatusConflict, "The alert can't move to that state"),
+	errorResponse(http.StatusInternalServerError, queueFailed),
+}
+
+// Register Attaches every route of Table to router
+func Register(router gin.IRoutes) {
+	for i := range Table {
+		router.Handle(Table[i].Method, Table[i].Path, Table[i].Handler)
+	}
+}
+
+// Attach Registers every route of Table under /v1, and at its old unversioned path for deployed helmets, plus /openapi.json
+func Attach(engine *gin.Engine) {
+	engine.Use(util.RequestId, util.Recovery())
+	engine.NoRoute(util.NoRoute)
+	Register(engine.Group(BasePath))
+	Register(engine.Group("", Deprecated))
+
+	engine.GET("/openapi.json", GetOpenAPI)
+}
+
+// Deprecated Marks the unversioned paths, helmets still call them but new clients should use /v1
+func Deprecated(ctx *gin.Context) {
+	ctx.Header("Deprecation", "true")
+	ctx.Header("Link", "<"+BasePath+ctx.FullPath()+">; rel=\"successor-version\"")
+	ctx.Next()
+}
+
+var spec struct {
+	once sync.Once
+	data []byte
+	err  error
+}
+
+// Spec Returns the OpenAPI document of Table as written to openapi.json
+func Spec() ([]byte, error) {
+	spec.once.Do(func() {
+		operations := make([]openapi.Operation, len(Table))
+		for i := range Table {
+			operations[i] = Table[i].Operation
+		}
+		spec.data, spec.err = openapi.Build(Title, Version, BasePath, operations).MarshalIndent()
+	})
+	return spec.data, spec.err
+}
+
+// GetOpenAPI Serves the OpenAPI document
+func GetOpenAPI(ctx *gin.Context) {
+	util.DebugPrint(FILENAME, "GET", "Just Test")
+	data, err := Spec()
+	if err != nil {
+		log.Printf("Couldn't build the OpenAPI document. Reason => %v\n", err)
+		util.WriteError(ctx, http.StatusInternalServerError, "Couldn't build the OpenAPI document")
+		return
+	}
+	ctx.Data(http.StatusOK, "application/json", data)
+}
@@ -0,0 +1,196 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"go_backend/util"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+var update = flag.Bool("update", false, "Regenerate openapi.json instead of checking it")
+
+var specFile string // The committed openapi.json at the repository root
+
+// TestMain Runs the handlers on the memory backend in a scratch directory, the alert queue opens LOCAL_DB_FILE there
+func TestMain(m *testing.M) {
+	flag.Parse()
+	var err error
+	if specFile, err = filepath.Abs(filepath.Join("..", "openapi.json")); err != nil {
+		log.Fatalln(err)
+	}
+	dir, err := os.MkdirTemp("", "routes-test")
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err = os.Chdir(dir); err != nil {
+		log.Fatalln(err)
+	}
+	viper.Set("STORAGE_BACKEND", util.StorageMemory)
+	viper.Set("HUB_DASHBOARD_TOKEN", "dashboard")
+	gin.SetMode(gin.TestMode)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// TestSpecMatchesFile Fails when openapi.json no longer matches the route table and the Go types of its handlers
+// go test ./routes -update regenerates it
+func TestSpecMatchesFile(t *testing.T) {
+	generated, err := Spec()
+	if err != nil {
+		t.Fatalf("Couldn't build the OpenAPI document. Reason => %v", err)
+	}
+	if *update {
+		if err = os.WriteFile(specFile, generated, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	committed, err := os.ReadFile(specFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(committed, generated) {
+		t.Errorf("openapi.json is out of date with the handlers in %v, run go test ./routes -update and commit the result",
+			strings.Join(drifted(committed, generated), ", "))
+	}
+}
+
+// drifted Names the paths and component schemas that differ between two documents
+func drifted(committed, generated []byte) []string {
+	var before, after map[string]interface{}
+	if json.Unmarshal(committed, &before) != nil {
+		return []string{"not valid JSON"}
+	}
+	json.Unmarshal(generated, &after)
+
+	var sections []string
+	compare := func(prefix string, before, after interface{}) {
+		beforeMap, _ := before.(map[string]interface{})
+		afterMap, _ := after.(map[string]interface{})
+		for key := range beforeMap {
+			if !reflect.DeepEqual(beforeMap[key], afterMap[key]) {
+				sections = append(sections, prefix+key)
+			}
+		}
+		for key := range afterMap {
+			if _, isFound := beforeMap[key]; !isFound {
+				sections = append(sections, prefix+key)
+			}
+		}
+	}
+	compare("paths ", before["paths"], after["paths"])
+	beforeComponents, _ := before["components"].(map[string]interface{})
+	afterComponents, _ := after["components"].(map[string]interface{})
+	compare("schema ", beforeComponents["schemas"], afterComponents["schemas"])
+	compare("", map[string]interface{}{"info": before["info"], "openapi": before["openapi"]},
+		map[string]interface{}{"info": after["info"], "openapi": after["openapi"]})
+	sort.Strings(sections)
+	if len(sections) == 0 {
+		sections = append(sections, "formatting")
+	}
+	return sections
+}
+
+func TestDrifted(t *testing.T) {
+	committed := []byte(`{"openapi":"3.0.3","paths":{"/a":{},"/b":{"get":{}}},"components":{"schemas":{"A":{}}}}`)
+	generated := []byte(`{"openapi":"3.0.3","paths":{"/a":{},"/c":{}},"components":{"schemas":{"A":{"type":"object"}}}}`)
+	if got := strings.Join(drifted(committed, generated), ","); got != "paths /b,paths /c,schema A" {
+		t.Errorf("drifted = %v", got)
+	}
+	if got := drifted(committed, append(committed, '\n')); !reflect.DeepEqual(got, []string{"formatting"}) {
+		t.Errorf("drifted of a reformatted document = %v", got)
+	}
+	if got := drifted([]byte("{"), committed); !reflect.DeepEqual(got, []string{"not valid JSON"}) {
+		t.Errorf("drifted of a broken document = %v", got)
+	}
+}
+
+// TestRoutesAnswer Calls every route of Table under /v1 with no input, each answers with one of its documented statuses
+func TestRoutesAnswer(t *testing.T) {
+	engine := gin.New()
+	Attach(engine)
+	for _, route := range Table {
+		path := BasePath + strings.ReplaceAll(route.Path, ":id", "1_2")
+		t.Run(route.Method+" "+route.Path, func(t *testing.T) {
+			var body *strings.Reader
+			if route.Method == http.MethodGet {
+				body = strings.NewReader("")
+			} else {
+				body = strings.NewReader("{}")
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			if route.OperationId == "streamAlerts" {
+				cancel() // The stream only ends with the client
+			}
+			defer cancel()
+			req := httptest.NewRequest(route.Method, path, body).WithContext(ctx)
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			engine.ServeHTTP(recorder, req)
+
+			var documented []int
+			for _, response := range route.Responses {
+				documented = append(documented, response.Status)
+				if response.Status == recorder.Code {
+					return
+				}
+			}
+			t.Errorf("%v %v = %v %v, documented %v", route.Method, path, recorder.Code, recorder.Body, documented)
+		})
+	}
+}
+
+func TestUnversionedPathsAreDeprecated(t *testing.T) {
+	engine := gin.New()
+	Attach(engine)
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/rules", nil))
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Deprecation") != "true" ||
+		recorder.Header().Get("Link") != `</v1/rules>; rel="successor-version"` {
+		t.Errorf("GET /rules = %v, headers %v", recorder.Code, recorder.Header())
+	}
+
+	recorder = httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, BasePath+"/rules", nil))
+	if recorder.Header().Get("Deprecation") != "" {
+		t.Errorf("GET /v1/rules is marked deprecated")
+	}
+}
+
+// TestMalformedBodies Sends broken JSON to every route reading a body, each answers with a 400 envelope and keeps serving
+func TestMalformedBodies(t *testing.T) {
+	engine := gin.New()
+	Attach(engine)
+	for _, route := range Table {
+		if route.Body == nil || route.Path == "/table" { // /table answers 501 first, it needs the dynamodb backend
+			continue
+		}
+		path := BasePath + strings.ReplaceAll(route.Path, ":id", "1")
+		req := httptest.NewRequest(route.Method, path, strings.NewReader(`{"Name":`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(util.RequestIdHeader, "malformed-1")
+		req.Header.Set("Authorization", "Bearer dashboard") // POST /hub checks it before the body
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, req)
+
+		body := util.ErrorBody{}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil || recorder.Code != http.StatusBadRequest ||
+			body.Code != "bad_request" || body.RequestId != "malformed-1" || body.Message == "" {
+			t.Errorf("%v %v with broken JSON = %v %v", route.Method, path, recorder.Code, recorder.Body)
+		}
+	}
+}
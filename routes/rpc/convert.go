@@ -0,0 +1,126 @@
+package rpc
+
+import (
+	"go_backend/routes/contacts"
+	"go_backend/routes/danger"
+	"go_backend/routes/hospital"
+	"go_backend/routes/rpc/pb"
+	"go_backend/routes/userinfo"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Conversions between the REST structs and the generated messages
+
+func fromRawWorkerInfo(raw *pb.RawWorkerInfo) *userinfo.RawWorkerInfo {
+	return &userinfo.RawWorkerInfo{
+		GroundNumber:   raw.GroundNumber,
+		HelmetNumber:   raw.HelmetNumber,
+		Spo2Level:      int16(raw.Spo2Level),
+		Temperature:    raw.Temperature,
+		GasLevel:       raw.GasLevel,
+		GasType:        raw.GasType,
+		HeartRate:      raw.HeartRate,
+		DangerType:     raw.DangerType,
+		MeasuredAt:     raw.MeasuredAt,
+		Sequence:       raw.Sequence,
+		IdempotencyKey: raw.IdempotencyKey,
+	}
+}
+
+func toWorkerInfo(workerInfo *userinfo.WorkerInfo) *pb.WorkerInfo {
+	return &pb.WorkerInfo{
+		Id:                workerInfo.Id,
+		Name:              workerInfo.Name,
+		Spo2Level:         int32(workerInfo.Spo2Level),
+		Temperature:       workerInfo.Temperature,
+		GasLevel:          workerInfo.GasLevel,
+		GasType:           workerInfo.GasType,
+		HeartRate:         workerInfo.HeartRate,
+		DangerType:        workerInfo.DangerType,
+		Date:              workerInfo.Date,
+		Timestamp:         workerInfo.Timestamp,
+		Sequence:          workerInfo.Sequence,
+		MeasuredAt:        workerInfo.MeasuredAt,
+		ReceivedAt:        workerInfo.ReceivedAt,
+		ClockFlag:         workerInfo.ClockFlag,
+		TreatedDoctor:     workerInfo.TreatedDoctor,
+		EarlyWarningScore: int32(workerInfo.EarlyWarningScore),
+		EarlyWarningRisk:  workerInfo.EarlyWarningRisk,
+	}
+}
+
+func fromWorkerInfo(workerInfo *pb.WorkerInfo) *userinfo.WorkerInfo {
+	return &userinfo.WorkerInfo{
+		Id:                workerInfo.Id,
+		Name:              workerInfo.Name,
+		Spo2Level:         int16(workerInfo.Spo2Level),
+		Temperature:       workerInfo.Temperature,
+		GasLevel:          workerInfo.GasLevel,
+		GasType:           workerInfo.GasType,
+		HeartRate:         workerInfo.HeartRate,
+		DangerType:        workerInfo.DangerType,
+		Date:              workerInfo.Date,
+		Timestamp:         workerInfo.Timestamp,
+		Sequence:          workerInfo.Sequence,
+		MeasuredAt:        workerInfo.MeasuredAt,
+		ReceivedAt:        workerInfo.ReceivedAt,
+		ClockFlag:         workerInfo.ClockFlag,
+		TreatedDoctor:     workerInfo.TreatedDoctor,
+		EarlyWarningScore: int(workerInfo.EarlyWarningScore),
+		EarlyWarningRisk:  workerInfo.EarlyWarningRisk,
+	}
+}
+
+func toWorkerInfoList(workerInfoList []userinfo.WorkerInfo) []*pb.WorkerInfo {
+	converted := make([]*pb.WorkerInfo, len(workerInfoList))
+	for i := range workerInfoList {
+		converted[i] = toWorkerInfo(&workerInfoList[i])
+	}
+	return converted
+}
+
+func toBatchReport(report *userinfo.BatchReport) *pb.BatchReport {
+	converted := &pb.BatchReport{
+		Accepted:   int32(report.Accepted),
+		Rejected:   int32(report.Rejected),
+		Duplicates: int32(report.Duplicates),
+		Results:    make([]*pb.BatchItemResult, len(report.Results)),
+	}
+	for i, result := range report.Results {
+		converted.Results[i] = &pb.BatchItemResult{
+			Index: int32(result.Index), Id: result.Id, Status: result.Status, Reason: result.Reason,
+		}
+	}
+	return converted
+}
+
+func toContact(contact *contacts.Contact) *pb.Contact {
+	return &pb.Contact{Name: contact.Name, PhoneNumber: contact.PhoneNumber, Specification: contact.Specification}
+}
+
+func toHospital(hospitalInfo *hospital.Hospital) *pb.Hospital {
+	return &pb.Hospital{Name: hospitalInfo.Name, PhoneNumber: hospitalInfo.PhoneNumber, Address: hospitalInfo.Address}
+}
+
+// toTimestamp Returns nil for a nil time, which leaves the field unset
+func toTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+func toAlert(alert *danger.Alert) *pb.Alert {
+	return &pb.Alert{
+		Id:             alert.Id,
+		State:          alert.State,
+		WorkerInfo:     toWorkerInfo(&alert.WorkerInfo),
+		CreatedAt:      timestamppb.New(alert.CreatedAt),
+		AcknowledgedBy: alert.AcknowledgedBy,
+		AcknowledgedAt: toTimestamp(alert.AcknowledgedAt),
+		ResolvedBy:     alert.ResolvedBy,
+		ResolvedAt:     toTimestamp(alert.ResolvedAt),
+	}
+}
@@ -0,0 +1,103 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"go_backend/routes/danger"
+	"go_backend/routes/rpc/pb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type dangerServer struct {
+	pb.UnimplementedDangerServiceServer
+}
+
+func (server *dangerServer) ListAlerts(ctx context.Context, req *pb.ListAlertsRequest) (*pb.ListAlertsResponse, error) {
+	states := req.State
+	if len(states) == 0 {
+		states = []string{danger.StatePending, danger.StateAcknowledged}
+	}
+	alertList, err := danger.GetQueue().List(states...)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	response := &pb.ListAlertsResponse{Alerts: make([]*pb.Alert, len(alertList))}
+	for i := range alertList {
+		response.Alerts[i] = toAlert(&alertList[i])
+	}
+	return response, nil
+}
+
+func (server *dangerServer) RaiseAlert(ctx context.Context, req *pb.RawWorkerInfo) (*pb.RaiseAlertResponse, error) {
+	rawWorkInfo := fromRawWorkerInfo(req)
+	if reason := rawWorkInfo.Validate(); reason != "" {
+		return nil, status.Error(codes.InvalidArgument, reason)
+	}
+	alert, isNew, err := danger.RaiseReading(rawWorkInfo)
+	switch {
+	case errors.Is(err, danger.ErrInProgress):
+		return nil, status.Error(codes.Aborted, err.Error())
+	case err != nil:
+		return nil, storeError(err)
+	}
+	return &pb.RaiseAlertResponse{Alert: toAlert(&alert), Replayed: !isNew}, nil
+}
+
+func (server *dangerServer) AcknowledgeAlert(ctx context.Context, req *pb.AlertActionRequest) (*pb.Alert, error) {
+	return changeState(req, danger.GetQueue().Acknowledge)
+}
+
+func (server *dangerServer) ResolveAlert(ctx context.Context, req *pb.AlertActionRequest) (*pb.Alert, error) {
+	return changeState(req, danger.GetQueue().Resolve)
+}
+
+func changeState(req *pb.AlertActionRequest, operation func(id uint64, by string) (danger.Alert, error)) (*pb.Alert, error) {
+	if req.By == "" {
+		return nil, status.Error(codes.InvalidArgument, "by not provided")
+	}
+	alert, err := operation(req.Id, req.By)
+	switch {
+	case errors.Is(err, danger.ErrAlertNotFound):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, danger.ErrInvalidTransition):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case err != nil:
+		return nil, storeError(err)
+	}
+	return toAlert(&alert), nil
+}
+
+// StreamAlerts Sends new alerts until the client cancels, replaying those after last_event_id first
+// Uses the same danger.Subscription as /danger/stream, so each alert is sent once and in Id order
+// A client too slow to keep up gets Unavailable and should reconnect with the last id it received
+func (server *dangerServer) StreamAlerts(req *pb.StreamAlertsRequest, stream pb.DangerService_StreamAlertsServer) error {
+	filter := danger.AlertFilter{Grounds: req.Ground, DangerTypes: req.DangerType}
+	subscription, backlog, err := danger.Subscribe(filter, req.GetLastEventId(), req.LastEventId != nil)
+	if err != nil {
+		return storeError(err)
+	}
+	defer subscription.Close()
+
+	for i := range backlog {
+		if err = stream.Send(toAlert(&backlog[i])); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case alert, isOpen := <-subscription.Alerts:
+			if !isOpen {
+				return status.Error(codes.Unavailable, "stream fell behind, reconnect with last_event_id")
+			}
+			if subscription.Accept(&alert) {
+				if err = stream.Send(toAlert(&alert)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
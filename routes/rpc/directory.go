@@ -0,0 +1,115 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"go_backend/routes/contacts"
+	"go_backend/routes/hospital"
+	"go_backend/routes/rpc/pb"
+	"go_backend/util"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// readList Returns every entry when the request asks for no page, else the page it asks for
+// Scopes match the REST routes, a cursor works with either API
+func readList[T any](scope string, req *pb.ListRequest, readAll func() ([]T, error), read util.PageReader[T]) (util.Page[T], error) {
+	if req.Limit == 0 && req.Cursor == "" {
+		items, err := readAll()
+		if err != nil {
+			return util.Page[T]{}, storeError(err)
+		}
+		return util.Page[T]{Items: items}, nil
+	}
+	page, err := util.ReadPage(scope, req.Cursor, int(req.Limit), read)
+	if err != nil {
+		return page, pageError(err)
+	}
+	return page, nil
+}
+
+// pageError Returns InvalidArgument for a bad cursor or limit, the store failed otherwise
+func pageError(err error) error {
+	if errors.Is(err, util.ErrInvalidCursor) || errors.Is(err, util.ErrInvalidLimit) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return storeError(err)
+}
+
+type contactServer struct {
+	pb.UnimplementedContactServiceServer
+}
+
+func (server *contactServer) ListContacts(ctx context.Context, req *pb.ListRequest) (*pb.ListContactsResponse, error) {
+	store := contacts.GetStore()
+	page, err := readList("contacts", req, store.GetAllContactInfo, store.GetContactPage)
+	if err != nil {
+		return nil, err
+	}
+	response := &pb.ListContactsResponse{Items: make([]*pb.Contact, len(page.Items)), NextCursor: page.NextCursor}
+	for i := range page.Items {
+		response.Items[i] = toContact(&page.Items[i])
+	}
+	return response, nil
+}
+
+func (server *contactServer) InsertContact(ctx context.Context, req *pb.Contact) (*emptypb.Empty, error) {
+	if req.PhoneNumber == "" {
+		return nil, status.Error(codes.InvalidArgument, "phone_number not provided")
+	}
+	contact := &contacts.Contact{Name: req.Name, PhoneNumber: req.PhoneNumber, Specification: req.Specification}
+	if err := contacts.GetStore().InsertContact(contact); err != nil {
+		return nil, storeError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (server *contactServer) DeleteContact(ctx context.Context, req *pb.DeleteRequest) (*emptypb.Empty, error) {
+	if req.PhoneNumber == "" {
+		return nil, status.Error(codes.InvalidArgument, "phone_number not provided")
+	}
+	if err := contacts.GetStore().DeleteContact(&contacts.Contact{PhoneNumber: req.PhoneNumber}); err != nil {
+		return nil, storeError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+type hospitalServer struct {
+	pb.UnimplementedHospitalServiceServer
+}
+
+func (server *hospitalServer) ListHospitals(ctx context.Context, req *pb.ListRequest) (*pb.ListHospitalsResponse, error) {
+	store := hospital.GetStore()
+	page, err := readList("hospital", req, store.GetAllHospitalInfo, store.GetHospitalPage)
+	if err != nil {
+		return nil, err
+	}
+	response := &pb.ListHospitalsResponse{Items: make([]*pb.Hospital, len(page.Items)), NextCursor: page.NextCursor}
+	for i := range page.Items {
+		response.Items[i] = toHospital(&page.Items[i])
+	}
+	return response, nil
+}
+
+func (server *hospitalServer) InsertHospital(ctx context.Context, req *pb.Hospital) (*emptypb.Empty, error) {
+	if req.PhoneNumber == "" {
+		return nil, status.Error(codes.InvalidArgument, "phone_number not provided")
+	}
+	hospitalInfo := &hospital.Hospital{Name: req.Name, PhoneNumber: req.PhoneNumber, Address: req.Address}
+	if err := hospital.GetStore().InsertHospital(hospitalInfo); err != nil {
+		return nil, storeError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (server *hospitalServer) DeleteHospital(ctx context.Context, req *pb.DeleteRequest) (*emptypb.Empty, error) {
+	if req.PhoneNumber == "" {
+		return nil, status.Error(codes.InvalidArgument, "phone_number not provided")
+	}
+	if err := hospital.GetStore().DeleteHospital(&hospital.Hospital{PhoneNumber: req.PhoneNumber}); err != nil {
+		return nil, storeError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
@@ -0,0 +1,86 @@
+/*
+Rpc Package serves the gRPC API defined in proto/smlr.proto next to the gin routes
+Every service calls the same stores and ingest paths as its REST routes, generated code lives in rpc/pb
+The WebSocket hub, /table administration and the status routes (/rules, /exposure, /sync, /mqtt, /udp) stay REST only
+Only active when GRPC_LISTEN_ADDR is set
+*/
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"go_backend/routes/rpc/pb"
+	"go_backend/util"
+	"log"
+	"net"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const FILENAME = "rpc/index.go"
+
+// storeError Reports a storage failure with the code of its domain error, as util.WriteStoreError does for REST
+// Throttled and unavailable errors carry a RetryInfo detail, the gRPC form of Retry-After
+func storeError(err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, util.ErrNotFound):
+		code = codes.NotFound
+	case errors.Is(err, util.ErrConflict):
+		code = codes.Aborted
+	case errors.Is(err, util.ErrThrottled):
+		code = codes.ResourceExhausted
+	case errors.Is(err, util.ErrUnavailable):
+		code = codes.Unavailable
+	case errors.Is(err, util.ErrInvalid):
+		code = codes.InvalidArgument
+	}
+	st := status.New(code, err.Error())
+	if code == codes.ResourceExhausted || code == codes.Unavailable {
+		retryInfo := &errdetails.RetryInfo{RetryDelay: durationpb.New(util.GetStoreRetryAfter())}
+		if detailed, detailErr := st.WithDetails(retryInfo); detailErr == nil {
+			st = detailed
+		}
+	}
+	return st.Err()
+}
+
+func debugUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	util.DebugPrint(FILENAME, info.FullMethod, "Just Test")
+	return handler(ctx, req)
+}
+
+func debugStream(server interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	util.DebugPrint(FILENAME, info.FullMethod, "Just Test")
+	return handler(server, stream)
+}
+
+// NewServer Returns a gRPC server with every service registered, reflection included for grpcurl
+func NewServer() *grpc.Server {
+	server := grpc.NewServer(grpc.UnaryInterceptor(debugUnary), grpc.StreamInterceptor(debugStream))
+	pb.RegisterWorkerInfoServiceServer(server, &workerInfoServer{})
+	pb.RegisterContactServiceServer(server, &contactServer{})
+	pb.RegisterHospitalServiceServer(server, &hospitalServer{})
+	pb.RegisterDangerServiceServer(server, &dangerServer{})
+	reflection.Register(server)
+	return server
+}
+
+// Start Serves the gRPC API on GRPC_LISTEN_ADDR, blocks
+func Start(pLog *log.Logger, eLog *log.Logger) {
+	listener, err := net.Listen("tcp", util.GetGRPCListenAddr())
+	if err != nil {
+		eLog.Printf("Couldn't listen for gRPC. Here's why: %v\n", err)
+		return
+	}
+	pLog.Printf("gRPC Started on %v\n", listener.Addr())
+	if err = NewServer().Serve(listener); err != nil {
+		eLog.Printf("gRPC server stopped. Reason => %v\n", err)
+	}
+}
@@ -0,0 +1,193 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"go_backend/routes/danger"
+	"go_backend/routes/rpc/pb"
+	"go_backend/util"
+	"log"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// TestMain Runs the tests in a scratch directory, the alert queue opens LOCAL_DB_FILE in the working directory
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "rpc-test")
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err = os.Chdir(dir); err != nil {
+		log.Fatalln(err)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// dialTestServer Serves NewServer over an in-memory listener until the test ends
+func dialTestServer(t *testing.T) *grpc.ClientConn {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer()
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestStoreErrorCodes(t *testing.T) {
+	tests := []struct {
+		err       error
+		code      codes.Code
+		retryInfo bool
+	}{
+		{util.NewStoreError(util.ErrNotFound, "missing"), codes.NotFound, false},
+		{util.NewStoreError(util.ErrConflict, "changed"), codes.Aborted, false},
+		{util.NewStoreError(util.ErrThrottled, ""), codes.ResourceExhausted, true},
+		{util.NewStoreError(util.ErrUnavailable, ""), codes.Unavailable, true},
+		{util.NewStoreError(util.ErrInvalid, "bad"), codes.InvalidArgument, false},
+		{fmt.Errorf("disk full"), codes.Internal, false},
+	}
+	for _, test := range tests {
+		st := status.Convert(storeError(test.err))
+		if st.Code() != test.code {
+			t.Errorf("storeError(%v) code = %v, want %v", test.err, st.Code(), test.code)
+		}
+		hasRetryInfo := false
+		for _, detail := range st.Details() {
+			_, isRetryInfo := detail.(*errdetails.RetryInfo)
+			hasRetryInfo = hasRetryInfo || isRetryInfo
+		}
+		if hasRetryInfo != test.retryInfo {
+			t.Errorf("storeError(%v) RetryInfo = %v, want %v", test.err, hasRetryInfo, test.retryInfo)
+		}
+	}
+}
+
+func raise(t *testing.T, client pb.DangerServiceClient, ground string) *pb.Alert {
+	t.Helper()
+	response, err := client.RaiseAlert(context.Background(), &pb.RawWorkerInfo{GroundNumber: ground, HelmetNumber: "1", DangerType: "SOS"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response.Alert
+}
+
+func TestStreamAlertsResumesThenFollows(t *testing.T) {
+	client := pb.NewDangerServiceClient(dialTestServer(t))
+	missed := raise(t, client, "1")
+	raise(t, client, "2")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	lastEventId := missed.Id - 1
+	stream, err := client.StreamAlerts(ctx, &pb.StreamAlertsRequest{Ground: []string{"1"}, LastEventId: &lastEventId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The backlog is sent once the subscription is in place, nothing raised from here on is missed
+	if alert, err := stream.Recv(); err != nil || alert.Id != missed.Id {
+		t.Fatalf("first alert = %v, %v, want the missed alert %v", alert, err, missed.Id)
+	}
+
+	raise(t, client, "2")
+	live := raise(t, client, "1")
+	if alert, err := stream.Recv(); err != nil || alert.Id != live.Id || alert.WorkerInfo.Id != "1_1" {
+		t.Fatalf("live alert = %v, %v, want %v of ground 1", alert, err, live.Id)
+	}
+
+	cancel()
+	if _, err = stream.Recv(); status.Code(err) != codes.Canceled {
+		t.Errorf("Recv after cancel error = %v", err)
+	}
+}
+
+func TestStreamAlertsSkipsBacklogDuplicates(t *testing.T) {
+	client := pb.NewDangerServiceClient(dialTestServer(t))
+	first := raise(t, client, "3")
+	second := raise(t, client, "3")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	lastEventId := first.Id - 1
+	stream, err := client.StreamAlerts(ctx, &pb.StreamAlertsRequest{DangerType: []string{"SOS"}, LastEventId: &lastEventId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var received []uint64
+	for len(received) < 2 {
+		alert, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		received = append(received, alert.Id)
+	}
+	third := raise(t, client, "3")
+	alert, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	received = append(received, alert.Id)
+	if fmt.Sprint(received) != fmt.Sprint([]uint64{first.Id, second.Id, third.Id}) {
+		t.Errorf("received %v, want each alert once in Id order", received)
+	}
+}
+
+func TestAlertActions(t *testing.T) {
+	client := pb.NewDangerServiceClient(dialTestServer(t))
+	ctx := context.Background()
+
+	if _, err := client.RaiseAlert(ctx, &pb.RawWorkerInfo{GroundNumber: "4"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("RaiseAlert without a helmet error = %v, want InvalidArgument", err)
+	}
+
+	alert := raise(t, client, "4")
+	tests := []struct {
+		name   string
+		action func(context.Context, *pb.AlertActionRequest, ...grpc.CallOption) (*pb.Alert, error)
+		req    *pb.AlertActionRequest
+		code   codes.Code
+	}{
+		{"ack without by", client.AcknowledgeAlert, &pb.AlertActionRequest{Id: alert.Id}, codes.InvalidArgument},
+		{"ack unknown", client.AcknowledgeAlert, &pb.AlertActionRequest{Id: alert.Id + 1000, By: "ops"}, codes.NotFound},
+		{"ack", client.AcknowledgeAlert, &pb.AlertActionRequest{Id: alert.Id, By: "ops"}, codes.OK},
+		{"resolve", client.ResolveAlert, &pb.AlertActionRequest{Id: alert.Id, By: "ops"}, codes.OK},
+		{"ack resolved", client.AcknowledgeAlert, &pb.AlertActionRequest{Id: alert.Id, By: "ops"}, codes.FailedPrecondition},
+	}
+	for _, test := range tests {
+		if _, err := test.action(ctx, test.req); status.Code(err) != test.code {
+			t.Errorf("%v: error = %v, want %v", test.name, err, test.code)
+		}
+	}
+
+	resolved, err := client.ListAlerts(ctx, &pb.ListAlertsRequest{State: []string{danger.StateResolved}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	isListed := false
+	for _, listed := range resolved.Alerts {
+		isListed = isListed || (listed.Id == alert.Id && listed.ResolvedBy == "ops")
+	}
+	if !isListed {
+		t.Errorf("resolved alerts %v don't hold %v", resolved.Alerts, alert.Id)
+	}
+}
@@ -0,0 +1,1147 @@
+// gRPC API of the helmet monitoring backend, served next to the REST routes when GRPC_LISTEN_ADDR is set
+// Messages mirror the JSON bodies of the REST routes, field comments only note where they differ
+//
+// Regenerate the Go code from the repository root with
+//   protoc --go_out=. --go_opt=module=go_backend --go-grpc_out=. --go-grpc_opt=module=go_backend proto/smlr.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: proto/smlr.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	WorkerInfoService_GetWorkerInfo_FullMethodName      = "/smlr.v1.WorkerInfoService/GetWorkerInfo"
+	WorkerInfoService_ListWorkerInfo_FullMethodName     = "/smlr.v1.WorkerInfoService/ListWorkerInfo"
+	WorkerInfoService_InsertWorkerInfo_FullMethodName   = "/smlr.v1.WorkerInfoService/InsertWorkerInfo"
+	WorkerInfoService_UpdateWorkerInfo_FullMethodName   = "/smlr.v1.WorkerInfoService/UpdateWorkerInfo"
+	WorkerInfoService_DeleteWorkerInfo_FullMethodName   = "/smlr.v1.WorkerInfoService/DeleteWorkerInfo"
+	WorkerInfoService_InsertBatch_FullMethodName        = "/smlr.v1.WorkerInfoService/InsertBatch"
+	WorkerInfoService_IngestTelemetry_FullMethodName    = "/smlr.v1.WorkerInfoService/IngestTelemetry"
+	WorkerInfoService_GetReadings_FullMethodName        = "/smlr.v1.WorkerInfoService/GetReadings"
+	WorkerInfoService_AggregateReadings_FullMethodName  = "/smlr.v1.WorkerInfoService/AggregateReadings"
+	WorkerInfoService_DownsampleReadings_FullMethodName = "/smlr.v1.WorkerInfoService/DownsampleReadings"
+	WorkerInfoService_ListGaps_FullMethodName           = "/smlr.v1.WorkerInfoService/ListGaps"
+)
+
+// WorkerInfoServiceClient is the client API for WorkerInfoService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type WorkerInfoServiceClient interface {
+	// GET /userinfo?Id=
+	GetWorkerInfo(ctx context.Context, in *GetWorkerInfoRequest, opts ...grpc.CallOption) (*WorkerInfo, error)
+	// GET /userinfo with sdate and edate or range, paged like ?limit=&cursor=
+	ListWorkerInfo(ctx context.Context, in *ListWorkerInfoRequest, opts ...grpc.CallOption) (*ListWorkerInfoResponse, error)
+	// POST /userinfo
+	InsertWorkerInfo(ctx context.Context, in *RawWorkerInfo, opts ...grpc.CallOption) (*InsertWorkerInfoResponse, error)
+	// PUT /userinfo
+	UpdateWorkerInfo(ctx context.Context, in *WorkerInfo, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// DELETE /userinfo?Id=
+	DeleteWorkerInfo(ctx context.Context, in *DeleteWorkerInfoRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// POST /userinfo/batch
+	InsertBatch(ctx context.Context, in *InsertBatchRequest, opts ...grpc.CallOption) (*BatchReport, error)
+	// Streams readings one by one through the POST /userinfo path, the summary is returned once the client closes
+	IngestTelemetry(ctx context.Context, opts ...grpc.CallOption) (WorkerInfoService_IngestTelemetryClient, error)
+	// GET /userinfo/:id/readings
+	GetReadings(ctx context.Context, in *ReadingsRequest, opts ...grpc.CallOption) (*ReadingsResponse, error)
+	// GET /userinfo/:id/readings/aggregate
+	AggregateReadings(ctx context.Context, in *AggregateRequest, opts ...grpc.CallOption) (*AggregateResponse, error)
+	// GET /userinfo/:id/readings/downsample
+	DownsampleReadings(ctx context.Context, in *DownsampleRequest, opts ...grpc.CallOption) (*DownsampleResponse, error)
+	// GET /userinfo/gaps
+	ListGaps(ctx context.Context, in *ListGapsRequest, opts ...grpc.CallOption) (*ListGapsResponse, error)
+}
+
+type workerInfoServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWorkerInfoServiceClient(cc grpc.ClientConnInterface) WorkerInfoServiceClient {
+	return &workerInfoServiceClient{cc}
+}
+
+func (c *workerInfoServiceClient) GetWorkerInfo(ctx context.Context, in *GetWorkerInfoRequest, opts ...grpc.CallOption) (*WorkerInfo, error) {
+	out := new(WorkerInfo)
+	err := c.cc.Invoke(ctx, WorkerInfoService_GetWorkerInfo_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *workerInfoServiceClient) ListWorkerInfo(ctx context.Context, in *ListWorkerInfoRequest, opts ...grpc.CallOption) (*ListWorkerInfoResponse, error) {
+	out := new(ListWorkerInfoResponse)
+	err := c.cc.Invoke(ctx, WorkerInfoService_ListWorkerInfo_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *workerInfoServiceClient) InsertWorkerInfo(ctx context.Context, in *RawWorkerInfo, opts ...grpc.CallOption) (*InsertWorkerInfoResponse, error) {
+	out := new(InsertWorkerInfoResponse)
+	err := c.cc.Invoke(ctx, WorkerInfoService_InsertWorkerInfo_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *workerInfoServiceClient) UpdateWorkerInfo(ctx context.Context, in *WorkerInfo, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, WorkerInfoService_UpdateWorkerInfo_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *workerInfoServiceClient) DeleteWorkerInfo(ctx context.Context, in *DeleteWorkerInfoRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, WorkerInfoService_DeleteWorkerInfo_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *workerInfoServiceClient) InsertBatch(ctx context.Context, in *InsertBatchRequest, opts ...grpc.CallOption) (*BatchReport, error) {
+	out := new(BatchReport)
+	err := c.cc.Invoke(ctx, WorkerInfoService_InsertBatch_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *workerInfoServiceClient) IngestTelemetry(ctx context.Context, opts ...grpc.CallOption) (WorkerInfoService_IngestTelemetryClient, error) {
+	stream, err := c.cc.NewStream(ctx, &WorkerInfoService_ServiceDesc.Streams[0], WorkerInfoService_IngestTelemetry_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &workerInfoServiceIngestTelemetryClient{stream}
+	return x, nil
+}
+
+type WorkerInfoService_IngestTelemetryClient interface {
+	Send(*RawWorkerInfo) error
+	CloseAndRecv() (*BatchReport, error)
+	grpc.ClientStream
+}
+
+type workerInfoServiceIngestTelemetryClient struct {
+	grpc.ClientStream
+}
+
+func (x *workerInfoServiceIngestTelemetryClient) Send(m *RawWorkerInfo) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *workerInfoServiceIngestTelemetryClient) CloseAndRecv() (*BatchReport, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(BatchReport)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *workerInfoServiceClient) GetReadings(ctx context.Context, in *ReadingsRequest, opts ...grpc.CallOption) (*ReadingsResponse, error) {
+	out := new(ReadingsResponse)
+	err := c.cc.Invoke(ctx, WorkerInfoService_GetReadings_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *workerInfoServiceClient) AggregateReadings(ctx context.Context, in *AggregateRequest, opts ...grpc.CallOption) (*AggregateResponse, error) {
+	out := new(AggregateResponse)
+	err := c.cc.Invoke(ctx, WorkerInfoService_AggregateReadings_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *workerInfoServiceClient) DownsampleReadings(ctx context.Context, in *DownsampleRequest, opts ...grpc.CallOption) (*DownsampleResponse, error) {
+	out := new(DownsampleResponse)
+	err := c.cc.Invoke(ctx, WorkerInfoService_DownsampleReadings_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *workerInfoServiceClient) ListGaps(ctx context.Context, in *ListGapsRequest, opts ...grpc.CallOption) (*ListGapsResponse, error) {
+	out := new(ListGapsResponse)
+	err := c.cc.Invoke(ctx, WorkerInfoService_ListGaps_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// WorkerInfoServiceServer is the server API for WorkerInfoService service.
+// All implementations must embed UnimplementedWorkerInfoServiceServer
+// for forward compatibility
+type WorkerInfoServiceServer interface {
+	// GET /userinfo?Id=
+	GetWorkerInfo(context.Context, *GetWorkerInfoRequest) (*WorkerInfo, error)
+	// GET /userinfo with sdate and edate or range, paged like ?limit=&cursor=
+	ListWorkerInfo(context.Context, *ListWorkerInfoRequest) (*ListWorkerInfoResponse, error)
+	// POST /userinfo
+	InsertWorkerInfo(context.Context, *RawWorkerInfo) (*InsertWorkerInfoResponse, error)
+	// PUT /userinfo
+	UpdateWorkerInfo(context.Context, *WorkerInfo) (*emptypb.Empty, error)
+	// DELETE /userinfo?Id=
+	DeleteWorkerInfo(context.Context, *DeleteWorkerInfoRequest) (*emptypb.Empty, error)
+	// POST /userinfo/batch
+	InsertBatch(context.Context, *InsertBatchRequest) (*BatchReport, error)
+	// Streams readings one by one through the POST /userinfo path, the summary is returned once the client closes
+	IngestTelemetry(WorkerInfoService_IngestTelemetryServer) error
+	// GET /userinfo/:id/readings
+	GetReadings(context.Context, *ReadingsRequest) (*ReadingsResponse, error)
+	// GET /userinfo/:id/readings/aggregate
+	AggregateReadings(context.Context, *AggregateRequest) (*AggregateResponse, error)
+	// GET /userinfo/:id/readings/downsample
+	DownsampleReadings(context.Context, *DownsampleRequest) (*DownsampleResponse, error)
+	// GET /userinfo/gaps
+	ListGaps(context.Context, *ListGapsRequest) (*ListGapsResponse, error)
+	mustEmbedUnimplementedWorkerInfoServiceServer()
+}
+
+// UnimplementedWorkerInfoServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedWorkerInfoServiceServer struct {
+}
+
+func (UnimplementedWorkerInfoServiceServer) GetWorkerInfo(context.Context, *GetWorkerInfoRequest) (*WorkerInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetWorkerInfo not implemented")
+}
+func (UnimplementedWorkerInfoServiceServer) ListWorkerInfo(context.Context, *ListWorkerInfoRequest) (*ListWorkerInfoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListWorkerInfo not implemented")
+}
+func (UnimplementedWorkerInfoServiceServer) InsertWorkerInfo(context.Context, *RawWorkerInfo) (*InsertWorkerInfoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InsertWorkerInfo not implemented")
+}
+func (UnimplementedWorkerInfoServiceServer) UpdateWorkerInfo(context.Context, *WorkerInfo) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateWorkerInfo not implemented")
+}
+func (UnimplementedWorkerInfoServiceServer) DeleteWorkerInfo(context.Context, *DeleteWorkerInfoRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteWorkerInfo not implemented")
+}
+func (UnimplementedWorkerInfoServiceServer) InsertBatch(context.Context, *InsertBatchRequest) (*BatchReport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InsertBatch not implemented")
+}
+func (UnimplementedWorkerInfoServiceServer) IngestTelemetry(WorkerInfoService_IngestTelemetryServer) error {
+	return status.Errorf(codes.Unimplemented, "method IngestTelemetry not implemented")
+}
+func (UnimplementedWorkerInfoServiceServer) GetReadings(context.Context, *ReadingsRequest) (*ReadingsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetReadings not implemented")
+}
+func (UnimplementedWorkerInfoServiceServer) AggregateReadings(context.Context, *AggregateRequest) (*AggregateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AggregateReadings not implemented")
+}
+func (UnimplementedWorkerInfoServiceServer) DownsampleReadings(context.Context, *DownsampleRequest) (*DownsampleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DownsampleReadings not implemented")
+}
+func (UnimplementedWorkerInfoServiceServer) ListGaps(context.Context, *ListGapsRequest) (*ListGapsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListGaps not implemented")
+}
+func (UnimplementedWorkerInfoServiceServer) mustEmbedUnimplementedWorkerInfoServiceServer() {}
+
+// UnsafeWorkerInfoServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to WorkerInfoServiceServer will
+// result in compilation errors.
+type UnsafeWorkerInfoServiceServer interface {
+	mustEmbedUnimplementedWorkerInfoServiceServer()
+}
+
+func RegisterWorkerInfoServiceServer(s grpc.ServiceRegistrar, srv WorkerInfoServiceServer) {
+	s.RegisterService(&WorkerInfoService_ServiceDesc, srv)
+}
+
+func _WorkerInfoService_GetWorkerInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWorkerInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WorkerInfoServiceServer).GetWorkerInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WorkerInfoService_GetWorkerInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkerInfoServiceServer).GetWorkerInfo(ctx, req.(*GetWorkerInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WorkerInfoService_ListWorkerInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListWorkerInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WorkerInfoServiceServer).ListWorkerInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WorkerInfoService_ListWorkerInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkerInfoServiceServer).ListWorkerInfo(ctx, req.(*ListWorkerInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WorkerInfoService_InsertWorkerInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RawWorkerInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WorkerInfoServiceServer).InsertWorkerInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WorkerInfoService_InsertWorkerInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkerInfoServiceServer).InsertWorkerInfo(ctx, req.(*RawWorkerInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WorkerInfoService_UpdateWorkerInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WorkerInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WorkerInfoServiceServer).UpdateWorkerInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WorkerInfoService_UpdateWorkerInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkerInfoServiceServer).UpdateWorkerInfo(ctx, req.(*WorkerInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WorkerInfoService_DeleteWorkerInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteWorkerInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WorkerInfoServiceServer).DeleteWorkerInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WorkerInfoService_DeleteWorkerInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkerInfoServiceServer).DeleteWorkerInfo(ctx, req.(*DeleteWorkerInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WorkerInfoService_InsertBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InsertBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WorkerInfoServiceServer).InsertBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WorkerInfoService_InsertBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkerInfoServiceServer).InsertBatch(ctx, req.(*InsertBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WorkerInfoService_IngestTelemetry_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(WorkerInfoServiceServer).IngestTelemetry(&workerInfoServiceIngestTelemetryServer{stream})
+}
+
+type WorkerInfoService_IngestTelemetryServer interface {
+	SendAndClose(*BatchReport) error
+	Recv() (*RawWorkerInfo, error)
+	grpc.ServerStream
+}
+
+type workerInfoServiceIngestTelemetryServer struct {
+	grpc.ServerStream
+}
+
+func (x *workerInfoServiceIngestTelemetryServer) SendAndClose(m *BatchReport) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *workerInfoServiceIngestTelemetryServer) Recv() (*RawWorkerInfo, error) {
+	m := new(RawWorkerInfo)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _WorkerInfoService_GetReadings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReadingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WorkerInfoServiceServer).GetReadings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WorkerInfoService_GetReadings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkerInfoServiceServer).GetReadings(ctx, req.(*ReadingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WorkerInfoService_AggregateReadings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AggregateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WorkerInfoServiceServer).AggregateReadings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WorkerInfoService_AggregateReadings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkerInfoServiceServer).AggregateReadings(ctx, req.(*AggregateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WorkerInfoService_DownsampleReadings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DownsampleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WorkerInfoServiceServer).DownsampleReadings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WorkerInfoService_DownsampleReadings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkerInfoServiceServer).DownsampleReadings(ctx, req.(*DownsampleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WorkerInfoService_ListGaps_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListGapsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WorkerInfoServiceServer).ListGaps(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WorkerInfoService_ListGaps_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkerInfoServiceServer).ListGaps(ctx, req.(*ListGapsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// WorkerInfoService_ServiceDesc is the grpc.ServiceDesc for WorkerInfoService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var WorkerInfoService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "smlr.v1.WorkerInfoService",
+	HandlerType: (*WorkerInfoServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetWorkerInfo",
+			Handler:    _WorkerInfoService_GetWorkerInfo_Handler,
+		},
+		{
+			MethodName: "ListWorkerInfo",
+			Handler:    _WorkerInfoService_ListWorkerInfo_Handler,
+		},
+		{
+			MethodName: "InsertWorkerInfo",
+			Handler:    _WorkerInfoService_InsertWorkerInfo_Handler,
+		},
+		{
+			MethodName: "UpdateWorkerInfo",
+			Handler:    _WorkerInfoService_UpdateWorkerInfo_Handler,
+		},
+		{
+			MethodName: "DeleteWorkerInfo",
+			Handler:    _WorkerInfoService_DeleteWorkerInfo_Handler,
+		},
+		{
+			MethodName: "InsertBatch",
+			Handler:    _WorkerInfoService_InsertBatch_Handler,
+		},
+		{
+			MethodName: "GetReadings",
+			Handler:    _WorkerInfoService_GetReadings_Handler,
+		},
+		{
+			MethodName: "AggregateReadings",
+			Handler:    _WorkerInfoService_AggregateReadings_Handler,
+		},
+		{
+			MethodName: "DownsampleReadings",
+			Handler:    _WorkerInfoService_DownsampleReadings_Handler,
+		},
+		{
+			MethodName: "ListGaps",
+			Handler:    _WorkerInfoService_ListGaps_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "IngestTelemetry",
+			Handler:       _WorkerInfoService_IngestTelemetry_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "proto/smlr.proto",
+}
+
+const (
+	ContactService_ListContacts_FullMethodName  = "/smlr.v1.ContactService/ListContacts"
+	ContactService_InsertContact_FullMethodName = "/smlr.v1.ContactService/InsertContact"
+	ContactService_DeleteContact_FullMethodName = "/smlr.v1.ContactService/DeleteContact"
+)
+
+// ContactServiceClient is the client API for ContactService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ContactServiceClient interface {
+	ListContacts(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListContactsResponse, error)
+	InsertContact(ctx context.Context, in *Contact, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	DeleteContact(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+type contactServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewContactServiceClient(cc grpc.ClientConnInterface) ContactServiceClient {
+	return &contactServiceClient{cc}
+}
+
+func (c *contactServiceClient) ListContacts(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListContactsResponse, error) {
+	out := new(ListContactsResponse)
+	err := c.cc.Invoke(ctx, ContactService_ListContacts_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *contactServiceClient) InsertContact(ctx context.Context, in *Contact, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, ContactService_InsertContact_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *contactServiceClient) DeleteContact(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, ContactService_DeleteContact_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ContactServiceServer is the server API for ContactService service.
+// All implementations must embed UnimplementedContactServiceServer
+// for forward compatibility
+type ContactServiceServer interface {
+	ListContacts(context.Context, *ListRequest) (*ListContactsResponse, error)
+	InsertContact(context.Context, *Contact) (*emptypb.Empty, error)
+	DeleteContact(context.Context, *DeleteRequest) (*emptypb.Empty, error)
+	mustEmbedUnimplementedContactServiceServer()
+}
+
+// UnimplementedContactServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedContactServiceServer struct {
+}
+
+func (UnimplementedContactServiceServer) ListContacts(context.Context, *ListRequest) (*ListContactsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListContacts not implemented")
+}
+func (UnimplementedContactServiceServer) InsertContact(context.Context, *Contact) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InsertContact not implemented")
+}
+func (UnimplementedContactServiceServer) DeleteContact(context.Context, *DeleteRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteContact not implemented")
+}
+func (UnimplementedContactServiceServer) mustEmbedUnimplementedContactServiceServer() {}
+
+// UnsafeContactServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ContactServiceServer will
+// result in compilation errors.
+type UnsafeContactServiceServer interface {
+	mustEmbedUnimplementedContactServiceServer()
+}
+
+func RegisterContactServiceServer(s grpc.ServiceRegistrar, srv ContactServiceServer) {
+	s.RegisterService(&ContactService_ServiceDesc, srv)
+}
+
+func _ContactService_ListContacts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ContactServiceServer).ListContacts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ContactService_ListContacts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ContactServiceServer).ListContacts(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ContactService_InsertContact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Contact)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ContactServiceServer).InsertContact(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ContactService_InsertContact_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ContactServiceServer).InsertContact(ctx, req.(*Contact))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ContactService_DeleteContact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ContactServiceServer).DeleteContact(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ContactService_DeleteContact_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ContactServiceServer).DeleteContact(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ContactService_ServiceDesc is the grpc.ServiceDesc for ContactService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ContactService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "smlr.v1.ContactService",
+	HandlerType: (*ContactServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListContacts",
+			Handler:    _ContactService_ListContacts_Handler,
+		},
+		{
+			MethodName: "InsertContact",
+			Handler:    _ContactService_InsertContact_Handler,
+		},
+		{
+			MethodName: "DeleteContact",
+			Handler:    _ContactService_DeleteContact_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/smlr.proto",
+}
+
+const (
+	HospitalService_ListHospitals_FullMethodName  = "/smlr.v1.HospitalService/ListHospitals"
+	HospitalService_InsertHospital_FullMethodName = "/smlr.v1.HospitalService/InsertHospital"
+	HospitalService_DeleteHospital_FullMethodName = "/smlr.v1.HospitalService/DeleteHospital"
+)
+
+// HospitalServiceClient is the client API for HospitalService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type HospitalServiceClient interface {
+	ListHospitals(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListHospitalsResponse, error)
+	InsertHospital(ctx context.Context, in *Hospital, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	DeleteHospital(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+type hospitalServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewHospitalServiceClient(cc grpc.ClientConnInterface) HospitalServiceClient {
+	return &hospitalServiceClient{cc}
+}
+
+func (c *hospitalServiceClient) ListHospitals(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListHospitalsResponse, error) {
+	out := new(ListHospitalsResponse)
+	err := c.cc.Invoke(ctx, HospitalService_ListHospitals_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hospitalServiceClient) InsertHospital(ctx context.Context, in *Hospital, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, HospitalService_InsertHospital_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hospitalServiceClient) DeleteHospital(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, HospitalService_DeleteHospital_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// HospitalServiceServer is the server API for HospitalService service.
+// All implementations must embed UnimplementedHospitalServiceServer
+// for forward compatibility
+type HospitalServiceServer interface {
+	ListHospitals(context.Context, *ListRequest) (*ListHospitalsResponse, error)
+	InsertHospital(context.Context, *Hospital) (*emptypb.Empty, error)
+	DeleteHospital(context.Context, *DeleteRequest) (*emptypb.Empty, error)
+	mustEmbedUnimplementedHospitalServiceServer()
+}
+
+// UnimplementedHospitalServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedHospitalServiceServer struct {
+}
+
+func (UnimplementedHospitalServiceServer) ListHospitals(context.Context, *ListRequest) (*ListHospitalsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListHospitals not implemented")
+}
+func (UnimplementedHospitalServiceServer) InsertHospital(context.Context, *Hospital) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InsertHospital not implemented")
+}
+func (UnimplementedHospitalServiceServer) DeleteHospital(context.Context, *DeleteRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteHospital not implemented")
+}
+func (UnimplementedHospitalServiceServer) mustEmbedUnimplementedHospitalServiceServer() {}
+
+// UnsafeHospitalServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to HospitalServiceServer will
+// result in compilation errors.
+type UnsafeHospitalServiceServer interface {
+	mustEmbedUnimplementedHospitalServiceServer()
+}
+
+func RegisterHospitalServiceServer(s grpc.ServiceRegistrar, srv HospitalServiceServer) {
+	s.RegisterService(&HospitalService_ServiceDesc, srv)
+}
+
+func _HospitalService_ListHospitals_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HospitalServiceServer).ListHospitals(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HospitalService_ListHospitals_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HospitalServiceServer).ListHospitals(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HospitalService_InsertHospital_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Hospital)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HospitalServiceServer).InsertHospital(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HospitalService_InsertHospital_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HospitalServiceServer).InsertHospital(ctx, req.(*Hospital))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HospitalService_DeleteHospital_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HospitalServiceServer).DeleteHospital(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HospitalService_DeleteHospital_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HospitalServiceServer).DeleteHospital(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// HospitalService_ServiceDesc is the grpc.ServiceDesc for HospitalService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var HospitalService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "smlr.v1.HospitalService",
+	HandlerType: (*HospitalServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListHospitals",
+			Handler:    _HospitalService_ListHospitals_Handler,
+		},
+		{
+			MethodName: "InsertHospital",
+			Handler:    _HospitalService_InsertHospital_Handler,
+		},
+		{
+			MethodName: "DeleteHospital",
+			Handler:    _HospitalService_DeleteHospital_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/smlr.proto",
+}
+
+const (
+	DangerService_ListAlerts_FullMethodName       = "/smlr.v1.DangerService/ListAlerts"
+	DangerService_RaiseAlert_FullMethodName       = "/smlr.v1.DangerService/RaiseAlert"
+	DangerService_AcknowledgeAlert_FullMethodName = "/smlr.v1.DangerService/AcknowledgeAlert"
+	DangerService_ResolveAlert_FullMethodName     = "/smlr.v1.DangerService/ResolveAlert"
+	DangerService_StreamAlerts_FullMethodName     = "/smlr.v1.DangerService/StreamAlerts"
+)
+
+// DangerServiceClient is the client API for DangerService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type DangerServiceClient interface {
+	// GET /danger
+	ListAlerts(ctx context.Context, in *ListAlertsRequest, opts ...grpc.CallOption) (*ListAlertsResponse, error)
+	// POST /danger
+	RaiseAlert(ctx context.Context, in *RawWorkerInfo, opts ...grpc.CallOption) (*RaiseAlertResponse, error)
+	// POST /danger/:id/ack
+	AcknowledgeAlert(ctx context.Context, in *AlertActionRequest, opts ...grpc.CallOption) (*Alert, error)
+	// POST /danger/:id/resolve
+	ResolveAlert(ctx context.Context, in *AlertActionRequest, opts ...grpc.CallOption) (*Alert, error)
+	// GET /danger/stream, alerts after last_event_id are replayed first
+	StreamAlerts(ctx context.Context, in *StreamAlertsRequest, opts ...grpc.CallOption) (DangerService_StreamAlertsClient, error)
+}
+
+type dangerServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDangerServiceClient(cc grpc.ClientConnInterface) DangerServiceClient {
+	return &dangerServiceClient{cc}
+}
+
+func (c *dangerServiceClient) ListAlerts(ctx context.Context, in *ListAlertsRequest, opts ...grpc.CallOption) (*ListAlertsResponse, error) {
+	out := new(ListAlertsResponse)
+	err := c.cc.Invoke(ctx, DangerService_ListAlerts_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dangerServiceClient) RaiseAlert(ctx context.Context, in *RawWorkerInfo, opts ...grpc.CallOption) (*RaiseAlertResponse, error) {
+	out := new(RaiseAlertResponse)
+	err := c.cc.Invoke(ctx, DangerService_RaiseAlert_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dangerServiceClient) AcknowledgeAlert(ctx context.Context, in *AlertActionRequest, opts ...grpc.CallOption) (*Alert, error) {
+	out := new(Alert)
+	err := c.cc.Invoke(ctx, DangerService_AcknowledgeAlert_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dangerServiceClient) ResolveAlert(ctx context.Context, in *AlertActionRequest, opts ...grpc.CallOption) (*Alert, error) {
+	out := new(Alert)
+	err := c.cc.Invoke(ctx, DangerService_ResolveAlert_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dangerServiceClient) StreamAlerts(ctx context.Context, in *StreamAlertsRequest, opts ...grpc.CallOption) (DangerService_StreamAlertsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &DangerService_ServiceDesc.Streams[0], DangerService_StreamAlerts_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &dangerServiceStreamAlertsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type DangerService_StreamAlertsClient interface {
+	Recv() (*Alert, error)
+	grpc.ClientStream
+}
+
+type dangerServiceStreamAlertsClient struct {
+	grpc.ClientStream
+}
+
+func (x *dangerServiceStreamAlertsClient) Recv() (*Alert, error) {
+	m := new(Alert)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// DangerServiceServer is the server API for DangerService service.
+// All implementations must embed UnimplementedDangerServiceServer
+// for forward compatibility
+type DangerServiceServer interface {
+	// GET /danger
+	ListAlerts(context.Context, *ListAlertsRequest) (*ListAlertsResponse, error)
+	// POST /danger
+	RaiseAlert(context.Context, *RawWorkerInfo) (*RaiseAlertResponse, error)
+	// POST /danger/:id/ack
+	AcknowledgeAlert(context.Context, *AlertActionRequest) (*Alert, error)
+	// POST /danger/:id/resolve
+	ResolveAlert(context.Context, *AlertActionRequest) (*Alert, error)
+	// GET /danger/stream, alerts after last_event_id are replayed first
+	StreamAlerts(*StreamAlertsRequest, DangerService_StreamAlertsServer) error
+	mustEmbedUnimplementedDangerServiceServer()
+}
+
+// UnimplementedDangerServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedDangerServiceServer struct {
+}
+
+func (UnimplementedDangerServiceServer) ListAlerts(context.Context, *ListAlertsRequest) (*ListAlertsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAlerts not implemented")
+}
+func (UnimplementedDangerServiceServer) RaiseAlert(context.Context, *RawWorkerInfo) (*RaiseAlertResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RaiseAlert not implemented")
+}
+func (UnimplementedDangerServiceServer) AcknowledgeAlert(context.Context, *AlertActionRequest) (*Alert, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AcknowledgeAlert not implemented")
+}
+func (UnimplementedDangerServiceServer) ResolveAlert(context.Context, *AlertActionRequest) (*Alert, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResolveAlert not implemented")
+}
+func (UnimplementedDangerServiceServer) StreamAlerts(*StreamAlertsRequest, DangerService_StreamAlertsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamAlerts not implemented")
+}
+func (UnimplementedDangerServiceServer) mustEmbedUnimplementedDangerServiceServer() {}
+
+// UnsafeDangerServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DangerServiceServer will
+// result in compilation errors.
+type UnsafeDangerServiceServer interface {
+	mustEmbedUnimplementedDangerServiceServer()
+}
+
+func RegisterDangerServiceServer(s grpc.ServiceRegistrar, srv DangerServiceServer) {
+	s.RegisterService(&DangerService_ServiceDesc, srv)
+}
+
+func _DangerService_ListAlerts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAlertsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DangerServiceServer).ListAlerts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DangerService_ListAlerts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DangerServiceServer).ListAlerts(ctx, req.(*ListAlertsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DangerService_RaiseAlert_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RawWorkerInfo)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DangerServiceServer).RaiseAlert(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DangerService_RaiseAlert_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DangerServiceServer).RaiseAlert(ctx, req.(*RawWorkerInfo))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DangerService_AcknowledgeAlert_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AlertActionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DangerServiceServer).AcknowledgeAlert(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DangerService_AcknowledgeAlert_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DangerServiceServer).AcknowledgeAlert(ctx, req.(*AlertActionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DangerService_ResolveAlert_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AlertActionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DangerServiceServer).ResolveAlert(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DangerService_ResolveAlert_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DangerServiceServer).ResolveAlert(ctx, req.(*AlertActionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DangerService_StreamAlerts_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamAlertsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DangerServiceServer).StreamAlerts(m, &dangerServiceStreamAlertsServer{stream})
+}
+
+type DangerService_StreamAlertsServer interface {
+	Send(*Alert) error
+	grpc.ServerStream
+}
+
+type dangerServiceStreamAlertsServer struct {
+	grpc.ServerStream
+}
+
+func (x *dangerServiceStreamAlertsServer) Send(m *Alert) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// DangerService_ServiceDesc is the grpc.ServiceDesc for DangerService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var DangerService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "smlr.v1.DangerService",
+	HandlerType: (*DangerServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListAlerts",
+			Handler:    _DangerService_ListAlerts_Handler,
+		},
+		{
+			MethodName: "RaiseAlert",
+			Handler:    _DangerService_RaiseAlert_Handler,
+		},
+		{
+			MethodName: "AcknowledgeAlert",
+			Handler:    _DangerService_AcknowledgeAlert_Handler,
+		},
+		{
+			MethodName: "ResolveAlert",
+			Handler:    _DangerService_ResolveAlert_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamAlerts",
+			Handler:       _DangerService_StreamAlerts_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proto/smlr.proto",
+}
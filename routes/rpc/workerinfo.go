@@ -0,0 +1,264 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"go_backend/routes/rpc/pb"
+	"go_backend/routes/userinfo"
+	"go_backend/util"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// DefaultDownsamplePoints is used when DownsampleRequest.points is 0, as the REST route does
+const DefaultDownsamplePoints = 500
+
+type workerInfoServer struct {
+	pb.UnimplementedWorkerInfoServiceServer
+}
+
+func (server *workerInfoServer) GetWorkerInfo(ctx context.Context, req *pb.GetWorkerInfoRequest) (*pb.WorkerInfo, error) {
+	if req.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "id not provided")
+	}
+	workerInfo, err := userinfo.GetStore().GetWorkerInfo(req.Id)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return toWorkerInfo(&workerInfo), nil
+}
+
+func (server *workerInfoServer) ListWorkerInfo(ctx context.Context, req *pb.ListWorkerInfoRequest) (*pb.ListWorkerInfoResponse, error) {
+	if req.Range == "" && (req.Sdate == "" || req.Edate == "") {
+		return nil, status.Error(codes.InvalidArgument, "sdate and edate, or range, are required")
+	}
+	sdate, edate, err := userinfo.ResolveListDates(req.Range, req.Sdate, req.Edate, time.Now(), util.GetGroundLocation(req.Ground))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	minScore := math.MinInt
+	if req.MinScore != nil {
+		minScore = int(*req.MinScore)
+	}
+	keep := userinfo.MatchFilter(minScore, req.Risk, req.Ground)
+	store := userinfo.GetStore()
+
+	if req.Limit == 0 && req.Cursor == "" {
+		workerInfoList, err := store.GetAllWorkerInfo(sdate, edate)
+		if err != nil {
+			return nil, storeError(err)
+		}
+		workerInfoList = userinfo.FilterWorkerInfo(workerInfoList, keep)
+		if req.SortByScore {
+			sort.SliceStable(workerInfoList, func(i, j int) bool {
+				return workerInfoList[i].EarlyWarningScore > workerInfoList[j].EarlyWarningScore
+			})
+		}
+		return &pb.ListWorkerInfoResponse{Items: toWorkerInfoList(workerInfoList)}, nil
+	}
+	if req.SortByScore {
+		return nil, status.Error(codes.InvalidArgument, "sort_by_score can't be combined with limit or cursor")
+	}
+	// Same scope as GET /userinfo, a cursor works with either API
+	page, err := util.ReadPage("userinfo|"+sdate+"|"+edate+"|"+req.Ground, req.Cursor, int(req.Limit),
+		func(after string, limit int) ([]userinfo.WorkerInfo, string, error) {
+			workerInfoList, next, err := store.GetWorkerInfoPage(sdate, edate, after, limit)
+			return userinfo.FilterWorkerInfo(workerInfoList, keep), next, err
+		})
+	if err != nil {
+		return nil, pageError(err)
+	}
+	return &pb.ListWorkerInfoResponse{Items: toWorkerInfoList(page.Items), NextCursor: page.NextCursor}, nil
+}
+
+func (server *workerInfoServer) InsertWorkerInfo(ctx context.Context, req *pb.RawWorkerInfo) (*pb.InsertWorkerInfoResponse, error) {
+	rawWorkInfo := fromRawWorkerInfo(req)
+	if reason := rawWorkInfo.Validate(); reason != "" {
+		return nil, status.Error(codes.InvalidArgument, reason)
+	}
+	workInfo, isNew, err := userinfo.Ingest(rawWorkInfo)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	if !isNew {
+		return &pb.InsertWorkerInfoResponse{Replayed: true}, nil
+	}
+	return &pb.InsertWorkerInfoResponse{WorkerInfo: toWorkerInfo(workInfo)}, nil
+}
+
+func (server *workerInfoServer) UpdateWorkerInfo(ctx context.Context, req *pb.WorkerInfo) (*emptypb.Empty, error) {
+	workInfo := fromWorkerInfo(req)
+	workInfo.FillEarlyWarningScore()
+	if _, err := userinfo.GetStore().UpdateWorkerInfo(workInfo); err != nil {
+		return nil, storeError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (server *workerInfoServer) DeleteWorkerInfo(ctx context.Context, req *pb.DeleteWorkerInfoRequest) (*emptypb.Empty, error) {
+	if req.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "id not provided")
+	}
+	if err := userinfo.GetStore().DeleteWorkerInfo(userinfo.WorkerInfo{Id: req.Id}); err != nil {
+		return nil, storeError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (server *workerInfoServer) InsertBatch(ctx context.Context, req *pb.InsertBatchRequest) (*pb.BatchReport, error) {
+	if len(req.Items) > userinfo.MaxBatchItems {
+		return nil, status.Errorf(codes.InvalidArgument, "At most %v readings per batch", userinfo.MaxBatchItems)
+	}
+	rawWorkInfoList := make([]*userinfo.RawWorkerInfo, len(req.Items))
+	for i := range req.Items {
+		rawWorkInfoList[i] = fromRawWorkerInfo(req.Items[i])
+	}
+	report, err := userinfo.IngestBatch(rawWorkInfoList, nil)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return toBatchReport(&report), nil
+}
+
+// IngestTelemetry Ingests every reading as it arrives, a bad reading is reported without ending the stream
+func (server *workerInfoServer) IngestTelemetry(stream pb.WorkerInfoService_IngestTelemetryServer) error {
+	report := userinfo.BatchReport{Results: []userinfo.BatchItemResult{}}
+	for index := 0; ; index++ {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(toBatchReport(&report))
+		}
+		if err != nil {
+			return err
+		}
+
+		result := userinfo.BatchItemResult{Index: index, Status: userinfo.BatchRejected}
+		rawWorkInfo := fromRawWorkerInfo(req)
+		if result.Reason = rawWorkInfo.Validate(); result.Reason == "" {
+			var workInfo *userinfo.WorkerInfo
+			var isNew bool
+			workInfo, isNew, err = userinfo.Ingest(rawWorkInfo)
+			switch {
+			case err != nil:
+				result.Reason = err.Error()
+			case !isNew:
+				result.Status = userinfo.BatchDuplicate
+			default:
+				result.Status = userinfo.BatchAccepted
+				result.Id = workInfo.Id
+			}
+		}
+		switch result.Status {
+		case userinfo.BatchAccepted:
+			report.Accepted++
+		case userinfo.BatchDuplicate:
+			report.Duplicates++
+		default:
+			report.Rejected++
+		}
+		report.Results = append(report.Results, result)
+	}
+}
+
+func readingsRange(id string, query userinfo.ReadingsQuery) (string, string, error) {
+	from, to, err := query.Resolve(id, time.Now())
+	if err != nil {
+		return "", "", status.Error(codes.InvalidArgument, err.Error())
+	}
+	return userinfo.FormatTimestamp(from), userinfo.FormatTimestamp(to), nil
+}
+
+func (server *workerInfoServer) GetReadings(ctx context.Context, req *pb.ReadingsRequest) (*pb.ReadingsResponse, error) {
+	from, to, err := readingsRange(req.Id, userinfo.ReadingsQuery{From: req.From, To: req.To, Range: req.Range})
+	if err != nil {
+		return nil, err
+	}
+	readings, err := userinfo.GetStore().GetReadings(req.Id, from, to)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return &pb.ReadingsResponse{Readings: toWorkerInfoList(readings)}, nil
+}
+
+func (server *workerInfoServer) AggregateReadings(ctx context.Context, req *pb.AggregateRequest) (*pb.AggregateResponse, error) {
+	bucket := req.Bucket
+	if bucket == "" {
+		bucket = "5m"
+	}
+	size, isFound := userinfo.BucketSizes[bucket]
+	if !isFound {
+		return nil, status.Error(codes.InvalidArgument, "bucket must be 1m, 5m or 1h")
+	}
+	from, to, err := readingsRange(req.Id, userinfo.ReadingsQuery{From: req.From, To: req.To, Range: req.Range})
+	if err != nil {
+		return nil, err
+	}
+
+	readings, err := userinfo.GetStore().GetReadings(req.Id, from, to)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	buckets := userinfo.Aggregate(readings, size, userinfo.GetLocation(req.Id))
+	response := &pb.AggregateResponse{Buckets: make([]*pb.Bucket, len(buckets))}
+	for i, bucket := range buckets {
+		fields := make(map[string]*pb.FieldStats)
+		for field, stats := range bucket.Fields {
+			fields[field] = &pb.FieldStats{Min: stats.Min, Max: stats.Max, Avg: stats.Avg, Last: stats.Last}
+		}
+		response.Buckets[i] = &pb.Bucket{Start: timestamppb.New(bucket.Start), Count: int32(bucket.Count), Fields: fields}
+	}
+	return response, nil
+}
+
+func (server *workerInfoServer) DownsampleReadings(ctx context.Context, req *pb.DownsampleRequest) (*pb.DownsampleResponse, error) {
+	threshold := int(req.Points)
+	if threshold == 0 {
+		threshold = DefaultDownsamplePoints
+	}
+	if threshold < 3 || threshold > userinfo.MaxDownsamplePoints {
+		return nil, status.Error(codes.InvalidArgument, "points must be a number between 3 and "+strconv.Itoa(userinfo.MaxDownsamplePoints))
+	}
+	fields := userinfo.VitalFields
+	if req.Field != "" {
+		if _, isKnown := (&userinfo.WorkerInfo{}).GetField(req.Field); !isKnown {
+			return nil, status.Error(codes.InvalidArgument, "Unknown field "+req.Field)
+		}
+		fields = []string{req.Field}
+	}
+	from, to, err := readingsRange(req.Id, userinfo.ReadingsQuery{From: req.From, To: req.To, Range: req.Range})
+	if err != nil {
+		return nil, err
+	}
+
+	readings, err := userinfo.GetStore().GetReadings(req.Id, from, to)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	response := &pb.DownsampleResponse{Series: make(map[string]*pb.Series)}
+	for _, field := range fields {
+		points := userinfo.Downsample(userinfo.Series(readings, field), threshold)
+		series := &pb.Series{Points: make([]*pb.Point, len(points))}
+		for i, point := range points {
+			series.Points[i] = &pb.Point{At: timestamppb.New(point.At), Value: point.Value}
+		}
+		response.Series[field] = series
+	}
+	return response, nil
+}
+
+func (server *workerInfoServer) ListGaps(ctx context.Context, req *pb.ListGapsRequest) (*pb.ListGapsResponse, error) {
+	gaps := userinfo.ListGaps(req.Id)
+	response := &pb.ListGapsResponse{Gaps: make([]*pb.Gap, len(gaps))}
+	for i, gap := range gaps {
+		response.Gaps[i] = &pb.Gap{Id: gap.Id, From: gap.From, To: gap.To, DetectedAt: timestamppb.New(gap.DetectedAt)}
+	}
+	return response, nil
+}
@@ -0,0 +1,608 @@
+package rules
+
+/*
+Rule expressions reference WorkerInfo fields of the current reading
+
+	GasLevel > 50 && for(2m)
+	avg(HeartRate, 5m) > 140
+	Spo2Level < 90 || (HeartRate > 150 && Temperature > 38)
+
+Operators: || && ! < <= > >= == != + - * /
+Functions: avg, min, max (field, window) over the worker's recent readings
+           for(duration) the rest of the expression must hold that long, only allowed as a top level && operand
+Durations: 30s, 5m, 1h (and combinations Go understands, 1h30m)
+*/
+
+import (
+	"fmt"
+	"go_backend/routes/userinfo"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Fields usable in expressions
+var Fields = []string{"Spo2Level", "Temperature", "GasLevel", "HeartRate"}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenDuration
+	tokenIdent
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+type token struct {
+	kind     tokenKind
+	text     string
+	pos      int // 1 based column
+	number   float64
+	duration time.Duration
+}
+
+// SyntaxError points at the column of the expression that couldn't be understood
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (err *SyntaxError) Error() string {
+	return fmt.Sprintf("col %d: %s", err.Pos, err.Msg)
+}
+
+func lex(source string) ([]token, error) {
+	var tokens []token
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case unicode.IsDigit(r) || r == '.':
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			// A unit right after the number makes it a duration, 90s, 5m, 1h30m
+			if i < len(runes) && unicode.IsLetter(runes[i]) {
+				for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '.') {
+					i++
+				}
+				text := string(runes[start:i])
+				duration, err := time.ParseDuration(text)
+				if err != nil {
+					return nil, &SyntaxError{Pos: start + 1, Msg: fmt.Sprintf("invalid duration %q", text)}
+				}
+				tokens = append(tokens, token{kind: tokenDuration, text: text, pos: start + 1, duration: duration})
+				continue
+			}
+			text := string(runes[start:i])
+			number, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, &SyntaxError{Pos: start + 1, Msg: fmt.Sprintf("invalid number %q", text)}
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: text, pos: start + 1, number: number})
+			continue
+		case unicode.IsLetter(r) || r == '_':
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(runes[start:i]), pos: start + 1})
+			continue
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: start + 1})
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: start + 1})
+		case r == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", pos: start + 1})
+		default:
+			operator := ""
+			if i+1 < len(runes) {
+				switch two := string(runes[i : i+2]); two {
+				case "&&", "||", "<=", ">=", "==", "!=":
+					operator = two
+				}
+			}
+			if operator == "" && strings.ContainsRune("<>!+-*/", r) {
+				operator = string(r)
+			}
+			if operator == "" {
+				return nil, &SyntaxError{Pos: start + 1, Msg: fmt.Sprintf("unexpected %q", string(r))}
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: operator, pos: start + 1})
+			i += len([]rune(operator))
+			continue
+		}
+		i++
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(runes) + 1}), nil
+}
+
+type valueType int
+
+const (
+	typeNumber valueType = iota
+	typeBool
+)
+
+func (t valueType) String() string {
+	if t == typeBool {
+		return "boolean"
+	}
+	return "number"
+}
+
+// History gives aggregate functions access to a worker's recent readings
+type History interface {
+	Values(field string, window time.Duration) []float64
+}
+
+type evalContext struct {
+	current *userinfo.WorkerInfo
+	history History
+}
+
+// node is a type checked expression
+type node interface {
+	Type() valueType
+	Number(ctx *evalContext) float64
+	Bool(ctx *evalContext) bool
+}
+
+type numberNode struct{ value float64 }
+
+func (n *numberNode) Type() valueType                 { return typeNumber }
+func (n *numberNode) Number(ctx *evalContext) float64 { return n.value }
+func (n *numberNode) Bool(ctx *evalContext) bool      { return false }
+
+type fieldNode struct{ name string }
+
+func (n *fieldNode) Type() valueType { return typeNumber }
+func (n *fieldNode) Number(ctx *evalContext) float64 {
+	value, _ := ctx.current.GetField(n.name)
+	return value
+}
+func (n *fieldNode) Bool(ctx *evalContext) bool { return false }
+
+type aggregateNode struct {
+	function string
+	field    string
+	window   time.Duration
+}
+
+func (n *aggregateNode) Type() valueType { return typeNumber }
+func (n *aggregateNode) Number(ctx *evalContext) float64 {
+	values := ctx.history.Values(n.field, n.window)
+	if len(values) == 0 {
+		return 0
+	}
+	result := values[0]
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+		if n.function == "min" && value < result {
+			result = value
+		}
+		if n.function == "max" && value > result {
+			result = value
+		}
+	}
+	if n.function == "avg" {
+		return sum / float64(len(values))
+	}
+	return result
+}
+func (n *aggregateNode) Bool(ctx *evalContext) bool { return false }
+
+type binaryNode struct {
+	operator    string
+	left, right node
+}
+
+func (n *binaryNode) Type() valueType {
+	switch n.operator {
+	case "+", "-", "*", "/":
+		return typeNumber
+	}
+	return typeBool
+}
+
+func (n *binaryNode) Number(ctx *evalContext) float64 {
+	left, right := n.left.Number(ctx), n.right.Number(ctx)
+	switch n.operator {
+	case "+":
+		return left + right
+	case "-":
+		return left - right
+	case "*":
+		return left * right
+	case "/":
+		if right == 0 {
+			return 0
+		}
+		return left / right
+	}
+	return 0
+}
+
+func (n *binaryNode) Bool(ctx *evalContext) bool {
+	switch n.operator {
+	case "&&":
+		return n.left.Bool(ctx) && n.right.Bool(ctx)
+	case "||":
+		return n.left.Bool(ctx) || n.right.Bool(ctx)
+	}
+	left, right := n.left.Number(ctx), n.right.Number(ctx)
+	switch n.operator {
+	case "<":
+		return left < right
+	case "<=":
+		return left <= right
+	case ">":
+		return left > right
+	case ">=":
+		return left >= right
+	case "==":
+		return left == right
+	case "!=":
+		return left != right
+	}
+	return false
+}
+
+type unaryNode struct {
+	operator string
+	operand  node
+}
+
+func (n *unaryNode) Type() valueType { return n.operand.Type() }
+func (n *unaryNode) Number(ctx *evalContext) float64 {
+	return -n.operand.Number(ctx)
+}
+func (n *unaryNode) Bool(ctx *evalContext) bool {
+	return !n.operand.Bool(ctx)
+}
+
+// forNode only exists while parsing, Compile lifts it out into Expression.For
+type forNode struct {
+	duration time.Duration
+	pos      int
+}
+
+func (n *forNode) Type() valueType                 { return typeBool }
+func (n *forNode) Number(ctx *evalContext) float64 { return 0 }
+func (n *forNode) Bool(ctx *evalContext) bool      { return true }
+
+type parser struct {
+	tokens []token
+	next   int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) take() token {
+	current := p.tokens[p.next]
+	if current.kind != tokenEOF {
+		p.next++
+	}
+	return current
+}
+
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	current := p.take()
+	if current.kind != kind {
+		return current, &SyntaxError{Pos: current.pos, Msg: fmt.Sprintf("expected %s, found %s", what, describe(current))}
+	}
+	return current, nil
+}
+
+func describe(t token) string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+func (p *parser) isOperator(operators ...string) bool {
+	current := p.peek()
+	if current.kind != tokenOperator {
+		return false
+	}
+	for _, operator := range operators {
+		if current.text == operator {
+			return true
+		}
+	}
+	return false
+}
+
+func typeCheck(operator token, operand node, want valueType) error {
+	if operand.Type() != want {
+		return &SyntaxError{Pos: operator.pos, Msg: fmt.Sprintf("%s needs a %s operand, found a %s", operator.text, want, operand.Type())}
+	}
+	return nil
+}
+
+func (p *parser) parseBinary(operators []string, operand valueType, parseNext func() (node, error)) (node, error) {
+	left, err := parseNext()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator(operators...) {
+		operator := p.take()
+		right, err := parseNext()
+		if err != nil {
+			return nil, err
+		}
+		if err = typeCheck(operator, left, operand); err != nil {
+			return nil, err
+		}
+		if err = typeCheck(operator, right, operand); err != nil {
+			return nil, err
+		}
+		left = &binaryNode{operator: operator.text, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	return p.parseBinary([]string{"||"}, typeBool, p.parseAnd)
+}
+
+func (p *parser) parseAnd() (node, error) {
+	return p.parseBinary([]string{"&&"}, typeBool, p.parseNot)
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.isOperator("!") {
+		operator := p.take()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if err = typeCheck(operator, operand, typeBool); err != nil {
+			return nil, err
+		}
+		return &unaryNode{operator: "!", operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if p.isOperator("<", "<=", ">", ">=", "==", "!=") {
+		operator := p.take()
+		right, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if err = typeCheck(operator, left, typeNumber); err != nil {
+			return nil, err
+		}
+		if err = typeCheck(operator, right, typeNumber); err != nil {
+			return nil, err
+		}
+		return &binaryNode{operator: operator.text, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseSum() (node, error) {
+	return p.parseBinary([]string{"+", "-"}, typeNumber, p.parseProduct)
+}
+
+func (p *parser) parseProduct() (node, error) {
+	return p.parseBinary([]string{"*", "/"}, typeNumber, p.parseUnary)
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOperator("-") {
+		operator := p.take()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if err = typeCheck(operator, operand, typeNumber); err != nil {
+			return nil, err
+		}
+		return &unaryNode{operator: "-", operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	current := p.take()
+	switch current.kind {
+	case tokenNumber:
+		return &numberNode{value: current.number}, nil
+	case tokenDuration:
+		return nil, &SyntaxError{Pos: current.pos, Msg: fmt.Sprintf("duration %s is only allowed as a function argument", current.text)}
+	case tokenLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err = p.expect(tokenRParen, "')'"); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	case tokenIdent:
+		if p.peek().kind == tokenLParen {
+			return p.parseCall(current)
+		}
+		if !isField(current.text) {
+			return nil, &SyntaxError{Pos: current.pos, Msg: fmt.Sprintf("unknown field %s, expected one of %s", current.text, strings.Join(Fields, ", "))}
+		}
+		return &fieldNode{name: current.text}, nil
+	}
+	return nil, &SyntaxError{Pos: current.pos, Msg: fmt.Sprintf("unexpected %s", describe(current))}
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	p.take() // (
+	switch name.text {
+	case "for":
+		duration, err := p.expect(tokenDuration, "a duration")
+		if err != nil {
+			return nil, err
+		}
+		if _, err = p.expect(tokenRParen, "')'"); err != nil {
+			return nil, err
+		}
+		return &forNode{duration: duration.duration, pos: name.pos}, nil
+	case "avg", "min", "max":
+		field, err := p.expect(tokenIdent, "a field")
+		if err != nil {
+			return nil, err
+		}
+		if !isField(field.text) {
+			return nil, &SyntaxError{Pos: field.pos, Msg: fmt.Sprintf("unknown field %s, expected one of %s", field.text, strings.Join(Fields, ", "))}
+		}
+		if _, err = p.expect(tokenComma, "','"); err != nil {
+			return nil, err
+		}
+		window, err := p.expect(tokenDuration, "a duration")
+		if err != nil {
+			return nil, err
+		}
+		if window.duration <= 0 {
+			return nil, &SyntaxError{Pos: window.pos, Msg: "window must be positive"}
+		}
+		if _, err = p.expect(tokenRParen, "')'"); err != nil {
+			return nil, err
+		}
+		return &aggregateNode{function: name.text, field: field.text, window: window.duration}, nil
+	}
+	return nil, &SyntaxError{Pos: name.pos, Msg: fmt.Sprintf("unknown function %s, expected avg, min, max or for", name.text)}
+}
+
+func isField(name string) bool {
+	for _, field := range Fields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Expression is a compiled rule condition
+type Expression struct {
+	Source string
+	For    time.Duration // from a top level for(), 0 when absent
+	Window time.Duration // longest aggregate window, how much history the rule needs
+	Fields []string      // fields read from the current reading
+	root   node
+}
+
+// Compile Parses and type checks an expression
+func Compile(source string) (*Expression, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if current := p.peek(); current.kind != tokenEOF {
+		return nil, &SyntaxError{Pos: current.pos, Msg: fmt.Sprintf("unexpected %s", describe(current))}
+	}
+	if root.Type() != typeBool {
+		return nil, &SyntaxError{Pos: 1, Msg: "expression must be a condition, not a number"}
+	}
+
+	expression := &Expression{Source: source}
+	isTimed := false
+	if root, err = expression.liftFor(root, &isTimed); err != nil {
+		return nil, err
+	}
+	if root == nil {
+		return nil, &SyntaxError{Pos: 1, Msg: "for() needs a condition to time"}
+	}
+	if err = expression.inspect(root); err != nil {
+		return nil, err
+	}
+	expression.root = root
+	return expression, nil
+}
+
+// liftFor Removes the top level for() operands of a && chain and records their duration, isTimed is set by the first
+func (expression *Expression) liftFor(root node, isTimed *bool) (node, error) {
+	switch n := root.(type) {
+	case *forNode:
+		if *isTimed {
+			return nil, &SyntaxError{Pos: n.pos, Msg: "only one for() is allowed"}
+		}
+		if n.duration <= 0 {
+			return nil, &SyntaxError{Pos: n.pos, Msg: "for() needs a duration above zero"}
+		}
+		*isTimed = true
+		expression.For = n.duration
+		return nil, nil
+	case *binaryNode:
+		if n.operator != "&&" {
+			return root, nil
+		}
+		left, err := expression.liftFor(n.left, isTimed)
+		if err != nil {
+			return nil, err
+		}
+		right, err := expression.liftFor(n.right, isTimed)
+		if err != nil {
+			return nil, err
+		}
+		if left == nil {
+			return right, nil
+		}
+		if right == nil {
+			return left, nil
+		}
+		return &binaryNode{operator: "&&", left: left, right: right}, nil
+	}
+	return root, nil
+}
+
+// inspect Collects fields and windows, and rejects for() anywhere liftFor couldn't reach
+func (expression *Expression) inspect(root node) error {
+	switch n := root.(type) {
+	case *forNode:
+		return &SyntaxError{Pos: n.pos, Msg: "for() must be combined with the rest of the rule using a top level &&"}
+	case *fieldNode:
+		for _, field := range expression.Fields {
+			if field == n.name {
+				return nil
+			}
+		}
+		expression.Fields = append(expression.Fields, n.name)
+	case *aggregateNode:
+		if n.window > expression.Window {
+			expression.Window = n.window
+		}
+	case *binaryNode:
+		if err := expression.inspect(n.left); err != nil {
+			return err
+		}
+		return expression.inspect(n.right)
+	case *unaryNode:
+		return expression.inspect(n.operand)
+	}
+	return nil
+}
+
+// Holds Evaluates the condition, without the for() part, for a reading
+func (expression *Expression) Holds(current *userinfo.WorkerInfo, history History) bool {
+	return expression.root.Bool(&evalContext{current: current, history: history})
+}
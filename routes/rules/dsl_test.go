@@ -0,0 +1,101 @@
+package rules
+
+import (
+	"go_backend/routes/userinfo"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type fixedHistory map[string][]float64
+
+func (history fixedHistory) Values(field string, window time.Duration) []float64 {
+	return history[field]
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		source string
+		For    time.Duration
+		Window time.Duration
+		Fields []string
+	}{
+		{"GasLevel > 50", 0, 0, []string{"GasLevel"}},
+		{"GasLevel > 50 && for(2m)", 2 * time.Minute, 0, []string{"GasLevel"}},
+		{"for(1h30m) && Spo2Level < 90", 90 * time.Minute, 0, []string{"Spo2Level"}},
+		{"avg(HeartRate, 5m) > 140 && for(30s)", 30 * time.Second, 5 * time.Minute, nil},
+		{"Spo2Level < 90 || (HeartRate > 150 && Temperature > 38)", 0, 0, []string{"Spo2Level", "HeartRate", "Temperature"}},
+		{"max(GasLevel, 1m) - min(GasLevel, 10m) > 20 && GasLevel > 0", 0, 10 * time.Minute, []string{"GasLevel"}},
+		{"!(GasLevel > 50) && HeartRate >= 0.5", 0, 0, []string{"GasLevel", "HeartRate"}},
+	}
+	for _, test := range tests {
+		expression, err := Compile(test.source)
+		if err != nil {
+			t.Errorf("Compile(%q) error = %v", test.source, err)
+			continue
+		}
+		if expression.For != test.For || expression.Window != test.Window || !reflect.DeepEqual(expression.Fields, test.Fields) {
+			t.Errorf("Compile(%q) = For %v, Window %v, Fields %v, want %v, %v, %v", test.source,
+				expression.For, expression.Window, expression.Fields, test.For, test.Window, test.Fields)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"GasLevel >", `col 11: unexpected end of expression`},
+		{"Foo > 1", `col 1: unknown field Foo, expected one of Spo2Level, Temperature, GasLevel, HeartRate`},
+		{"GasLevel # 1", `col 10: unexpected "#"`},
+		{"GasLevel + 1", `col 1: expression must be a condition, not a number`},
+		{"GasLevel > 5m", `col 12: duration 5m is only allowed as a function argument`},
+		{"GasLevel > 1 && 2", `col 14: && needs a boolean operand, found a number`},
+		{"HeartRate > 140 || for(30s)", `col 20: for() must be combined with the rest of the rule using a top level &&`},
+		{"GasLevel > 1 && for(1s) && for(2s)", `col 28: only one for() is allowed`},
+		{"GasLevel > 1 && for(0s) && for(2s)", `col 17: for() needs a duration above zero`},
+		{"GasLevel > 1 && for(2s) && for(0s)", `col 28: only one for() is allowed`},
+		{"GasLevel > 1 && for(-1s)", `col 21: expected a duration, found "-"`},
+		{"for(30s)", `col 1: for() needs a condition to time`},
+		{"avg(HeartRate, 0s) > 1", `col 16: window must be positive`},
+		{"avg(HeartRate 5m) > 1", `col 15: expected ',', found "5m"`},
+		{"sum(GasLevel, 1m) > 1", `col 1: unknown function sum, expected avg, min, max or for`},
+		{"GasLevel > 1m5x", `col 12: invalid duration "1m5x"`},
+		{"(GasLevel > 1", `col 14: expected ')', found end of expression`},
+	}
+	for _, test := range tests {
+		_, err := Compile(test.source)
+		if err == nil || err.Error() != test.want {
+			t.Errorf("Compile(%q) error = %v, want %v", test.source, err, test.want)
+		}
+	}
+}
+
+func TestHolds(t *testing.T) {
+	current := &userinfo.WorkerInfo{Spo2Level: 95, HeartRate: 160, Temperature: 39, GasLevel: 40}
+	history := fixedHistory{"HeartRate": {120, 150, 180}}
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{"Spo2Level < 90 || (HeartRate > 150 && Temperature > 38)", true},
+		{"Spo2Level < 90 || HeartRate > 150 && Temperature > 40", false},
+		{"!(GasLevel > 50)", true},
+		{"GasLevel * 2 - 10 > 50", true},
+		{"-GasLevel < -50", false},
+		{"GasLevel / 0 == 0", true},
+		{"avg(HeartRate, 5m) == 150", true},
+		{"min(HeartRate, 5m) == 120 && max(HeartRate, 5m) == 180", true},
+		{"avg(Temperature, 5m) == 0", true}, // no history
+	}
+	for _, test := range tests {
+		expression, err := Compile(test.source)
+		if err != nil {
+			t.Fatalf("Compile(%q) error = %v", test.source, err)
+		}
+		if got := expression.Holds(current, history); got != test.want {
+			t.Errorf("%q holds = %v, want %v", test.source, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package rules
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// RuleDefinition is one entry of the rules file
+//
+//	rules:
+//	  - name: gas-exposure
+//	    type: GasExposure
+//	    when: GasLevel > 50 && for(2m)
+type RuleDefinition struct {
+	Name string `mapstructure:"name"`
+	Type string `mapstructure:"type"`
+	When string `mapstructure:"when"`
+}
+
+type ruleFile struct {
+	Rules []RuleDefinition `mapstructure:"rules"`
+}
+
+var reloadMu sync.Mutex
+var lastReloadError string
+
+// LoadRulesFile Reads and compiles every rule, all problems are reported together
+func LoadRulesFile(path string) ([]Rule, error) {
+	rulesViper := viper.New()
+	rulesViper.SetConfigFile(path)
+	if err := rulesViper.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("couldn't read rules file %v: %v", path, err)
+	}
+	return compileRulesFile(rulesViper, path)
+}
+
+func compileRulesFile(rulesViper *viper.Viper, path string) ([]Rule, error) {
+	file := ruleFile{}
+	if err := rulesViper.Unmarshal(&file); err != nil {
+		return nil, fmt.Errorf("couldn't parse rules file %v: %v", path, err)
+	}
+
+	var rules []Rule
+	var problems []string
+	names := make(map[string]bool)
+	for i, definition := range file.Rules {
+		rule, err := NewRule(definition.Name, definition.Type, definition.When)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("rules[%d]: %v", i, err))
+			continue
+		}
+		if names[rule.Name] {
+			problems = append(problems, fmt.Sprintf("rules[%d]: duplicate rule name %v", i, rule.Name))
+			continue
+		}
+		names[rule.Name] = true
+		rules = append(rules, rule)
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid rules file %v:\n  %v", path, strings.Join(problems, "\n  "))
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("rules file %v has no rules", path)
+	}
+	return rules, nil
+}
+
+// WatchRulesFile Reloads the rules whenever the file changes, an invalid or empty file keeps the previous rules
+func WatchRulesFile(path string) {
+	rulesViper := viper.New()
+	rulesViper.SetConfigFile(path)
+	if err := rulesViper.ReadInConfig(); err != nil {
+		log.Printf("Couldn't watch rules file. Reason => %v\n", err)
+		return
+	}
+
+	rulesViper.OnConfigChange(func(event fsnotify.Event) {
+		reloadRulesFile(path)
+	})
+	rulesViper.WatchConfig()
+}
+
+// reloadRulesFile Reads path again and swaps its rules in, the previous rules stay when it can't be used
+func reloadRulesFile(path string) error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	rules, err := LoadRulesFile(path)
+	if err != nil {
+		lastReloadError = err.Error()
+		log.Printf("Rules not reloaded, keeping the previous rules. Reason => %v\n", err)
+		return err
+	}
+	lastReloadError = ""
+	GetEngine().SetRules(rules)
+	log.Printf("Reloaded %v rules from %v\n", len(rules), path)
+	return nil
+}
+
+func GetLastReloadError() string {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	return lastReloadError
+}
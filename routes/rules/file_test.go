@@ -0,0 +1,105 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const validRules = `rules:
+  - name: gas
+    type: GasExposure
+    when: GasLevel > 50
+  - name: hypoxia
+    type: Hypoxia
+    when: Spo2Level < 90 && for(30s)
+`
+
+func writeRules(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func ruleNames(rules []Rule) string {
+	var names []string
+	for _, rule := range rules {
+		names = append(names, rule.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestLoadRulesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	writeRules(t, path, validRules)
+	rules, err := LoadRulesFile(path)
+	if err != nil || ruleNames(rules) != "gas,hypoxia" || rules[1].expression.For != 30*time.Second {
+		t.Fatalf("LoadRulesFile = %v, %v", rules, err)
+	}
+
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"rules: []\n", "has no rules"},
+		{"# nothing yet\n", "has no rules"},
+		{validRules + "  - name: gas\n    type: GasExposure\n    when: GasLevel > 60\n", "rules[2]: duplicate rule name gas"},
+		{"rules:\n  - name: bad\n    type: Hypoxia\n    when: Spo2Level <\n  - type: Hypoxia\n    when: Spo2Level < 90\n",
+			"rules[0]: rule bad: col 12: unexpected end of expression in \"Spo2Level <\"\n  rules[1]: rule without a name"},
+	}
+	for _, test := range tests {
+		writeRules(t, path, test.content)
+		if _, err := LoadRulesFile(path); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("LoadRulesFile(%q) error = %v, want it to contain %q", test.content, err, test.want)
+		}
+	}
+}
+
+func TestReloadKeepsPreviousRules(t *testing.T) {
+	defer GetEngine().SetRules(DefaultRules())
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	writeRules(t, path, validRules)
+	if err := reloadRulesFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, content := range []string{"rules: []\n", "rules:\n  - name: x\n    type: Hypoxia\n    when: Nope > 1\n", "rules: ["} {
+		writeRules(t, path, content)
+		if err := reloadRulesFile(path); err == nil {
+			t.Errorf("reload of %q accepted", content)
+		}
+		if got := ruleNames(GetEngine().Rules()); got != "gas,hypoxia" {
+			t.Errorf("rules after rejected reload of %q = %v, want the previous gas,hypoxia", content, got)
+		}
+		if GetLastReloadError() == "" {
+			t.Errorf("LastError not set after rejected reload of %q", content)
+		}
+	}
+
+	writeRules(t, path, strings.Replace(validRules, "GasLevel > 50", "GasLevel > 70", 1))
+	if err := reloadRulesFile(path); err != nil || GetLastReloadError() != "" {
+		t.Fatalf("reload = %v, LastError %q", err, GetLastReloadError())
+	}
+	if when := GetEngine().Rules()[0].When; when != "GasLevel > 70" {
+		t.Errorf("reloaded rule gas = %q, want the new expression", when)
+	}
+}
+
+func TestWatchRulesFile(t *testing.T) {
+	defer GetEngine().SetRules(DefaultRules())
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	writeRules(t, path, validRules)
+	WatchRulesFile(path)
+
+	writeRules(t, path, "rules:\n  - name: heat\n    type: HeatStress\n    when: Temperature > 39\n")
+	deadline := time.Now().Add(5 * time.Second)
+	for ruleNames(GetEngine().Rules()) != "heat" {
+		if time.Now().After(deadline) {
+			t.Fatalf("rules after the file changed = %v, want heat", ruleNames(GetEngine().Rules()))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
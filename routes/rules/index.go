@@ -0,0 +1,271 @@
+/*
+Rules Package turns vital signs into danger alerts
+Every ingested reading is checked against the rules, a rule fires once its condition
+has held for its for() duration and re-arms when the condition clears
+Rules come from RULES_FILE (reloaded on change) or from the RULE_* values in config.env
+*/
+
+package rules
+
+import (
+	"fmt"
+	"go_backend/routes/danger"
+	"go_backend/routes/userinfo"
+	"go_backend/util"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const FILENAME = "rules/index.go"
+
+// Rule raises DangerType for a worker whenever its When expression holds
+type Rule struct {
+	Name       string
+	DangerType string // Type of the alert raised
+	When       string // Expression, see dsl.go
+	expression *Expression
+}
+
+// NewRule Compiles the When expression, errors name the rule and the column
+func NewRule(name, dangerType, when string) (Rule, error) {
+	if name == "" {
+		return Rule{}, fmt.Errorf("rule without a name")
+	}
+	if dangerType == "" {
+		return Rule{}, fmt.Errorf("rule %v: type not provided", name)
+	}
+	expression, err := Compile(when)
+	if err != nil {
+		return Rule{}, fmt.Errorf("rule %v: %v in %q", name, err, when)
+	}
+	return Rule{Name: name, DangerType: dangerType, When: when, expression: expression}, nil
+}
+
+// Event is a rule firing for a worker
+type Event struct {
+	Rule       Rule
+	WorkerInfo userinfo.WorkerInfo
+	At         time.Time
+}
+
+type ruleState struct {
+	holding bool
+	since   time.Time
+	fired   bool
+}
+
+type sample struct {
+	at         time.Time
+	workerInfo userinfo.WorkerInfo
+}
+
+// workerHistory holds a worker's readings for aggregate functions, newest last
+type workerHistory struct {
+	samples []sample
+	now     time.Time
+}
+
+// Values Returns the non zero values of a field reported within window of the current reading
+func (history *workerHistory) Values(field string, window time.Duration) []float64 {
+	var values []float64
+	for _, sample := range history.samples {
+		if history.now.Sub(sample.at) > window {
+			continue
+		}
+		if value, _ := sample.workerInfo.GetField(field); value != 0 {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Engine evaluates a rule set and remembers, per worker, how long each condition has held
+type Engine struct {
+	mu      sync.Mutex
+	rules   []Rule
+	window  time.Duration                    // history kept per worker
+	state   map[string]map[string]*ruleState // worker Id -> rule Name -> state
+	history map[string]*workerHistory        // worker Id -> recent readings
+}
+
+func NewEngine(rules []Rule) *Engine {
+	engine := &Engine{history: make(map[string]*workerHistory)}
+	engine.SetRules(rules)
+	return engine
+}
+
+// SetRules Swaps the rule set, how long conditions have held is forgotten but reading history is kept
+func (engine *Engine) SetRules(rules []Rule) {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+
+	engine.rules = rules
+	engine.window = 0
+	for _, rule := range rules {
+		if rule.expression.Window > engine.window {
+			engine.window = rule.expression.Window
+		}
+	}
+	engine.state = make(map[string]map[string]*ruleState)
+}
+
+func (engine *Engine) Rules() []Rule {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	return append([]Rule{}, engine.rules...)
+}
+
+// Evaluate Returns the rules that fire with this reading
+// A value of 0 means the helmet didn't report that sensor, rules reading it are left untouched
+func (engine *Engine) Evaluate(workerInfo *userinfo.WorkerInfo, at time.Time) []Event {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+
+	workerState, isFound := engine.state[workerInfo.Id]
+	if !isFound {
+		workerState = make(map[string]*ruleState)
+		engine.state[workerInfo.Id] = workerState
+	}
+	history := engine.record(workerInfo, at)
+
+	var events []Event
+	for _, rule := range engine.rules {
+		if !reported(workerInfo, rule.expression.Fields) {
+			continue
+		}
+		state, isFound := workerState[rule.Name]
+		if !isFound {
+			state = &ruleState{}
+			workerState[rule.Name] = state
+		}
+
+		if !rule.expression.Holds(workerInfo, history) {
+			*state = ruleState{}
+			continue
+		}
+		if !state.holding {
+			state.holding = true
+			state.since = at
+		}
+		if !state.fired && at.Sub(state.since) >= rule.expression.For {
+			state.fired = true
+			events = append(events, Event{Rule: rule, WorkerInfo: *workerInfo, At: at})
+		}
+	}
+	return events
+}
+
+// record Adds the reading to the worker's history and drops readings no rule looks at anymore
+func (engine *Engine) record(workerInfo *userinfo.WorkerInfo, at time.Time) *workerHistory {
+	history, isFound := engine.history[workerInfo.Id]
+	if !isFound {
+		history = &workerHistory{}
+		engine.history[workerInfo.Id] = history
+	}
+	history.now = at
+	history.samples = append(history.samples, sample{at: at, workerInfo: *workerInfo})
+
+	keep := 0
+	for keep < len(history.samples) && at.Sub(history.samples[keep].at) > engine.window {
+		keep++
+	}
+	history.samples = history.samples[keep:]
+	return history
+}
+
+func reported(workerInfo *userinfo.WorkerInfo, fields []string) bool {
+	for _, field := range fields {
+		if value, _ := workerInfo.GetField(field); value == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+var engine *Engine
+var engineOnce sync.Once
+
+// GetEngine Returns the engine evaluating ingested readings
+// Its rules are loaded on first use, RULES_FILE is then watched for changes
+func GetEngine() *Engine {
+	engineOnce.Do(func() {
+		engine = NewEngine(nil)
+		if util.GetRulesFile() == "" {
+			engine.SetRules(DefaultRules())
+			return
+		}
+		rules, err := LoadRulesFile(util.GetFilePath(util.GetRulesFile()))
+		if err != nil {
+			log.Fatalln(err)
+		}
+		engine.SetRules(rules)
+		WatchRulesFile(util.GetFilePath(util.GetRulesFile()))
+	})
+	return engine
+}
+
+// DefaultRules Builds the rule set from the RULE_* values in config.env, used when RULES_FILE is not set
+func DefaultRules() []Rule {
+	heartRateFor := util.GetConfigDuration("RULE_HEART_RATE_FOR", 30*time.Second)
+	definitions := [][3]string{
+		{"hypoxia", danger.DangerHypoxia, fmt.Sprintf("Spo2Level < %v%v",
+			util.GetConfigFloat("RULE_HYPOXIA_SPO2", 90), held(util.GetConfigDuration("RULE_HYPOXIA_FOR", 30*time.Second)))},
+		{"gas-exposure", danger.DangerGasExposure, fmt.Sprintf("GasLevel > %v%v",
+			util.GetConfigFloat("RULE_GAS_LIMIT", 50), held(util.GetConfigDuration("RULE_GAS_FOR", 0)))},
+		{"heat-stress", danger.DangerHeatStress, fmt.Sprintf("Temperature > %v%v",
+			util.GetConfigFloat("RULE_HEAT_TEMPERATURE", 39), held(util.GetConfigDuration("RULE_HEAT_FOR", 60*time.Second)))},
+		{"tachycardia", danger.DangerTachycardia, fmt.Sprintf("HeartRate > %v%v",
+			util.GetConfigFloat("RULE_HEART_RATE_MAX", 140), held(heartRateFor))},
+		{"bradycardia", danger.DangerBradycardia, fmt.Sprintf("HeartRate < %v%v",
+			util.GetConfigFloat("RULE_HEART_RATE_MIN", 40), held(heartRateFor))},
+	}
+
+	var rules []Rule
+	for _, definition := range definitions {
+		rule, err := NewRule(definition[0], definition[1], definition[2])
+		if err != nil {
+			log.Fatalln(err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// held Is the for() operand of a default rule, a RULE_*_FOR of 0 fires on the first reading
+func held(duration time.Duration) string {
+	if duration <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" && for(%v)", duration)
+}
+
+// OnReading Is registered as a userinfo ingest hook, fired rules become danger alerts
+func OnReading(workerInfo *userinfo.WorkerInfo, at time.Time) {
+	for _, event := range GetEngine().Evaluate(workerInfo, at) {
+		alertInfo := event.WorkerInfo
+		alertInfo.DangerType = event.Rule.DangerType
+		if _, err := danger.Raise(&alertInfo); err != nil {
+			log.Printf("Couldn't raise %v for %v. Reason => %v\n", event.Rule.DangerType, alertInfo.Id, err)
+		}
+	}
+}
+
+type RulesStatus struct {
+	Source    string // RULES_FILE or config.env
+	LastError string // Set when the latest reload was rejected, the previous rules stay active
+	Rules     []Rule
+}
+
+// Get Lists the active rules
+func Get(ctx *gin.Context) {
+	util.DebugPrint(FILENAME, "GET", "Just Test")
+	status := RulesStatus{Source: "config.env", LastError: GetLastReloadError(), Rules: GetEngine().Rules()}
+	if util.GetRulesFile() != "" {
+		status.Source = util.GetRulesFile()
+	}
+	ctx.JSON(http.StatusOK, status)
+}
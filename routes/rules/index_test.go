@@ -0,0 +1,101 @@
+package rules
+
+import (
+	"go_backend/routes/danger"
+	"go_backend/routes/userinfo"
+	"testing"
+	"time"
+)
+
+func mustRule(t *testing.T, name, dangerType, when string) Rule {
+	t.Helper()
+	rule, err := NewRule(name, dangerType, when)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rule
+}
+
+func TestEngineFiresAfterForAndRearms(t *testing.T) {
+	engine := NewEngine([]Rule{mustRule(t, "hypoxia", danger.DangerHypoxia, "Spo2Level < 90 && for(30s)")})
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	low := &userinfo.WorkerInfo{Id: "1_2", Spo2Level: 85}
+	normal := &userinfo.WorkerInfo{Id: "1_2", Spo2Level: 97}
+
+	steps := []struct {
+		reading *userinfo.WorkerInfo
+		after   time.Duration
+		fires   bool
+	}{
+		{low, 0, false},
+		{low, 20 * time.Second, false},
+		{low, 30 * time.Second, true},
+		{low, 40 * time.Second, false}, // fires once per episode
+		{normal, 50 * time.Second, false},
+		{low, 60 * time.Second, false}, // re-armed, the 30s start over
+		{low, 90 * time.Second, true},
+	}
+	for i, step := range steps {
+		events := engine.Evaluate(step.reading, start.Add(step.after))
+		if fired := len(events) == 1; fired != step.fires {
+			t.Fatalf("step %v: events = %v, want fired %v", i, events, step.fires)
+		}
+		if step.fires && events[0].Rule.DangerType != danger.DangerHypoxia {
+			t.Fatalf("step %v: fired %v", i, events[0].Rule.DangerType)
+		}
+	}
+}
+
+func TestEngineSkipsUnreportedSensors(t *testing.T) {
+	engine := NewEngine([]Rule{mustRule(t, "bradycardia", danger.DangerBradycardia, "HeartRate < 40")})
+	at := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	if events := engine.Evaluate(&userinfo.WorkerInfo{Id: "1_2", HeartRate: 0}, at); len(events) != 0 {
+		t.Errorf("HeartRate 0 (not reported) fired %v", events)
+	}
+	if events := engine.Evaluate(&userinfo.WorkerInfo{Id: "1_2", HeartRate: 35}, at); len(events) != 1 {
+		t.Errorf("HeartRate 35 fired %v, want bradycardia", events)
+	}
+}
+
+func TestEngineKeepsWorkersApart(t *testing.T) {
+	engine := NewEngine([]Rule{mustRule(t, "gas", danger.DangerGasExposure, "GasLevel > 50 && for(10s)")})
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	engine.Evaluate(&userinfo.WorkerInfo{Id: "1_2", GasLevel: 60}, start)
+	if events := engine.Evaluate(&userinfo.WorkerInfo{Id: "1_3", GasLevel: 60}, start.Add(10*time.Second)); len(events) != 0 {
+		t.Errorf("1_3 fired on the time 1_2 spent above the limit: %v", events)
+	}
+	if events := engine.Evaluate(&userinfo.WorkerInfo{Id: "1_2", GasLevel: 60}, start.Add(10*time.Second)); len(events) != 1 {
+		t.Errorf("1_2 didn't fire after 10s: %v", events)
+	}
+}
+
+func TestEngineAggregatesHistory(t *testing.T) {
+	engine := NewEngine([]Rule{mustRule(t, "tachycardia", danger.DangerTachycardia, "avg(HeartRate, 1m) > 140")})
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	heartRates := []int32{120, 150, 170}
+	var events []Event
+	for i, heartRate := range heartRates {
+		events = engine.Evaluate(&userinfo.WorkerInfo{Id: "1_2", HeartRate: heartRate}, start.Add(time.Duration(i)*20*time.Second))
+	}
+	if len(events) != 1 {
+		t.Fatalf("avg of 120, 150, 170 didn't fire: %v", events)
+	}
+
+	// Two minutes later only the last reading is in the window
+	if events = engine.Evaluate(&userinfo.WorkerInfo{Id: "1_2", HeartRate: 100}, start.Add(3*time.Minute)); len(events) != 0 {
+		t.Errorf("readings outside the window counted: %v", events)
+	}
+}
+
+func TestDefaultRulesCompile(t *testing.T) {
+	rules := DefaultRules()
+	if len(rules) != 5 {
+		t.Fatalf("DefaultRules returned %v rules, want 5", len(rules))
+	}
+	engine := NewEngine(rules)
+	at := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	events := engine.Evaluate(&userinfo.WorkerInfo{Id: "1_2", GasLevel: 80, Spo2Level: 97, HeartRate: 80, Temperature: 37}, at)
+	if len(events) != 1 || events[0].Rule.DangerType != danger.DangerGasExposure {
+		t.Errorf("GasLevel 80 fired %v, want gas exposure only", events)
+	}
+}
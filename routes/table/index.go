@@ -1,6 +1,6 @@
 /*
 Table Package Helps to do operations in table
-Presently supported operations are create,delete
+Presently supported operations are create,delete and index (adds a global secondary index to a table)
 */
 
 package table
@@ -10,6 +10,8 @@ import (
 	"fmt"
 	"go_backend/util"
 	"log"
+	"net/http"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -20,9 +22,14 @@ import (
 )
 
 var client *dynamodb.Client
+var clientOnce sync.Once
 
-func init() {
-	client = GetClientFromEnv()
+// getClient Loads the AWS config on the first table operation, not when the package is imported
+func getClient() *dynamodb.Client {
+	clientOnce.Do(func() {
+		client = GetClientFromEnv()
+	})
+	return client
 }
 
 func GetClientFromEnv() *dynamodb.Client {
@@ -30,23 +37,20 @@ func GetClientFromEnv() *dynamodb.Client {
 		return nil
 	})
 
-	CheckError(err)
+	if err != nil {
+		log.Fatalf("Couldn't load the AWS config. Reason => %v\n", err)
+	}
 
 	client := dynamodb.NewFromConfig(cfg)
 	return client
 }
 
-func CheckError(err error) {
-	if err != nil {
-		log.Fatalln(err.Error())
-	}
-}
-
 type TableOperation struct {
-	Name        string // Table Name
-	Operation   string
-	PrimKeyName string
-	SecKeyName  string
+	Name         string // Table Name
+	Operation    string
+	PrimKeyName  string
+	SecKeyName   string
+	IndexKeyName string // Optional, adds the global secondary index <IndexKeyName>Index hashed on it
 }
 
 type TableOperationList struct {
@@ -71,21 +75,30 @@ func Get(ctx *gin.Context) {
 /*
   sent : {
    	Name        string  // Table Name
-	Operation   string  // CREATE or DELETE
-	PrimKeyName string  // Set only if Operation=CREATE
+	Operation   string  // CREATE, INDEX or DELETE
+	PrimKeyName string  // Set only if Operation=CREATE or INDEX
 	SecKeyName  string  // same as above
+	IndexKeyName string // Optional for CREATE, required for INDEX
 }
 
+WorkerInfo needs the Date index : {"Name":"WorkerInfo","Operation":"CREATE","PrimKeyName":"Id","SecKeyName":"Date","IndexKeyName":"Date"}
+
 */
 func Post(ctx *gin.Context) {
+	if util.GetStorageBackend() != util.StorageDynamoDB {
+		util.WriteError(ctx, http.StatusNotImplemented, "Table operations need the dynamodb storage backend")
+		return
+	}
+
 	tableOps := &TableOperation{}
-	err := ctx.BindJSON(tableOps)
-	CheckError(err)
+	if err := ctx.ShouldBindJSON(tableOps); err != nil {
+		util.WriteError(ctx, http.StatusBadRequest, "Body must be a JSON TableOperation")
+		return
+	}
 	util.DebugPrint(FILENAME, "POST", fmt.Sprintf("{%v}", tableOps))
 
 	if tableOps.Operation == "CREATE" {
-		fmt.Println("Create Called")
-		_, err := client.CreateTable(context.Background(), &dynamodb.CreateTableInput{
+		createInput := &dynamodb.CreateTableInput{
 			AttributeDefinitions: []types.AttributeDefinition{{
 				AttributeName: aws.String(tableOps.PrimKeyName),
 				AttributeType: types.ScalarAttributeTypeS,
@@ -105,23 +118,107 @@ func Post(ctx *gin.Context) {
 				ReadCapacityUnits:  aws.Int64(3),
 				WriteCapacityUnits: aws.Int64(3),
 			},
-		})
+		}
+		if tableOps.IndexKeyName != "" {
+			createInput.AttributeDefinitions = tableOps.indexAttributes(createInput.AttributeDefinitions)
+			createInput.GlobalSecondaryIndexes = []types.GlobalSecondaryIndex{tableOps.index()}
+		}
+		_, err := getClient().CreateTable(context.Background(), createInput)
 
 		if err != nil {
 			log.Printf("Couldn't create table %v. Here's why: %v\n", tableOps.Name, err)
-		} else {
-			waiter := dynamodb.NewTableExistsWaiter(client)
-			err = waiter.Wait(context.Background(), &dynamodb.DescribeTableInput{
-				TableName: aws.String(tableOps.Name)}, 1*time.Minute)
-			if err != nil {
-				log.Printf("Wait for table exists failed. Here's why: %v\n", err)
-			}
-			util.DebugPrint(FILENAME, "POST", "Created Table")
+			util.WriteStoreError(ctx, util.ClassifyStoreError(err))
+			return
+		}
+		waiter := dynamodb.NewTableExistsWaiter(getClient())
+		err = waiter.Wait(context.Background(), &dynamodb.DescribeTableInput{
+			TableName: aws.String(tableOps.Name)}, 1*time.Minute)
+		if err != nil {
+			log.Printf("Wait for table exists failed. Here's why: %v\n", err)
+			util.WriteStoreError(ctx, util.ClassifyStoreError(err))
+			return
+		}
+		util.DebugPrint(FILENAME, "POST", "Created Table")
+	} else if tableOps.Operation == "INDEX" {
+		if tableOps.IndexKeyName == "" {
+			util.WriteError(ctx, http.StatusBadRequest, "IndexKeyName not provided")
+			return
 		}
+		index := tableOps.index()
+		_, err := getClient().UpdateTable(context.Background(), &dynamodb.UpdateTableInput{
+			TableName:            aws.String(tableOps.Name),
+			AttributeDefinitions: tableOps.indexAttributes(nil),
+			GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{{
+				Create: &types.CreateGlobalSecondaryIndexAction{
+					IndexName:             index.IndexName,
+					KeySchema:             index.KeySchema,
+					Projection:            index.Projection,
+					ProvisionedThroughput: index.ProvisionedThroughput,
+				},
+			}},
+		})
+		if err != nil {
+			log.Printf("Couldn't add index to table %v. Here's why: %v\n", tableOps.Name, err)
+			util.WriteStoreError(ctx, util.ClassifyStoreError(err))
+			return
+		}
+		// The index backfills in the background, userinfo queries get a ValidationException meanwhile and fall back to Scan
+		util.DebugPrint(FILENAME, "POST", "Creating Index "+*index.IndexName)
 	} else if tableOps.Operation == "DELETE" {
-		_, err := client.DeleteTable(context.Background(), &dynamodb.DeleteTableInput{
+		_, err := getClient().DeleteTable(context.Background(), &dynamodb.DeleteTableInput{
 			TableName: aws.String(tableOps.Name)})
-		CheckError(err)
+		if err != nil {
+			log.Printf("Couldn't delete table %v. Here's why: %v\n", tableOps.Name, err)
+			util.WriteStoreError(ctx, util.ClassifyStoreError(err))
+			return
+		}
 		util.DebugPrint(FILENAME, "POST", "Deleted Table")
+	} else {
+		util.WriteError(ctx, http.StatusBadRequest, "Operation must be CREATE, INDEX or DELETE")
+	}
+}
+
+// index Builds the global secondary index hashed on IndexKeyName, ranged on the table key not already used
+// Every attribute is projected so queries on the index return whole items
+func (tableOps *TableOperation) index() types.GlobalSecondaryIndex {
+	keySchema := []types.KeySchemaElement{{
+		AttributeName: aws.String(tableOps.IndexKeyName),
+		KeyType:       types.KeyTypeHash,
+	}}
+	rangeKey := tableOps.PrimKeyName
+	if rangeKey == tableOps.IndexKeyName {
+		rangeKey = tableOps.SecKeyName
+	}
+	if rangeKey != "" {
+		keySchema = append(keySchema, types.KeySchemaElement{
+			AttributeName: aws.String(rangeKey),
+			KeyType:       types.KeyTypeRange,
+		})
+	}
+	return types.GlobalSecondaryIndex{
+		IndexName:  aws.String(tableOps.IndexKeyName + "Index"),
+		KeySchema:  keySchema,
+		Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+		ProvisionedThroughput: &types.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(3),
+			WriteCapacityUnits: aws.Int64(3),
+		},
+	}
+}
+
+// indexAttributes Adds the index key attributes missing from definitions
+func (tableOps *TableOperation) indexAttributes(definitions []types.AttributeDefinition) []types.AttributeDefinition {
+	for _, name := range []string{tableOps.IndexKeyName, tableOps.PrimKeyName, tableOps.SecKeyName} {
+		isFound := name == ""
+		for _, definition := range definitions {
+			isFound = isFound || *definition.AttributeName == name
+		}
+		if !isFound {
+			definitions = append(definitions, types.AttributeDefinition{
+				AttributeName: aws.String(name),
+				AttributeType: types.ScalarAttributeTypeS,
+			})
+		}
 	}
+	return definitions
 }
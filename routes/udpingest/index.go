@@ -0,0 +1,130 @@
+/*
+Udpingest Package listens for binary helmet frames (see the telemetry package) sent by radio gateways over UDP
+A datagram may carry several frames, telemetry frames go through the same path as POST /userinfo,
+danger frames through POST /danger. Nothing is sent back, lost frames show up as sequence gaps
+Only active when UDP_LISTEN_ADDR is set
+*/
+
+package udpingest
+
+import (
+	"go_backend/routes/danger"
+	"go_backend/routes/userinfo"
+	"go_backend/telemetry"
+	"go_backend/util"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const FILENAME = "udpingest/index.go"
+
+// MaxDatagram is the largest datagram read, longer ones are truncated and rejected by the CRC check
+const MaxDatagram = 2048
+
+type Status struct {
+	Enabled     bool
+	Address     string `json:",omitempty"`
+	Datagrams   uint64
+	Received    uint64 // Frames stored or raised
+	Duplicates  uint64
+	Rejected    uint64 // Frames that didn't decode or couldn't be stored
+	LastFrameAt *time.Time
+	LastError   string
+}
+
+var mu sync.Mutex
+var status Status
+
+// Where Handle sends decoded frames, tests point them elsewhere
+var ingest = userinfo.Ingest
+var raiseReading = danger.RaiseReading
+
+// Handle Stores or raises one decoded frame
+func Handle(frame *telemetry.Frame) (bool, error) {
+	rawWorkInfo, err := userinfo.FromFrame(frame)
+	if err != nil {
+		return false, err
+	}
+	if frame.Kind == telemetry.KindDanger {
+		_, isNew, err := raiseReading(rawWorkInfo)
+		if err == danger.ErrInProgress {
+			return false, nil
+		}
+		return isNew, err
+	}
+	_, isNew, err := ingest(rawWorkInfo)
+	return isNew, err
+}
+
+// HandleDatagram Handles every frame of a datagram, the frames before an undecodable one are kept
+func HandleDatagram(data []byte, eLog *log.Logger) {
+	frames, decodeErr := telemetry.DecodeAll(data)
+	for i := range frames {
+		isNew, err := Handle(&frames[i])
+		if err != nil {
+			eLog.Printf("Couldn't ingest UDP frame. Reason => %v\n", err)
+		}
+		record(isNew, err)
+	}
+	if decodeErr != nil {
+		eLog.Printf("Couldn't decode UDP datagram. Reason => %v\n", decodeErr)
+		record(false, decodeErr)
+	}
+}
+
+func record(isNew bool, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	now := time.Now()
+	status.LastFrameAt = &now
+	switch {
+	case err != nil:
+		status.Rejected++
+		status.LastError = err.Error()
+	case isNew:
+		status.Received++
+	default:
+		status.Duplicates++
+	}
+}
+
+// Start Listens on UDP_LISTEN_ADDR and handles datagrams until the socket fails, blocks
+func Start(pLog *log.Logger, eLog *log.Logger) {
+	conn, err := net.ListenPacket("udp", util.GetUDPListenAddr())
+	if err != nil {
+		eLog.Printf("Couldn't listen for UDP frames. Here's why: %v\n", err)
+		return
+	}
+	defer conn.Close()
+	mu.Lock()
+	status.Enabled = true
+	status.Address = conn.LocalAddr().String()
+	mu.Unlock()
+	pLog.Printf("UDP ingestion Started on %v\n", conn.LocalAddr())
+
+	buffer := make([]byte, MaxDatagram)
+	for {
+		size, _, err := conn.ReadFrom(buffer)
+		if err != nil {
+			eLog.Printf("UDP ingestion stopped. Reason => %v\n", err)
+			return
+		}
+		mu.Lock()
+		status.Datagrams++
+		mu.Unlock()
+		HandleDatagram(buffer[:size], eLog)
+	}
+}
+
+// Get Reports the listener's datagram and frame counters
+func Get(ctx *gin.Context) {
+	util.DebugPrint(FILENAME, "GET", "Just Test")
+	mu.Lock()
+	defer mu.Unlock()
+	ctx.JSON(http.StatusOK, status)
+}
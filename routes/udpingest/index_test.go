@@ -0,0 +1,123 @@
+package udpingest
+
+import (
+	"errors"
+	"go_backend/routes/danger"
+	"go_backend/routes/userinfo"
+	"go_backend/telemetry"
+	"io"
+	"log"
+	"strings"
+	"testing"
+)
+
+// useFakePaths Records the readings Handle passes on instead of storing them, the answer of each comes from answer
+func useFakePaths(t *testing.T, answer func(rawWorkInfo *userinfo.RawWorkerInfo) (bool, error)) *[]userinfo.RawWorkerInfo {
+	t.Helper()
+	var received []userinfo.RawWorkerInfo
+	previousIngest, previousRaise := ingest, raiseReading
+	ingest = func(rawWorkInfo *userinfo.RawWorkerInfo) (*userinfo.WorkerInfo, bool, error) {
+		received = append(received, *rawWorkInfo)
+		isNew, err := answer(rawWorkInfo)
+		return rawWorkInfo.ConvertToWorkInfo(), isNew, err
+	}
+	raiseReading = func(rawWorkInfo *userinfo.RawWorkerInfo) (danger.Alert, bool, error) {
+		received = append(received, *rawWorkInfo)
+		isNew, err := answer(rawWorkInfo)
+		return danger.Alert{}, isNew, err
+	}
+	mu.Lock()
+	status = Status{}
+	mu.Unlock()
+	t.Cleanup(func() { ingest, raiseReading = previousIngest, previousRaise })
+	return &received
+}
+
+func encode(t *testing.T, frames ...telemetry.Frame) []byte {
+	t.Helper()
+	var data []byte
+	for _, frame := range frames {
+		encoded, err := frame.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = append(data, encoded...)
+	}
+	return data
+}
+
+func counters() Status {
+	mu.Lock()
+	defer mu.Unlock()
+	return status
+}
+
+var discard = log.New(io.Discard, "", 0)
+
+func TestHandleDatagramFrames(t *testing.T) {
+	received := useFakePaths(t, func(rawWorkInfo *userinfo.RawWorkerInfo) (bool, error) {
+		return rawWorkInfo.Sequence != 2, nil // 2 was already received
+	})
+	HandleDatagram(encode(t,
+		telemetry.Frame{Kind: telemetry.KindTelemetry, GroundNumber: 1, HelmetNumber: 7, Sequence: 1, Spo2Level: 97},
+		telemetry.Frame{Kind: telemetry.KindTelemetry, GroundNumber: 1, HelmetNumber: 7, Sequence: 2, Spo2Level: 96},
+		telemetry.Frame{Kind: telemetry.KindDanger, GroundNumber: 1, HelmetNumber: 8, Sequence: 3, DangerType: telemetry.DangerSOS},
+	), discard)
+
+	if len(*received) != 3 {
+		t.Fatalf("passed on %v frames, want 3", len(*received))
+	}
+	if sos := (*received)[2]; sos.GroundNumber != "1" || sos.HelmetNumber != "8" || sos.DangerType != danger.DangerSOS {
+		t.Errorf("danger frame passed on as %+v, want an SOS of 1_8", sos)
+	}
+	if got := counters(); got.Received != 2 || got.Duplicates != 1 || got.Rejected != 0 || got.LastFrameAt == nil {
+		t.Errorf("counters = %+v, want 2 received and 1 duplicate", got)
+	}
+}
+
+func TestHandleDatagramDangerFrameRaises(t *testing.T) {
+	var raised []userinfo.RawWorkerInfo
+	useFakePaths(t, func(rawWorkInfo *userinfo.RawWorkerInfo) (bool, error) { return true, nil })
+	ingest = func(rawWorkInfo *userinfo.RawWorkerInfo) (*userinfo.WorkerInfo, bool, error) {
+		t.Errorf("danger frame %+v went to ingest", rawWorkInfo)
+		return nil, false, nil
+	}
+	raiseReading = func(rawWorkInfo *userinfo.RawWorkerInfo) (danger.Alert, bool, error) {
+		raised = append(raised, *rawWorkInfo)
+		return danger.Alert{}, true, nil
+	}
+	HandleDatagram(encode(t, telemetry.Frame{Kind: telemetry.KindDanger, GroundNumber: 2, HelmetNumber: 3,
+		DangerType: 2}), discard) // Water
+	if len(raised) != 1 || raised[0].DangerType != danger.DangerWater {
+		t.Errorf("raised %+v, want the Water alert of 2_3", raised)
+	}
+}
+
+func TestHandleDatagramCorruptTrailingFrame(t *testing.T) {
+	received := useFakePaths(t, func(rawWorkInfo *userinfo.RawWorkerInfo) (bool, error) { return true, nil })
+	data := encode(t,
+		telemetry.Frame{Kind: telemetry.KindTelemetry, GroundNumber: 1, HelmetNumber: 7, Sequence: 1},
+		telemetry.Frame{Kind: telemetry.KindTelemetry, GroundNumber: 1, HelmetNumber: 7, Sequence: 2},
+		telemetry.Frame{Kind: telemetry.KindTelemetry, GroundNumber: 1, HelmetNumber: 7, Sequence: 3},
+	)
+	data[len(data)-1] ^= 0xff // breaks the CRC of the last frame
+
+	HandleDatagram(data, discard)
+	if len(*received) != 2 || (*received)[1].Sequence != 2 {
+		t.Errorf("passed on %+v, want the 2 frames before the corrupt one", *received)
+	}
+	got := counters()
+	if got.Received != 2 || got.Rejected != 1 || !strings.Contains(got.LastError, "frame 2") {
+		t.Errorf("counters = %+v, want 2 received and frame 2 rejected", got)
+	}
+}
+
+func TestHandleDatagramStoreFailure(t *testing.T) {
+	useFakePaths(t, func(rawWorkInfo *userinfo.RawWorkerInfo) (bool, error) {
+		return false, errors.New("database unreachable")
+	})
+	HandleDatagram(encode(t, telemetry.Frame{Kind: telemetry.KindTelemetry, GroundNumber: 1, HelmetNumber: 7}), discard)
+	if got := counters(); got.Received != 0 || got.Rejected != 1 || got.LastError != "database unreachable" {
+		t.Errorf("counters = %+v, want the frame rejected", got)
+	}
+}
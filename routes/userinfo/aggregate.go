@@ -0,0 +1,223 @@
+package userinfo
+
+import (
+	"github.com/gin-gonic/gin"
+	"go_backend/util"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// VitalFields are the numeric fields charted by the dashboards
+var VitalFields = []string{"Spo2Level", "Temperature", "GasLevel", "HeartRate"}
+
+// BucketSizes are the bucket query values GetAggregate accepts
+var BucketSizes = map[string]time.Duration{
+	"1m": time.Minute,
+	"5m": 5 * time.Minute,
+	"1h": time.Hour,
+}
+
+// MaxDownsamplePoints caps the points query of GetDownsample
+const MaxDownsamplePoints = 5000
+
+// FieldStats summarises one field over a bucket, a field the helmet never reported (0) is left out
+type FieldStats struct {
+	Min  float64
+	Max  float64
+	Avg  float64
+	Last float64
+}
+
+type Bucket struct {
+	Start  time.Time
+	Count  int // Readings in the bucket
+	Fields map[string]FieldStats
+}
+
+// Point is one (time, value) pair of a downsampled series
+type Point struct {
+	At    time.Time
+	Value float64
+}
+
+// Aggregate Groups ordered readings into buckets of size aligned to the local clock of loc
+// (hour buckets start on the hour of a +05:30 site too), empty buckets are skipped
+func Aggregate(readings []WorkerInfo, size time.Duration, loc *time.Location) []Bucket {
+	buckets := []Bucket{}
+	sums := make(map[string]float64)
+	counts := make(map[string]int)
+	flush := func() {
+		last := &buckets[len(buckets)-1]
+		for field, stats := range last.Fields {
+			stats.Avg = sums[field] / float64(counts[field])
+			last.Fields[field] = stats
+		}
+		sums = make(map[string]float64)
+		counts = make(map[string]int)
+	}
+
+	for i := range readings {
+		at, err := ParseTime(readings[i].Timestamp)
+		if err != nil {
+			continue
+		}
+		_, offset := at.In(loc).Zone()
+		shift := time.Duration(offset) * time.Second
+		start := at.Add(shift).Truncate(size).Add(-shift).In(loc)
+		if len(buckets) == 0 || !buckets[len(buckets)-1].Start.Equal(start) {
+			if len(buckets) > 0 {
+				flush()
+			}
+			buckets = append(buckets, Bucket{Start: start, Fields: make(map[string]FieldStats)})
+		}
+		bucket := &buckets[len(buckets)-1]
+		bucket.Count++
+		for _, field := range VitalFields {
+			value, _ := readings[i].GetField(field)
+			if value == 0 {
+				continue
+			}
+			stats, isFound := bucket.Fields[field]
+			if !isFound {
+				stats = FieldStats{Min: value, Max: value}
+			}
+			stats.Min = math.Min(stats.Min, value)
+			stats.Max = math.Max(stats.Max, value)
+			stats.Last = value
+			bucket.Fields[field] = stats
+			sums[field] += value
+			counts[field]++
+		}
+	}
+	if len(buckets) > 0 {
+		flush()
+	}
+	return buckets
+}
+
+// Series Returns the ordered points of one field, readings that didn't report it are skipped
+func Series(readings []WorkerInfo, field string) []Point {
+	points := []Point{}
+	for i := range readings {
+		value, _ := readings[i].GetField(field)
+		at, err := ParseTime(readings[i].Timestamp)
+		if value == 0 || err != nil {
+			continue
+		}
+		points = append(points, Point{At: at, Value: value})
+	}
+	return points
+}
+
+// Downsample Reduces points to at most threshold using Largest Triangle Three Buckets
+// The first and last points are kept, from every bucket in between the point forming the largest
+// triangle with the previously kept point and the average of the next bucket is kept
+func Downsample(points []Point, threshold int) []Point {
+	if threshold >= len(points) || threshold <= 0 {
+		return points
+	}
+	if threshold < 3 {
+		threshold = 3
+		if threshold >= len(points) {
+			return points
+		}
+	}
+
+	sampled := make([]Point, 0, threshold)
+	sampled = append(sampled, points[0])
+	every := float64(len(points)-2) / float64(threshold-2)
+	kept := 0
+	for i := 0; i < threshold-2; i++ {
+		// Average of the next bucket, the last point for the final bucket
+		nextStart := int(float64(i+1)*every) + 1
+		nextEnd := int(float64(i+2)*every) + 1
+		if nextEnd > len(points) {
+			nextEnd = len(points)
+		}
+		avgAt, avgValue := 0.0, 0.0
+		for _, point := range points[nextStart:nextEnd] {
+			avgAt += seconds(point.At)
+			avgValue += point.Value
+		}
+		if count := float64(nextEnd - nextStart); count > 0 {
+			avgAt /= count
+			avgValue /= count
+		}
+
+		start := int(float64(i)*every) + 1
+		end := nextStart
+		keptAt, keptValue := seconds(points[kept].At), points[kept].Value
+		maxArea, maxIndex := -1.0, start
+		for j := start; j < end; j++ {
+			area := math.Abs((keptAt-avgAt)*(points[j].Value-keptValue) -
+				(keptAt-seconds(points[j].At))*(avgValue-keptValue))
+			if area > maxArea {
+				maxArea, maxIndex = area, j
+			}
+		}
+		sampled = append(sampled, points[maxIndex])
+		kept = maxIndex
+	}
+	return append(sampled, points[len(points)-1])
+}
+
+func seconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+// GetAggregate Returns per bucket min/max/avg/last of every vital sign
+// GET /userinfo/:id/readings/aggregate?bucket=1m|5m|1h&from=&to= , from and to work as in GetReadings
+func GetAggregate(ctx *gin.Context) {
+	util.DebugPrint(FILENAME, "GET AGGREGATE", "Just Test")
+	size, isFound := BucketSizes[ctx.DefaultQuery("bucket", "5m")]
+	if !isFound {
+		util.WriteError(ctx, http.StatusBadRequest, "bucket must be 1m, 5m or 1h")
+		return
+	}
+	from, to, isValid := readingsRange(ctx)
+	if !isValid {
+		return
+	}
+	readings, err := GetStore().GetReadings(ctx.Param("id"), FormatTimestamp(from), FormatTimestamp(to))
+	if err != nil {
+		util.WriteStoreError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, Aggregate(readings, size, GetLocation(ctx.Param("id"))))
+}
+
+// GetDownsample Returns at most points points per vital sign, field selects a single one
+// GET /userinfo/:id/readings/downsample?points=N&field=&from=&to=
+func GetDownsample(ctx *gin.Context) {
+	util.DebugPrint(FILENAME, "GET DOWNSAMPLE", "Just Test")
+	threshold, err := strconv.Atoi(ctx.DefaultQuery("points", "500"))
+	if err != nil || threshold < 3 || threshold > MaxDownsamplePoints {
+		util.WriteError(ctx, http.StatusBadRequest, "points must be a number between 3 and "+strconv.Itoa(MaxDownsamplePoints))
+		return
+	}
+	fields := VitalFields
+	if field, isFound := ctx.GetQuery("field"); isFound {
+		if _, isKnown := (&WorkerInfo{}).GetField(field); !isKnown {
+			util.WriteError(ctx, http.StatusBadRequest, "Unknown field "+field)
+			return
+		}
+		fields = []string{field}
+	}
+	from, to, isValid := readingsRange(ctx)
+	if !isValid {
+		return
+	}
+
+	readings, err := GetStore().GetReadings(ctx.Param("id"), FormatTimestamp(from), FormatTimestamp(to))
+	if err != nil {
+		util.WriteStoreError(ctx, err)
+		return
+	}
+	seriesMap := make(map[string][]Point)
+	for _, field := range fields {
+		seriesMap[field] = Downsample(Series(readings, field), threshold)
+	}
+	ctx.JSON(http.StatusOK, seriesMap)
+}
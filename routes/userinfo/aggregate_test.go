@@ -0,0 +1,112 @@
+package userinfo
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func pointsOf(values ...float64) []Point {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	points := make([]Point, len(values))
+	for i, value := range values {
+		points[i] = Point{At: start.Add(time.Duration(i) * time.Second), Value: value}
+	}
+	return points
+}
+
+func TestDownsampleKeepsShape(t *testing.T) {
+	values := make([]float64, 1000)
+	for i := range values {
+		values[i] = math.Sin(float64(i) / 50)
+	}
+	values[437] = 25 // a spike LTTB must not average away
+	points := pointsOf(values...)
+
+	sampled := Downsample(points, 50)
+	if len(sampled) != 50 {
+		t.Fatalf("Downsample returned %v points, want 50", len(sampled))
+	}
+	if sampled[0] != points[0] || sampled[len(sampled)-1] != points[len(points)-1] {
+		t.Error("first and last points not kept")
+	}
+	spike := false
+	for i, point := range sampled {
+		if i > 0 && !point.At.After(sampled[i-1].At) {
+			t.Fatalf("points out of order at %v", i)
+		}
+		spike = spike || point.Value == 25
+	}
+	if !spike {
+		t.Error("spike dropped")
+	}
+}
+
+func TestDownsampleSmallCases(t *testing.T) {
+	points := pointsOf(1, 2, 3, 4, 5)
+	if got := Downsample(points, 5); len(got) != 5 {
+		t.Errorf("threshold = len returned %v points", len(got))
+	}
+	if got := Downsample(points, 0); len(got) != 5 {
+		t.Errorf("threshold 0 returned %v points, want the input", len(got))
+	}
+	if got := Downsample(points, 1); len(got) != 3 {
+		t.Errorf("threshold 1 returned %v points, want 3 (first, one, last)", len(got))
+	}
+	if got := Downsample(nil, 10); len(got) != 0 {
+		t.Errorf("empty input returned %v", got)
+	}
+
+	// One bucket per middle point of 0 0 10 0 0 0 : the 10 forms the largest triangle
+	got := Downsample(pointsOf(0, 0, 10, 0, 0, 0), 3)
+	if len(got) != 3 || got[1].Value != 10 {
+		t.Errorf("Downsample = %v, want the peak in the middle", got)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	at := func(clock string) string {
+		parsed, _ := time.Parse(time.RFC3339, "2024-01-01T"+clock+"Z")
+		return FormatTimestamp(parsed)
+	}
+	readings := []WorkerInfo{
+		{Timestamp: at("10:00:05"), HeartRate: 80, Spo2Level: 97},
+		{Timestamp: at("10:00:40"), HeartRate: 100},
+		{Timestamp: at("10:00:50"), HeartRate: 90, Spo2Level: 95},
+		{Timestamp: at("10:03:00"), HeartRate: 70},
+	}
+	buckets := Aggregate(readings, time.Minute, time.UTC)
+	if len(buckets) != 2 {
+		t.Fatalf("Aggregate returned %v buckets, want 2 (empty minutes skipped)", len(buckets))
+	}
+	first := buckets[0]
+	if first.Count != 3 || first.Start.Format("15:04") != "10:00" {
+		t.Errorf("first bucket %+v", first)
+	}
+	if heartRate := first.Fields["HeartRate"]; heartRate != (FieldStats{Min: 80, Max: 100, Avg: 90, Last: 90}) {
+		t.Errorf("HeartRate stats %+v", heartRate)
+	}
+	// Spo2Level unreported (0) in the second reading doesn't drag the average down
+	if spo2 := first.Fields["Spo2Level"]; spo2 != (FieldStats{Min: 95, Max: 97, Avg: 96, Last: 95}) {
+		t.Errorf("Spo2Level stats %+v", spo2)
+	}
+	if _, isFound := first.Fields["GasLevel"]; isFound {
+		t.Error("GasLevel never reported but has stats")
+	}
+	if buckets[1].Start.Format("15:04") != "10:03" || buckets[1].Fields["HeartRate"].Avg != 70 {
+		t.Errorf("second bucket %+v", buckets[1])
+	}
+}
+
+func TestAggregateAlignsToLocalHour(t *testing.T) {
+	ist := time.FixedZone("IST", 5*3600+1800)
+	readings := []WorkerInfo{
+		{Timestamp: FormatTimestamp(time.Date(2024, 1, 1, 10, 10, 0, 0, ist)), HeartRate: 80},
+		{Timestamp: FormatTimestamp(time.Date(2024, 1, 1, 10, 50, 0, 0, ist)), HeartRate: 90},
+		{Timestamp: FormatTimestamp(time.Date(2024, 1, 1, 11, 5, 0, 0, ist)), HeartRate: 100},
+	}
+	buckets := Aggregate(readings, time.Hour, ist)
+	if len(buckets) != 2 || !buckets[0].Start.Equal(time.Date(2024, 1, 1, 10, 0, 0, 0, ist)) || buckets[0].Count != 2 {
+		t.Fatalf("buckets = %+v, want 10:00 and 11:00 IST", buckets)
+	}
+}
@@ -0,0 +1,147 @@
+package userinfo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go_backend/util"
+	"log"
+	"net/http"
+	"time"
+)
+
+// MaxBatchItems caps the readings accepted by one POST /userinfo/batch
+const MaxBatchItems = 1000
+
+// Batch item statuses
+const (
+	BatchAccepted  = "accepted"
+	BatchRejected  = "rejected"
+	BatchDuplicate = "duplicate" // Already received within DEDUP_WINDOW, not stored again
+)
+
+type BatchItemResult struct {
+	Index  int    // Position in the request array
+	Id     string `json:",omitempty"`
+	Status string // accepted, rejected or duplicate
+	Reason string `json:",omitempty"`
+}
+
+type BatchReport struct {
+	Accepted   int
+	Rejected   int
+	Duplicates int
+	Results    []BatchItemResult
+}
+
+// Validate Returns why a reading can't be stored, "" when it can
+func (rawWorkInfo *RawWorkerInfo) Validate() string {
+	if rawWorkInfo.GroundNumber == "" || rawWorkInfo.HelmetNumber == "" {
+		return "GroundNumber and HelmetNumber are required"
+	}
+	return ""
+}
+
+// PostBatch Stores an array of RawWorkerInfo buffered by a helmet gateway
+// Entries are checked one by one, a bad entry is rejected without failing the others
+// The report lists every entry in request order
+func PostBatch(ctx *gin.Context) {
+	util.DebugPrint(FILENAME, "POST BATCH", "Just Test")
+	var rawItems []json.RawMessage
+	if err := ctx.ShouldBindJSON(&rawItems); err != nil {
+		util.WriteError(ctx, http.StatusBadRequest, "Body must be a JSON array of readings")
+		return
+	}
+	if len(rawItems) > MaxBatchItems {
+		util.WriteError(ctx, http.StatusRequestEntityTooLarge, fmt.Sprintf("At most %v readings per batch", MaxBatchItems))
+		return
+	}
+
+	rawWorkInfoList := make([]*RawWorkerInfo, len(rawItems))
+	decodeErrs := make([]error, len(rawItems))
+	for i, rawItem := range rawItems {
+		rawWorkInfoList[i] = &RawWorkerInfo{}
+		decodeErrs[i] = json.Unmarshal(rawItem, rawWorkInfoList[i])
+	}
+	report, err := IngestBatch(rawWorkInfoList, decodeErrs)
+	if err != nil {
+		util.WriteStoreError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, report)
+}
+
+// IngestBatch Stores a batch of readings like PostBatch, shared with the gRPC API
+// decodeErrs holds why an entry couldn't be decoded (nil when it was), the slice itself may be nil
+// The error is set only when every write failed because the store was throttled or unavailable, the whole batch can be retried
+func IngestBatch(rawWorkInfoList []*RawWorkerInfo, decodeErrs []error) (BatchReport, error) {
+	report := BatchReport{Results: make([]BatchItemResult, len(rawWorkInfoList))}
+	var workerInfoList []WorkerInfo
+	var owners []int
+	var dedupKeys []string
+	timestamps := make(map[string]bool) // Id#Timestamp already in this batch
+	// Readings without MeasuredAt get distinct, ordered timestamps so none of them overwrite each other
+	now := time.Now()
+	for i, rawWorkInfo := range rawWorkInfoList {
+		report.Results[i] = BatchItemResult{Index: i, Status: BatchRejected}
+		if i < len(decodeErrs) && decodeErrs[i] != nil {
+			report.Results[i].Reason = "Invalid reading: " + decodeErrs[i].Error()
+			continue
+		}
+		if reason := rawWorkInfo.Validate(); reason != "" {
+			report.Results[i].Reason = reason
+			continue
+		}
+		workerInfo := rawWorkInfo.ConvertToWorkInfoAt(now.Add(time.Duration(i)))
+		report.Results[i].Id = workerInfo.Id
+		for timestamps[workerInfo.Id+"#"+workerInfo.Timestamp] {
+			// Two readings of a helmet at the same instant, BatchWriteItem rejects duplicate keys
+			workerInfo.Timestamp = FormatTimestamp(workerInfo.MeasuredTime().Add(time.Nanosecond))
+		}
+		dedupKey := rawWorkInfo.DedupKey()
+		if _, isNew := ingestDedup.Claim(dedupKey, now); !isNew {
+			report.Results[i].Status = BatchDuplicate
+			continue
+		}
+		timestamps[workerInfo.Id+"#"+workerInfo.Timestamp] = true
+		workerInfoList = append(workerInfoList, *workerInfo)
+		owners = append(owners, i)
+		dedupKeys = append(dedupKeys, dedupKey)
+	}
+
+	errs := GetStore().InsertBatchWorkerInfo(workerInfoList)
+	var transientErr error
+	isAllTransient := len(errs) > 0
+	for at, err := range errs {
+		if err != nil {
+			log.Printf("Couldn't store batch item %v of %v. Reason => %v\n", owners[at], workerInfoList[at].Id, err)
+			report.Results[owners[at]].Reason = util.StoreErrorMessage(err)
+			ingestDedup.Release(dedupKeys[at])
+			if errors.Is(err, util.ErrThrottled) || errors.Is(err, util.ErrUnavailable) {
+				transientErr = err
+			} else {
+				isAllTransient = false
+			}
+			continue
+		}
+		isAllTransient = false
+		report.Results[owners[at]].Status = BatchAccepted
+		TrackSequence(&workerInfoList[at], now)
+		RunIngestHooks(&workerInfoList[at], workerInfoList[at].MeasuredTime())
+	}
+	for _, result := range report.Results {
+		switch result.Status {
+		case BatchAccepted:
+			report.Accepted++
+		case BatchDuplicate:
+			report.Duplicates++
+		default:
+			report.Rejected++
+		}
+	}
+	if isAllTransient {
+		return report, transientErr
+	}
+	return report, nil
+}
@@ -0,0 +1,231 @@
+package userinfo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"go_backend/util"
+)
+
+func (fake *fakeDynamo) BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	call := len(fake.batches)
+	output := &dynamodb.BatchWriteItemOutput{UnprocessedItems: map[string][]types.WriteRequest{}}
+	for table, writeReqs := range params.RequestItems {
+		fake.batches = append(fake.batches, len(writeReqs))
+		if fake.batchErr != nil {
+			return nil, fake.batchErr
+		}
+		var unprocessed []types.WriteRequest
+		if fake.unprocessed != nil {
+			unprocessed = fake.unprocessed(call, writeReqs)
+		}
+		skipped := make(map[string]bool)
+		for _, writeReq := range unprocessed {
+			skipped[writeRequestKey(writeReq)] = true
+		}
+		for _, writeReq := range writeReqs {
+			if skipped[writeRequestKey(writeReq)] || writeReq.PutRequest == nil {
+				continue
+			}
+			workerInfo := WorkerInfo{}
+			attributevalue.UnmarshalMap(writeReq.PutRequest.Item, &workerInfo)
+			if fake.written == nil {
+				fake.written = make(map[string][]WorkerInfo)
+			}
+			fake.written[table] = append(fake.written[table], workerInfo)
+		}
+		if len(unprocessed) > 0 {
+			output.UnprocessedItems[table] = unprocessed
+		}
+	}
+	return output, nil
+}
+
+// useFastBackoff Keeps the UnprocessedItems backoff from slowing a test down
+func useFastBackoff(t *testing.T) {
+	previous := batchBackoff
+	batchBackoff = time.Millisecond
+	t.Cleanup(func() { batchBackoff = previous })
+}
+
+func putRequests(count int) []types.WriteRequest {
+	writeReqs := make([]types.WriteRequest, count)
+	for i := range writeReqs {
+		item, _ := attributevalue.MarshalMap(WorkerInfo{Id: fmt.Sprintf("1_%v", i), Date: "2024-01-01", Timestamp: "2024-01-01T10:00:00Z"})
+		writeReqs[i] = types.WriteRequest{PutRequest: &types.PutRequest{Item: item}}
+	}
+	return writeReqs
+}
+
+func TestWriteBatchItemsChunks(t *testing.T) {
+	fake := &fakeDynamo{}
+	for i, err := range newFakeStore(fake).WriteBatchItems(READINGTABLENAME, putRequests(2*BatchSize+10)) {
+		if err != nil {
+			t.Errorf("request %v error = %v", i, err)
+		}
+	}
+	if fmt.Sprint(fake.batches) != fmt.Sprint([]int{BatchSize, BatchSize, 10}) {
+		t.Errorf("calls sent %v requests", fake.batches)
+	}
+	if len(fake.written[READINGTABLENAME]) != 2*BatchSize+10 {
+		t.Errorf("wrote %v items", len(fake.written[READINGTABLENAME]))
+	}
+}
+
+func TestWriteBatchItemsResendsUnprocessed(t *testing.T) {
+	useFastBackoff(t)
+	fake := &fakeDynamo{unprocessed: func(call int, writeReqs []types.WriteRequest) []types.WriteRequest {
+		if call == 0 {
+			return writeReqs[len(writeReqs)-3:]
+		}
+		return nil
+	}}
+	for i, err := range newFakeStore(fake).WriteBatchItems(READINGTABLENAME, putRequests(BatchSize+5)) {
+		if err != nil {
+			t.Errorf("request %v error = %v", i, err)
+		}
+	}
+	// The 3 left over are resent before the next chunk goes out
+	if fmt.Sprint(fake.batches) != fmt.Sprint([]int{BatchSize, 3, 5}) {
+		t.Errorf("calls sent %v requests", fake.batches)
+	}
+	if len(fake.written[READINGTABLENAME]) != BatchSize+5 {
+		t.Errorf("wrote %v items", len(fake.written[READINGTABLENAME]))
+	}
+}
+
+func TestWriteBatchItemsGivesUp(t *testing.T) {
+	useFastBackoff(t)
+	stuck := writeRequestKey(putRequests(4)[3])
+	fake := &fakeDynamo{unprocessed: func(call int, writeReqs []types.WriteRequest) []types.WriteRequest {
+		for _, writeReq := range writeReqs {
+			if writeRequestKey(writeReq) == stuck {
+				return []types.WriteRequest{writeReq}
+			}
+		}
+		return nil
+	}}
+	errs := newFakeStore(fake).WriteBatchItems(READINGTABLENAME, putRequests(10))
+	for i, err := range errs {
+		if i == 3 && !errors.Is(err, ErrUnprocessed) {
+			t.Errorf("stuck request error = %v, want ErrUnprocessed", err)
+		} else if i != 3 && err != nil {
+			t.Errorf("request %v error = %v", i, err)
+		}
+	}
+	if len(fake.batches) != BatchRetries+1 {
+		t.Errorf("made %v calls, want the first and %v resends", len(fake.batches), BatchRetries)
+	}
+}
+
+func TestWriteBatchItemsFailedCall(t *testing.T) {
+	fake := &fakeDynamo{batchErr: &types.ProvisionedThroughputExceededException{}}
+	for i, err := range newFakeStore(fake).WriteBatchItems(READINGTABLENAME, putRequests(BatchSize+1)) {
+		if !errors.Is(err, util.ErrThrottled) {
+			t.Errorf("request %v error = %v, want ErrThrottled", i, err)
+		}
+	}
+	if len(fake.batches) != 2 {
+		t.Errorf("made %v calls, want one per chunk and no resend", len(fake.batches))
+	}
+}
+
+func TestInsertBatchWorkerInfoKeepsNewestRow(t *testing.T) {
+	fake := &fakeDynamo{}
+	workerInfoList := []WorkerInfo{
+		{Id: "1_2", Date: "2024-01-01", Timestamp: "2024-01-01T11:00:00Z"},
+		{Id: "1_2", Date: "2024-01-01", Timestamp: "2024-01-01T12:00:00Z"},
+		{Id: "1_2", Date: "2024-01-01", Timestamp: "2024-01-01T10:00:00Z"},
+		{Id: "1_3", Date: "2024-01-01", Timestamp: "2024-01-01T10:00:00Z"},
+	}
+	for i, err := range newFakeStore(fake).InsertBatchWorkerInfo(workerInfoList) {
+		if err != nil {
+			t.Errorf("reading %v error = %v", i, err)
+		}
+	}
+	if len(fake.written[READINGTABLENAME]) != 4 {
+		t.Errorf("wrote %v readings, want all 4", len(fake.written[READINGTABLENAME]))
+	}
+	rows := fake.written[TABLENAME]
+	if len(rows) != 2 || rows[0].Timestamp != "2024-01-01T12:00:00Z" || rows[1].Id != "1_3" {
+		t.Errorf("WorkerInfo rows = %+v, want the newest reading of each Id + Date", rows)
+	}
+}
+
+func TestPostBatchReport(t *testing.T) {
+	useMemoryStore(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/userinfo/batch", PostBatch)
+
+	body := `[
+		{"GroundNumber":"7","HelmetNumber":"1","Sequence":1},
+		{"GroundNumber":"7"},
+		"not a reading",
+		{"GroundNumber":"7","HelmetNumber":"1","Sequence":1},
+		{"GroundNumber":"7","HelmetNumber":"2"}
+	]`
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/userinfo/batch", strings.NewReader(body)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("POST = %v %v", recorder.Code, recorder.Body)
+	}
+	report := BatchReport{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{BatchAccepted, BatchRejected, BatchRejected, BatchDuplicate, BatchAccepted}
+	for i, result := range report.Results {
+		if result.Index != i || result.Status != want[i] {
+			t.Errorf("result %v = %+v, want %v", i, result, want[i])
+		}
+	}
+	if report.Accepted != 2 || report.Rejected != 2 || report.Duplicates != 1 {
+		t.Errorf("report counts = %v/%v/%v", report.Accepted, report.Rejected, report.Duplicates)
+	}
+
+	// A store that fails every write is reported by status so the gateway retries the whole batch later
+	viper.Set("STORE_RETRY_AFTER", "2s")
+	t.Cleanup(func() { viper.Set("STORE_RETRY_AFTER", "") })
+	tClient = newFakeStore(&fakeDynamo{batchErr: &types.ProvisionedThroughputExceededException{}})
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/userinfo/batch",
+		strings.NewReader(`[{"GroundNumber":"7","HelmetNumber":"3"}]`)))
+	if recorder.Code != http.StatusTooManyRequests || recorder.Header().Get("Retry-After") != "2" {
+		t.Errorf("POST to a throttled store = %v, Retry-After %q, want 429 and 2", recorder.Code, recorder.Header().Get("Retry-After"))
+	}
+
+	// Other failures stay per item, and the database's text stays in the log
+	tClient = newFakeStore(&fakeDynamo{batchErr: &smithy.GenericAPIError{Code: "ValidationException", Message: "Table: WorkerInfo-prod"}})
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/userinfo/batch",
+		strings.NewReader(`[{"GroundNumber":"7","HelmetNumber":"4"}]`)))
+	report = BatchReport{}
+	json.Unmarshal(recorder.Body.Bytes(), &report)
+	if recorder.Code != http.StatusOK || len(report.Results) != 1 || report.Results[0].Reason != "The database rejected the request" {
+		t.Errorf("POST to a store rejecting the batch = %v %v", recorder.Code, recorder.Body)
+	}
+	useMemoryStore(t)
+
+	tooMany := "[" + strings.Repeat(`{},`, MaxBatchItems) + "{}]"
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/userinfo/batch", strings.NewReader(tooMany)))
+	if recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("POST of %v readings = %v, want 413", MaxBatchItems+1, recorder.Code)
+	}
+}
@@ -0,0 +1,301 @@
+package userinfo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"go_backend/util"
+	"log"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltWorkerInfoStore keeps WorkerInfo in the embedded on-disk database so a site can run without DynamoDB
+// Keys are Date#Id so that a date range is a single ordered cursor walk
+// WORKERINDEXBUCKET maps Id#Date to the Date#Id key, so the latest entry of an Id is one seek
+// Readings live in their own bucket keyed Id#Timestamp
+type BoltWorkerInfoStore struct {
+	DB         *bolt.DB
+	BucketName string
+}
+
+const WORKERINDEXBUCKET string = "WorkerInfoById"
+
+func NewBoltWorkerInfoStore(db *bolt.DB) *BoltWorkerInfoStore {
+	util.CreateBucket(db, TABLENAME)
+	util.CreateBucket(db, READINGTABLENAME)
+	store := &BoltWorkerInfoStore{DB: db, BucketName: TABLENAME}
+	if err := db.Update(store.buildIndex); err != nil {
+		log.Fatalln(err)
+	}
+	return store
+}
+
+func boltKey(id, date string) []byte {
+	return []byte(date + "#" + id)
+}
+
+func indexKey(id, date string) []byte {
+	return []byte(id + "#" + date)
+}
+
+// buildIndex Fills WORKERINDEXBUCKET from the entries of a database written before it existed
+func (store *BoltWorkerInfoStore) buildIndex(tx *bolt.Tx) error {
+	index, err := tx.CreateBucketIfNotExists([]byte(WORKERINDEXBUCKET))
+	if err != nil {
+		return err
+	}
+	if key, _ := index.Cursor().First(); key != nil {
+		return nil
+	}
+	return tx.Bucket([]byte(store.BucketName)).ForEach(func(key, value []byte) error {
+		workerInfo := WorkerInfo{}
+		if err := json.Unmarshal(value, &workerInfo); err != nil {
+			return err
+		}
+		return index.Put(indexKey(workerInfo.Id, workerInfo.Date), key)
+	})
+}
+
+// GetWorkerInfo Seeks past the last Id#Date of the index and steps back once, that Date is the latest
+func (store *BoltWorkerInfoStore) GetWorkerInfo(id string) (WorkerInfo, error) {
+	workerInfo := WorkerInfo{Id: id}
+	prefix := []byte(id + "#")
+	err := store.DB.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket([]byte(WORKERINDEXBUCKET)).Cursor()
+		key, value := cursor.Seek([]byte(id + "#\xff"))
+		if key == nil {
+			key, value = cursor.Last()
+		} else {
+			key, value = cursor.Prev()
+		}
+		if key == nil || !bytes.HasPrefix(key, prefix) {
+			return util.NewStoreError(util.ErrNotFound, "No worker info for "+id)
+		}
+		item := tx.Bucket([]byte(store.BucketName)).Get(value)
+		if item == nil {
+			return util.NewStoreError(util.ErrNotFound, "No worker info for "+id)
+		}
+		return json.Unmarshal(item, &workerInfo)
+	})
+	if err != nil && !errors.Is(err, util.ErrNotFound) {
+		log.Printf("Couldn't get info about %v. Reason => : %v\n", id, err)
+	}
+	return workerInfo, err
+}
+
+// GetAllWorkerInfo Returns all worker info recorded btw start and end date
+func (store *BoltWorkerInfoStore) GetAllWorkerInfo(startDate, endDate string) ([]WorkerInfo, error) {
+	var workerInfoList []WorkerInfo
+	err := store.DB.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket([]byte(store.BucketName)).Cursor()
+		upper := []byte(endDate + "#\xff")
+		for key, value := cursor.Seek([]byte(startDate + "#")); key != nil && bytes.Compare(key, upper) <= 0; key, value = cursor.Next() {
+			workerInfo := WorkerInfo{}
+			if err := json.Unmarshal(value, &workerInfo); err != nil {
+				return err
+			}
+			workerInfoList = append(workerInfoList, workerInfo)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Couldn't read workerinfo between %v and %v. Here's why: %v\n", startDate, endDate, err)
+	}
+	return workerInfoList, err
+}
+
+// GetWorkerInfoPage Continues the cursor walk of GetAllWorkerInfo after the key after, boltKey and WorkerInfoCursor agree
+func (store *BoltWorkerInfoStore) GetWorkerInfoPage(startDate, endDate, after string, limit int) ([]WorkerInfo, string, error) {
+	var workerInfoList []WorkerInfo
+	next := ""
+	err := store.DB.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket([]byte(store.BucketName)).Cursor()
+		upper := []byte(endDate + "#\xff")
+		key, value := cursor.Seek([]byte(startDate + "#"))
+		if after != "" {
+			if key, value = cursor.Seek([]byte(after)); key != nil && string(key) == after {
+				key, value = cursor.Next()
+			}
+		}
+		for ; key != nil && bytes.Compare(key, upper) <= 0; key, value = cursor.Next() {
+			if limit > 0 && len(workerInfoList) == limit {
+				next = WorkerInfoCursor(&workerInfoList[limit-1])
+				return nil
+			}
+			workerInfo := WorkerInfo{}
+			if err := json.Unmarshal(value, &workerInfo); err != nil {
+				return err
+			}
+			workerInfoList = append(workerInfoList, workerInfo)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Couldn't read workerinfo between %v and %v. Here's why: %v\n", startDate, endDate, err)
+	}
+	return workerInfoList, next, err
+}
+
+func (store *BoltWorkerInfoStore) InsertWorkerInfo(workerInfo *WorkerInfo) error {
+	err := store.DB.Update(func(tx *bolt.Tx) error {
+		_, err := store.putLatest(tx, workerInfo)
+		return err
+	})
+	if err != nil {
+		log.Printf("Couldn't add item to local database. Reason => %v\n", err)
+	}
+	return err
+}
+
+// UpdateWorkerInfo Behaves like the conditional DynamoDB UpdateItem, util.ErrNotFound when the row is missing
+func (store *BoltWorkerInfoStore) UpdateWorkerInfo(workerInfo *WorkerInfo) (map[string]interface{}, error) {
+	var attributeMap map[string]interface{}
+	err := store.DB.Update(func(tx *bolt.Tx) error {
+		stored, err := store.update(tx, workerInfo)
+		if err != nil {
+			return err
+		}
+		attributeMap = updatedAttributes(&stored)
+		return nil
+	})
+	if err != nil && !errors.Is(err, util.ErrNotFound) {
+		log.Printf("Couldn't update workerInfo %v. Reason => %v\n", *workerInfo, err)
+	}
+	return attributeMap, err
+}
+
+func (store *BoltWorkerInfoStore) DeleteWorkerInfo(info WorkerInfo) error {
+	err := store.DB.Update(func(tx *bolt.Tx) error {
+		_, err := store.delete(tx, &info)
+		return err
+	})
+	if err != nil && !errors.Is(err, util.ErrNotFound) {
+		log.Printf("Couldn't delete %v from the local database. Here's why: %v\n", info, err)
+	}
+	return err
+}
+
+func (store *BoltWorkerInfoStore) InsertBatchWorkerInfo(workerInfoList []WorkerInfo) []error {
+	return insertEach(store, workerInfoList)
+}
+
+func (store *BoltWorkerInfoStore) InsertReading(reading *WorkerInfo) error {
+	err := store.DB.Update(func(tx *bolt.Tx) error {
+		return store.putReading(tx, reading)
+	})
+	if err != nil {
+		log.Printf("Couldn't add reading to local database. Reason => %v\n", err)
+	}
+	return err
+}
+
+func (store *BoltWorkerInfoStore) GetReadings(id, from, to string) ([]WorkerInfo, error) {
+	readingList := []WorkerInfo{}
+	err := store.DB.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket([]byte(READINGTABLENAME)).Cursor()
+		upper := []byte(id + "#" + to)
+		for key, value := cursor.Seek([]byte(id + "#" + from)); key != nil && bytes.Compare(key, upper) <= 0; key, value = cursor.Next() {
+			reading := WorkerInfo{}
+			if err := json.Unmarshal(value, &reading); err != nil {
+				return err
+			}
+			readingList = append(readingList, reading)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Couldn't read readings of %v between %v and %v. Here's why: %v\n", id, from, to, err)
+	}
+	return readingList, err
+}
+
+// put, update and delete work inside a caller owned transaction so other buckets can be written atomically
+
+func (store *BoltWorkerInfoStore) put(tx *bolt.Tx, workerInfo *WorkerInfo) error {
+	item, err := json.Marshal(workerInfo)
+	if err != nil {
+		return err
+	}
+	key := boltKey(workerInfo.Id, workerInfo.Date)
+	if err := tx.Bucket([]byte(WORKERINDEXBUCKET)).Put(indexKey(workerInfo.Id, workerInfo.Date), key); err != nil {
+		return err
+	}
+	return tx.Bucket([]byte(store.BucketName)).Put(key, item)
+}
+
+// putLatest Puts the day's record unless the stored one holds the same or a later reading, Returns whether it was put
+func (store *BoltWorkerInfoStore) putLatest(tx *bolt.Tx, workerInfo *WorkerInfo) (bool, error) {
+	if value := tx.Bucket([]byte(store.BucketName)).Get(boltKey(workerInfo.Id, workerInfo.Date)); value != nil {
+		stored := WorkerInfo{}
+		if err := json.Unmarshal(value, &stored); err != nil {
+			return false, err
+		}
+		if stored.Timestamp >= workerInfo.Timestamp {
+			return false, nil
+		}
+	}
+	return true, store.put(tx, workerInfo)
+}
+
+func (store *BoltWorkerInfoStore) update(tx *bolt.Tx, workerInfo *WorkerInfo) (WorkerInfo, error) {
+	stored := WorkerInfo{}
+	value := tx.Bucket([]byte(store.BucketName)).Get(boltKey(workerInfo.Id, workerInfo.Date))
+	if value == nil {
+		return stored, errNoRecord(workerInfo)
+	}
+	if err := json.Unmarshal(value, &stored); err != nil {
+		return stored, err
+	}
+	stored.Id = workerInfo.Id
+	stored.Date = workerInfo.Date
+	stored.Name = workerInfo.Name
+	stored.Spo2Level = workerInfo.Spo2Level
+	stored.Temperature = workerInfo.Temperature
+	stored.GasLevel = workerInfo.GasLevel
+	stored.GasType = workerInfo.GasType
+	stored.HeartRate = workerInfo.HeartRate
+	stored.DangerType = workerInfo.DangerType
+	stored.TreatedDoctor = workerInfo.TreatedDoctor
+	stored.EarlyWarningScore = workerInfo.EarlyWarningScore
+	stored.EarlyWarningRisk = workerInfo.EarlyWarningRisk
+
+	return stored, store.put(tx, &stored)
+}
+
+// delete Removes the row of info.Date, or every row of info.Id when Date is empty, and returns the Dates removed
+func (store *BoltWorkerInfoStore) delete(tx *bolt.Tx, info *WorkerInfo) ([]string, error) {
+	index := tx.Bucket([]byte(WORKERINDEXBUCKET))
+	var dates []string
+	if info.Date != "" {
+		if index.Get(indexKey(info.Id, info.Date)) != nil {
+			dates = append(dates, info.Date)
+		}
+	} else {
+		prefix := []byte(info.Id + "#")
+		cursor := index.Cursor()
+		for key, _ := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+			dates = append(dates, string(key[len(prefix):]))
+		}
+	}
+	if len(dates) == 0 {
+		return nil, errNoRecord(info)
+	}
+	for _, date := range dates {
+		if err := index.Delete(indexKey(info.Id, date)); err != nil {
+			return nil, err
+		}
+		if err := tx.Bucket([]byte(store.BucketName)).Delete(boltKey(info.Id, date)); err != nil {
+			return nil, err
+		}
+	}
+	return dates, nil
+}
+
+func (store *BoltWorkerInfoStore) putReading(tx *bolt.Tx, reading *WorkerInfo) error {
+	item, err := json.Marshal(reading)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket([]byte(READINGTABLENAME)).Put([]byte(reading.Id+"#"+reading.Timestamp), item)
+}
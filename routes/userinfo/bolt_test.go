@@ -0,0 +1,95 @@
+package userinfo
+
+import (
+	"errors"
+	"go_backend/util"
+	"path/filepath"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func openTestDB(t *testing.T) *bolt.DB {
+	t.Helper()
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestBoltGetWorkerInfoLatestDate(t *testing.T) {
+	store := NewBoltWorkerInfoStore(openTestDB(t))
+	for _, info := range []WorkerInfo{
+		{Id: "1_2", Date: "2024-01-01", Name: "old"},
+		{Id: "1_2", Date: "2024-01-03", Name: "latest"},
+		{Id: "1_2", Date: "2024-01-02", Name: "middle"},
+		{Id: "1_20", Date: "2024-01-09", Name: "other"},
+		{Id: "1_1", Date: "2024-01-09", Name: "other"},
+	} {
+		info := info
+		if err := store.InsertWorkerInfo(&info); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := store.GetWorkerInfo("1_2")
+	if err != nil || got.Name != "latest" {
+		t.Fatalf("GetWorkerInfo(1_2) = %v, %v, want the 2024-01-03 entry", got, err)
+	}
+
+	if err := store.DeleteWorkerInfo(WorkerInfo{Id: "1_2", Date: "2024-01-03"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = store.GetWorkerInfo("1_2"); err != nil || got.Name != "middle" {
+		t.Fatalf("GetWorkerInfo(1_2) after delete = %v, %v, want the 2024-01-02 entry", got, err)
+	}
+
+	for _, id := range []string{"1_3", "0", "9_9"} {
+		if _, err := store.GetWorkerInfo(id); !errors.Is(err, util.ErrNotFound) {
+			t.Errorf("GetWorkerInfo(%v) error = %v, want ErrNotFound", id, err)
+		}
+	}
+}
+
+func TestBoltIndexBackfill(t *testing.T) {
+	db := openTestDB(t)
+	store := NewBoltWorkerInfoStore(db)
+	info := WorkerInfo{Id: "1_2", Date: "2024-01-01", Name: "before index"}
+	if err := store.InsertWorkerInfo(&info); err != nil {
+		t.Fatal(err)
+	}
+	// A database written before the index bucket existed
+	if err := db.Update(func(tx *bolt.Tx) error { return tx.DeleteBucket([]byte(WORKERINDEXBUCKET)) }); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := NewBoltWorkerInfoStore(db).GetWorkerInfo("1_2")
+	if err != nil || got.Name != "before index" {
+		t.Fatalf("GetWorkerInfo after reopen = %v, %v", got, err)
+	}
+}
+
+func TestBoltGetReadingsRange(t *testing.T) {
+	store := NewBoltWorkerInfoStore(openTestDB(t))
+	for _, reading := range []WorkerInfo{
+		{Id: "1_2", Timestamp: "2024-01-01T10:00:00Z"},
+		{Id: "1_2", Timestamp: "2024-01-01T11:00:00Z"},
+		{Id: "1_2", Timestamp: "2024-01-01T12:00:00Z"},
+		{Id: "1_20", Timestamp: "2024-01-01T11:00:00Z"},
+	} {
+		reading := reading
+		if err := store.InsertReading(&reading); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := store.GetReadings("1_2", "2024-01-01T10:30:00Z", "2024-01-01T12:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Timestamp != "2024-01-01T11:00:00Z" || got[1].Timestamp != "2024-01-01T12:00:00Z" {
+		t.Fatalf("GetReadings = %v, want the 11:00 and 12:00 readings of 1_2", got)
+	}
+}
@@ -0,0 +1,56 @@
+package userinfo
+
+import (
+	"go_backend/util"
+	"log"
+	"time"
+)
+
+// Clock flags of a reading, set when the helmet's MeasuredAt couldn't be trusted and the receive time was used
+const (
+	ClockOK      = ""
+	ClockAhead   = "ahead"   // MeasuredAt is later than the server time plus CLOCK_SKEW_TOLERANCE
+	ClockBehind  = "behind"  // MeasuredAt is older than MAX_READING_AGE
+	ClockInvalid = "invalid" // MeasuredAt is not an RFC 3339 time
+)
+
+// measuredTime Picks the time a reading is filed under, the helmet's MeasuredAt when it is plausible
+// Readings buffered on the helmet keep their own time so they land on the day they were taken
+func (rawWorkInfo *RawWorkerInfo) measuredTime(receivedAt time.Time) (time.Time, string) {
+	if rawWorkInfo.MeasuredAt == "" {
+		return receivedAt, ClockOK
+	}
+	measuredAt, err := time.Parse(time.RFC3339Nano, rawWorkInfo.MeasuredAt)
+	if err != nil {
+		return receivedAt, ClockInvalid
+	}
+	if measuredAt.After(receivedAt.Add(util.GetClockSkewTolerance())) {
+		return receivedAt, ClockAhead
+	}
+	if receivedAt.Sub(measuredAt) > util.GetMaxReadingAge() {
+		return receivedAt, ClockBehind
+	}
+	return measuredAt, ClockOK
+}
+
+// fillTimes Sets Date (the day in the ground's time zone) and Timestamp from the measured time and records both clocks
+func (workerInfo *WorkerInfo) fillTimes(rawWorkInfo *RawWorkerInfo, receivedAt time.Time) {
+	measuredAt, flag := rawWorkInfo.measuredTime(receivedAt)
+	if flag != ClockOK {
+		log.Printf("Clock of helmet %v is %v (MeasuredAt %q), using the server time\n", workerInfo.Id, flag, rawWorkInfo.MeasuredAt)
+	}
+	workerInfo.Date = civilDate(measuredAt.In(GetLocation(workerInfo.Id)))
+	workerInfo.Timestamp = FormatTimestamp(measuredAt)
+	workerInfo.MeasuredAt = rawWorkInfo.MeasuredAt
+	workerInfo.ReceivedAt = FormatTimestamp(receivedAt)
+	workerInfo.ClockFlag = flag
+}
+
+// MeasuredTime Returns the time the reading is filed under (Timestamp)
+func (workerInfo *WorkerInfo) MeasuredTime() time.Time {
+	measuredAt, err := time.Parse(TimestampLayout, workerInfo.Timestamp)
+	if err != nil {
+		return time.Now()
+	}
+	return measuredAt
+}
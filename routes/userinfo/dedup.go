@@ -0,0 +1,216 @@
+package userinfo
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go_backend/util"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// IdempotencyHeader may carry the IdempotencyKey of a single reading
+const IdempotencyHeader = "Idempotency-Key"
+
+// ReplayedHeader is set on responses to a request that was already processed
+const ReplayedHeader = "Idempotent-Replayed"
+
+// MaxGapsPerHelmet bounds the open gaps remembered for one helmet, the oldest are dropped first
+const MaxGapsPerHelmet = 100
+
+// DedupKey Identifies a reading across retries, "" when the helmet sent neither a key nor a sequence number
+// A helmet counts from 1 again after a reboot, so a sequence number is paired with MeasuredAt, or with the values
+// read when the helmet has no clock, for a reused number to be told from a retry
+func (rawWorkInfo *RawWorkerInfo) DedupKey() string {
+	id := rawWorkInfo.GroundNumber + "_" + rawWorkInfo.HelmetNumber
+	if rawWorkInfo.IdempotencyKey != "" {
+		return id + "|key|" + rawWorkInfo.IdempotencyKey
+	}
+	if rawWorkInfo.Sequence == 0 {
+		return ""
+	}
+	key := id + "|seq|" + strconv.FormatUint(rawWorkInfo.Sequence, 10)
+	if rawWorkInfo.MeasuredAt != "" {
+		return key + "|at|" + rawWorkInfo.MeasuredAt
+	}
+	return key + fmt.Sprintf("|read|%v,%v,%v,%v,%v,%v", rawWorkInfo.Spo2Level, rawWorkInfo.Temperature, rawWorkInfo.GasLevel,
+		rawWorkInfo.GasType, rawWorkInfo.HeartRate, rawWorkInfo.DangerType)
+}
+
+// ReadIdempotencyHeader Fills IdempotencyKey from the request header when the body has none
+func (rawWorkInfo *RawWorkerInfo) ReadIdempotencyHeader(ctx *gin.Context) {
+	if rawWorkInfo.IdempotencyKey == "" {
+		rawWorkInfo.IdempotencyKey = ctx.GetHeader(IdempotencyHeader)
+	}
+}
+
+type dedupEntry struct {
+	seenAt time.Time
+	result interface{}
+}
+
+// Deduplicator remembers the keys processed within a window, and what was answered for them
+type Deduplicator struct {
+	mu       sync.Mutex
+	window   time.Duration
+	entries  map[string]*dedupEntry
+	prunedAt time.Time
+}
+
+func NewDeduplicator(window time.Duration) *Deduplicator {
+	return &Deduplicator{window: window, entries: make(map[string]*dedupEntry)}
+}
+
+// Claim Reserves key for processing, a key seen within the window returns false with the result stored by Complete
+// (nil while the first request is still being processed). An empty key is never a duplicate
+func (dedup *Deduplicator) Claim(key string, now time.Time) (interface{}, bool) {
+	if key == "" {
+		return nil, true
+	}
+	dedup.mu.Lock()
+	defer dedup.mu.Unlock()
+
+	if now.Sub(dedup.prunedAt) > time.Minute {
+		for entryKey, entry := range dedup.entries {
+			if now.Sub(entry.seenAt) > dedup.window {
+				delete(dedup.entries, entryKey)
+			}
+		}
+		dedup.prunedAt = now
+	}
+	if entry, isFound := dedup.entries[key]; isFound && now.Sub(entry.seenAt) <= dedup.window {
+		return entry.result, false
+	}
+	dedup.entries[key] = &dedupEntry{seenAt: now}
+	return nil, true
+}
+
+// Complete Stores the result replayed to later duplicates of key
+func (dedup *Deduplicator) Complete(key string, result interface{}) {
+	dedup.mu.Lock()
+	defer dedup.mu.Unlock()
+	if entry, isFound := dedup.entries[key]; isFound {
+		entry.result = result
+	}
+}
+
+// Release Forgets a claimed key whose processing failed so a retry is processed again
+func (dedup *Deduplicator) Release(key string) {
+	dedup.mu.Lock()
+	defer dedup.mu.Unlock()
+	delete(dedup.entries, key)
+}
+
+// Gap is a run of sequence numbers a helmet never delivered
+type Gap struct {
+	Id         string
+	From       uint64 // First missing sequence number
+	To         uint64 // Last missing sequence number
+	DetectedAt time.Time
+}
+
+// SequenceTracker follows the sequence numbers of every helmet and records the gaps between them
+type SequenceTracker struct {
+	mu   sync.Mutex
+	last map[string]uint64
+	gaps map[string][]Gap
+}
+
+func NewSequenceTracker() *SequenceTracker {
+	return &SequenceTracker{last: make(map[string]uint64), gaps: make(map[string][]Gap)}
+}
+
+// Record Notes a delivered sequence number, a late reading closes (part of) the gap it was missing from
+// A number below the last one that isn't in a gap means the helmet restarted its counter
+func (tracker *SequenceTracker) Record(id string, sequence uint64, at time.Time) {
+	if sequence == 0 {
+		return
+	}
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	last, isFound := tracker.last[id]
+	switch {
+	case !isFound || sequence == last+1:
+		tracker.last[id] = sequence
+	case sequence > last+1:
+		gap := Gap{Id: id, From: last + 1, To: sequence - 1, DetectedAt: at}
+		log.Printf("Helmet %v skipped sequence %v to %v\n", id, gap.From, gap.To)
+		tracker.gaps[id] = append(tracker.gaps[id], gap)
+		if len(tracker.gaps[id]) > MaxGapsPerHelmet {
+			tracker.gaps[id] = tracker.gaps[id][1:]
+		}
+		tracker.last[id] = sequence
+	default:
+		if !tracker.fill(id, sequence) {
+			tracker.last[id] = sequence
+		}
+	}
+}
+
+// fill Removes sequence from the gap holding it, splitting the gap when needed
+func (tracker *SequenceTracker) fill(id string, sequence uint64) bool {
+	gaps := tracker.gaps[id]
+	for i, gap := range gaps {
+		if sequence < gap.From || sequence > gap.To {
+			continue
+		}
+		var rest []Gap
+		if sequence > gap.From {
+			rest = append(rest, Gap{Id: id, From: gap.From, To: sequence - 1, DetectedAt: gap.DetectedAt})
+		}
+		if sequence < gap.To {
+			rest = append(rest, Gap{Id: id, From: sequence + 1, To: gap.To, DetectedAt: gap.DetectedAt})
+		}
+		tracker.gaps[id] = append(append(append([]Gap{}, gaps[:i]...), rest...), gaps[i+1:]...)
+		return true
+	}
+	return false
+}
+
+// Gaps Returns the open gaps of one helmet, or of every helmet when id is empty
+func (tracker *SequenceTracker) Gaps(id string) []Gap {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	gapList := []Gap{}
+	for helmetId, gaps := range tracker.gaps {
+		if id == "" || id == helmetId {
+			gapList = append(gapList, gaps...)
+		}
+	}
+	sort.Slice(gapList, func(i, j int) bool {
+		if gapList[i].Id != gapList[j].Id {
+			return gapList[i].Id < gapList[j].Id
+		}
+		return gapList[i].From < gapList[j].From
+	})
+	return gapList
+}
+
+var ingestDedup *Deduplicator
+var sequences *SequenceTracker
+
+func init() {
+	ingestDedup = NewDeduplicator(util.GetDedupWindow())
+	sequences = NewSequenceTracker()
+}
+
+// TrackSequence Feeds a reading's sequence number to the gap detector shared by every ingestion route
+func TrackSequence(workerInfo *WorkerInfo, at time.Time) {
+	sequences.Record(workerInfo.Id, workerInfo.Sequence, at)
+}
+
+// ListGaps Returns the open gaps of one helmet, or of every helmet when id is empty
+func ListGaps(id string) []Gap {
+	return sequences.Gaps(id)
+}
+
+// GetGaps Lists missing sequence numbers, Id query param selects one helmet
+func GetGaps(ctx *gin.Context) {
+	util.DebugPrint(FILENAME, "GET GAPS", "Just Test")
+	ctx.JSON(http.StatusOK, ListGaps(ctx.Query("Id")))
+}
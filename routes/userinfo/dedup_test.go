@@ -0,0 +1,187 @@
+package userinfo
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDedupKey(t *testing.T) {
+	tests := []struct {
+		raw  RawWorkerInfo
+		want string
+	}{
+		{RawWorkerInfo{GroundNumber: "1", HelmetNumber: "2"}, ""},
+		{RawWorkerInfo{GroundNumber: "1", HelmetNumber: "2", Sequence: 7}, "1_2|seq|7|read|0,0,0,,0,"},
+		{RawWorkerInfo{GroundNumber: "1", HelmetNumber: "2", Sequence: 7, Spo2Level: 97, GasType: "CO", DangerType: "SOS"},
+			"1_2|seq|7|read|97,0,0,CO,0,SOS"},
+		{RawWorkerInfo{GroundNumber: "1", HelmetNumber: "2", Sequence: 7, MeasuredAt: "2024-01-01T10:00:00Z", Spo2Level: 97},
+			"1_2|seq|7|at|2024-01-01T10:00:00Z"},
+		{RawWorkerInfo{GroundNumber: "1", HelmetNumber: "2", Sequence: 7, IdempotencyKey: "abc"}, "1_2|key|abc"},
+	}
+	for _, test := range tests {
+		if got := test.raw.DedupKey(); got != test.want {
+			t.Errorf("DedupKey(%+v) = %q, want %q", test.raw, got, test.want)
+		}
+	}
+}
+
+func TestDeduplicator(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	dedup := NewDeduplicator(10 * time.Minute)
+
+	if _, isNew := dedup.Claim("", now); !isNew {
+		t.Error("an empty key must never be a duplicate")
+	}
+	if _, isNew := dedup.Claim("", now); !isNew {
+		t.Error("an empty key must never be a duplicate")
+	}
+
+	if _, isNew := dedup.Claim("a", now); !isNew {
+		t.Fatal("first claim of a is a duplicate")
+	}
+	if result, isNew := dedup.Claim("a", now.Add(time.Second)); isNew || result != nil {
+		t.Errorf("claim while processing = %v, %v, want a duplicate without result", result, isNew)
+	}
+	dedup.Complete("a", "answer")
+	if result, isNew := dedup.Claim("a", now.Add(10*time.Minute)); isNew || result != "answer" {
+		t.Errorf("claim within the window = %v, %v, want the stored answer", result, isNew)
+	}
+	if _, isNew := dedup.Claim("a", now.Add(10*time.Minute+time.Second)); !isNew {
+		t.Error("a key older than the window is still a duplicate")
+	}
+
+	dedup.Claim("b", now)
+	dedup.Release("b")
+	if _, isNew := dedup.Claim("b", now); !isNew {
+		t.Error("a released key must be processed again")
+	}
+}
+
+func TestDeduplicatorPrunes(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	dedup := NewDeduplicator(time.Minute)
+	for i := 0; i < 10; i++ {
+		dedup.Claim(fmt.Sprint(i), now)
+	}
+	dedup.Claim("late", now.Add(2*time.Minute))
+	if len(dedup.entries) != 1 {
+		t.Errorf("%v entries kept after the window, want only the new one", len(dedup.entries))
+	}
+}
+
+func gapRanges(gaps []Gap) string {
+	var ranges []string
+	for _, gap := range gaps {
+		ranges = append(ranges, fmt.Sprintf("%v:%v-%v", gap.Id, gap.From, gap.To))
+	}
+	return strings.Join(ranges, " ")
+}
+
+func TestSequenceTrackerGaps(t *testing.T) {
+	at := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	tracker := NewSequenceTracker()
+	for _, sequence := range []uint64{1, 2, 0, 3, 8, 9, 12} {
+		tracker.Record("1_2", sequence, at)
+	}
+	if got := gapRanges(tracker.Gaps("1_2")); got != "1_2:4-7 1_2:10-11" {
+		t.Fatalf("gaps = %v", got)
+	}
+
+	// Late readings close the gap they were missing from, splitting it when they land inside
+	tracker.Record("1_2", 5, at)
+	tracker.Record("1_2", 4, at)
+	tracker.Record("1_2", 10, at)
+	if got := gapRanges(tracker.Gaps("1_2")); got != "1_2:6-7 1_2:11-11" {
+		t.Errorf("gaps after late readings = %v", got)
+	}
+
+	// A restarted counter starts over without a gap, the next skip is measured from there
+	tracker.Record("1_2", 1, at)
+	tracker.Record("1_2", 3, at)
+	if got := gapRanges(tracker.Gaps("1_2")); got != "1_2:2-2 1_2:6-7 1_2:11-11" {
+		t.Errorf("gaps after a restart = %v", got)
+	}
+
+	tracker.Record("1_1", 1, at)
+	tracker.Record("1_1", 3, at)
+	if got := gapRanges(tracker.Gaps("")); got != "1_1:2-2 1_2:2-2 1_2:6-7 1_2:11-11" {
+		t.Errorf("every helmet's gaps = %v", got)
+	}
+	if gaps := tracker.Gaps("9_9"); gaps == nil || len(gaps) != 0 {
+		t.Errorf("unknown helmet gaps = %#v, want an empty list", gaps)
+	}
+}
+
+func TestSequenceTrackerBoundsGaps(t *testing.T) {
+	at := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	tracker := NewSequenceTracker()
+	for i := 0; i <= MaxGapsPerHelmet+5; i++ {
+		tracker.Record("1_2", uint64(2*i+1), at)
+	}
+	gaps := tracker.Gaps("1_2")
+	if len(gaps) != MaxGapsPerHelmet || gaps[0].From != 12 {
+		t.Errorf("kept %v gaps from %v, want the newest %v", len(gaps), gaps[0].From, MaxGapsPerHelmet)
+	}
+}
+
+func TestPostReplaysRetriedReading(t *testing.T) {
+	store := useMemoryStore(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/userinfo", Post)
+
+	post := func() *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/userinfo", strings.NewReader(`{"GroundNumber":"8","HelmetNumber":"4"}`))
+		request.Header.Set(IdempotencyHeader, "retry-me")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+	if first := post(); first.Code != http.StatusOK || first.Header().Get(ReplayedHeader) != "" {
+		t.Fatalf("first POST = %v %v", first.Code, first.Header())
+	}
+	if retry := post(); retry.Code != http.StatusOK || retry.Header().Get(ReplayedHeader) != "true" {
+		t.Errorf("retried POST = %v %v, want a replayed acknowledgement", retry.Code, retry.Header())
+	}
+	readings, _ := store.GetReadings("8_4", "", "\xff")
+	if len(readings) != 1 {
+		t.Errorf("stored %v readings, want the retry dropped", len(readings))
+	}
+}
+
+// A helmet rebooting inside DEDUP_WINDOW counts from 1 again, its readings aren't retries
+func TestIngestAfterHelmetReboot(t *testing.T) {
+	store := useMemoryStore(t)
+	ingest := func(raw RawWorkerInfo) bool {
+		raw.GroundNumber, raw.HelmetNumber = "8", "5"
+		_, isNew, err := Ingest(&raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return isNew
+	}
+	now := time.Now().UTC()
+	before := RawWorkerInfo{Sequence: 1, MeasuredAt: now.Add(-2 * time.Minute).Format(time.RFC3339), HeartRate: 80}
+	after := RawWorkerInfo{Sequence: 1, MeasuredAt: now.Format(time.RFC3339), HeartRate: 80}
+	if !ingest(before) || ingest(before) {
+		t.Fatal("the retry of sequence 1 wasn't dropped")
+	}
+	if !ingest(after) {
+		t.Error("sequence 1 after the reboot was dropped as a retry")
+	}
+	// Without a clock, the values read tell the readings apart
+	if !ingest(RawWorkerInfo{Sequence: 2, HeartRate: 80}) || !ingest(RawWorkerInfo{Sequence: 2, HeartRate: 95}) ||
+		ingest(RawWorkerInfo{Sequence: 2, HeartRate: 95}) {
+		t.Error("clockless readings reusing sequence 2 weren't told from their retry")
+	}
+	readings, _ := store.GetReadings("8_5", "", "\xff")
+	if len(readings) != 4 {
+		t.Errorf("stored %v readings, want 4", len(readings))
+	}
+}
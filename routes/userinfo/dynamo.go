@@ -0,0 +1,670 @@
+package userinfo
+
+import (
+	"cloud.google.com/go/civil"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	"go_backend/util"
+	"log"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DynamoDBAPI is the part of *dynamodb.Client the store calls, tests swap in a fake
+type DynamoDBAPI interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
+}
+
+// TClientUserInfo is the DynamoDB backed WorkerInfoStore
+type TClientUserInfo struct {
+	DynamoDbClient   DynamoDBAPI
+	TableName        string
+	ReadingTableName string
+	indexMissingAt   atomic.Int64 // Unix time DATEINDEX was last found missing or backfilling, 0 while it works
+}
+
+func NewTClientUserInfo() *TClientUserInfo {
+	return &TClientUserInfo{DynamoDbClient: GetClientFromEnv(), TableName: TABLENAME, ReadingTableName: READINGTABLENAME}
+}
+
+func GetClientFromEnv() *dynamodb.Client {
+	cfg, err := config.LoadDefaultConfig(context.Background(), func(l *config.LoadOptions) error {
+		return nil
+	})
+
+	if err != nil {
+		log.Fatalf("Couldn't load the AWS config. Reason => %v\n", err)
+	}
+
+	client := dynamodb.NewFromConfig(cfg)
+	return client
+}
+
+// GetWorkerInfo Queries the Id partition backwards for the entry of the latest Date
+func (tClient *TClientUserInfo) GetWorkerInfo(id string) (WorkerInfo, error) {
+	workerInfo := WorkerInfo{Id: id}
+	keyEx := expression.Key("Id").Equal(expression.Value(id))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyEx).WithProjection(workerInfoProjection()).Build()
+	if err != nil {
+		log.Printf("Couldn't build expression for query. Here's why: %v\n", err)
+		return workerInfo, err
+	}
+	response, err := tClient.DynamoDbClient.Query(context.Background(), &dynamodb.QueryInput{
+		TableName:                 aws.String(tClient.TableName),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ProjectionExpression:      expr.Projection(),
+		ScanIndexForward:          aws.Bool(false),
+		Limit:                     aws.Int32(1),
+	})
+	if err != nil {
+		log.Printf("Couldn't get info about %v. Reason => : %v\n", id, err)
+		return workerInfo, util.ClassifyStoreError(err)
+	}
+	if len(response.Items) == 0 {
+		return workerInfo, util.NewStoreError(util.ErrNotFound, "No worker info for "+id)
+	}
+	err = attributevalue.UnmarshalMap(response.Items[0], &workerInfo)
+	if err != nil {
+		log.Printf("Couldn't unmarshal response. Reason => : %v\n", err)
+	}
+	return workerInfo, err
+}
+
+// GetAllWorkerInfo Returns all worker info recorded btw start and end date
+// Each date is read with a Query on DATEINDEX, the table is scanned when the index is missing
+func (tClient *TClientUserInfo) GetAllWorkerInfo(startDate, endDate string) ([]WorkerInfo, error) {
+	dates, isValid := datesBetween(startDate, endDate)
+	if !isValid || !tClient.useDateIndex() {
+		return tClient.scanWorkerInfo(startDate, endDate)
+	}
+
+	workerInfoList := []WorkerInfo{}
+	for _, date := range dates {
+		page, err := tClient.queryDate(date)
+		if isIndexMissing(err) {
+			log.Printf("Index %v not usable on %v, falling back to Scan. Reason => %v\n", DATEINDEX, tClient.TableName, err)
+			tClient.indexMissingAt.Store(time.Now().Unix())
+			return tClient.scanWorkerInfo(startDate, endDate)
+		}
+		if err != nil {
+			log.Printf("Couldn't query workerinfo on %v. Here's why: %v\n", date, err)
+			return workerInfoList, util.ClassifyStoreError(err)
+		}
+		workerInfoList = append(workerInfoList, page...)
+	}
+	return workerInfoList, nil
+}
+
+// GetWorkerInfoPage Queries the date partitions in order, Ids within a date come sorted from the index
+// Without the index the range is scanned whole and paged in memory
+func (tClient *TClientUserInfo) GetWorkerInfoPage(startDate, endDate, after string, limit int) ([]WorkerInfo, string, error) {
+	dates, isValid := datesBetween(startDate, endDate)
+	if !isValid || !tClient.useDateIndex() {
+		return tClient.scanWorkerInfoPage(startDate, endDate, after, limit)
+	}
+
+	afterDate, afterId, _ := strings.Cut(after, "#")
+	workerInfoList := []WorkerInfo{}
+	for _, date := range dates {
+		if after != "" && date < afterDate {
+			continue
+		}
+		var startKey map[string]types.AttributeValue
+		if date == afterDate {
+			startKey = (&WorkerInfo{Id: afterId, Date: afterDate}).GetKey()
+		}
+		for {
+			remaining := 0
+			if limit > 0 {
+				remaining = limit - len(workerInfoList)
+			}
+			page, lastKey, err := tClient.queryDatePage(date, startKey, remaining)
+			if isIndexMissing(err) {
+				log.Printf("Index %v not usable on %v, falling back to Scan. Reason => %v\n", DATEINDEX, tClient.TableName, err)
+				tClient.indexMissingAt.Store(time.Now().Unix())
+				return tClient.scanWorkerInfoPage(startDate, endDate, after, limit)
+			}
+			if err != nil {
+				log.Printf("Couldn't query workerinfo on %v. Here's why: %v\n", date, err)
+				return workerInfoList, "", util.ClassifyStoreError(err)
+			}
+			workerInfoList = append(workerInfoList, page...)
+			if limit > 0 && len(workerInfoList) >= limit {
+				// The next page may turn out empty, DynamoDB can't tell whether more items follow
+				return workerInfoList, WorkerInfoCursor(&workerInfoList[len(workerInfoList)-1]), nil
+			}
+			if len(lastKey) == 0 {
+				break
+			}
+			startKey = lastKey
+		}
+	}
+	return workerInfoList, "", nil
+}
+
+func (tClient *TClientUserInfo) scanWorkerInfoPage(startDate, endDate, after string, limit int) ([]WorkerInfo, string, error) {
+	workerInfoList, err := tClient.scanWorkerInfo(startDate, endDate)
+	if err != nil {
+		return workerInfoList, "", err
+	}
+	sort.Slice(workerInfoList, func(i, j int) bool {
+		return WorkerInfoCursor(&workerInfoList[i]) < WorkerInfoCursor(&workerInfoList[j])
+	})
+	page, next := util.PageSorted(workerInfoList, WorkerInfoCursor, after, limit)
+	return page, next, nil
+}
+
+// DATEINDEX is the global secondary index on Date (hash) and Id (range), see the table package
+const DATEINDEX string = "DateIndex"
+
+// MaxIndexDays is the widest range read one date partition at a time, wider ranges are scanned
+const MaxIndexDays = 92
+
+// DateIndexRetry is how long Scan is used after the index was found missing or backfilling before trying it again
+const DateIndexRetry = 10 * time.Minute
+
+func (tClient *TClientUserInfo) useDateIndex() bool {
+	missingAt := tClient.indexMissingAt.Load()
+	return missingAt == 0 || time.Since(time.Unix(missingAt, 0)) > DateIndexRetry
+}
+
+// datesBetween Lists every date from start to end, false when either is not a date or the range is too wide
+func datesBetween(startDate, endDate string) ([]string, bool) {
+	start, err := civil.ParseDate(startDate)
+	if err != nil {
+		return nil, false
+	}
+	end, err := civil.ParseDate(endDate)
+	if err != nil || end.DaysSince(start) >= MaxIndexDays {
+		return nil, false
+	}
+	var dates []string
+	for date := start; !date.After(end); date = date.AddDays(1) {
+		dates = append(dates, date.String())
+	}
+	return dates, true
+}
+
+// isIndexMissing Reports whether a Query failed because the table has no such index,
+// or has it but can't serve it yet because it is still backfilling after being added
+func isIndexMissing(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return true
+	}
+	message := strings.ToLower(apiErr.ErrorMessage())
+	return apiErr.ErrorCode() == "ValidationException" &&
+		(strings.Contains(message, "specified index") || strings.Contains(message, "backfill"))
+}
+
+func (tClient *TClientUserInfo) queryDate(date string) ([]WorkerInfo, error) {
+	var workerInfoList []WorkerInfo
+	var startKey map[string]types.AttributeValue
+	for {
+		page, lastKey, err := tClient.queryDatePage(date, startKey, 0)
+		workerInfoList = append(workerInfoList, page...)
+		if err != nil || len(lastKey) == 0 {
+			return workerInfoList, err
+		}
+		startKey = lastKey
+	}
+}
+
+// queryDatePage Runs one Query on DATEINDEX for a date, limit 0 reads up to DynamoDB's 1 MB page size
+func (tClient *TClientUserInfo) queryDatePage(date string, startKey map[string]types.AttributeValue, limit int) ([]WorkerInfo, map[string]types.AttributeValue, error) {
+	var page []WorkerInfo
+	keyEx := expression.Key("Date").Equal(expression.Value(date))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyEx).WithProjection(workerInfoProjection()).Build()
+	if err != nil {
+		return page, nil, err
+	}
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(tClient.TableName),
+		IndexName:                 aws.String(DATEINDEX),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ProjectionExpression:      expr.Projection(),
+		ExclusiveStartKey:         startKey,
+	}
+	if limit > 0 {
+		input.Limit = aws.Int32(int32(limit))
+	}
+	response, err := tClient.DynamoDbClient.Query(context.Background(), input)
+	if err != nil {
+		return page, nil, err
+	}
+	err = attributevalue.UnmarshalListOfMaps(response.Items, &page)
+	return page, response.LastEvaluatedKey, err
+}
+
+// scanWorkerInfo Scans the whole table with a Between filter on Date, following every page
+func (tClient *TClientUserInfo) scanWorkerInfo(startDate, endDate string) ([]WorkerInfo, error) {
+	workerInfoList := []WorkerInfo{}
+	filtExpre := expression.Name("Date").Between(expression.Value(startDate), expression.Value(endDate))
+	expr, err := expression.NewBuilder().WithFilter(filtExpre).WithProjection(workerInfoProjection()).Build()
+	if err != nil {
+		log.Printf("Couldn't build expressions for scan. Here's why: %v\n", err)
+		return workerInfoList, err
+	}
+
+	var startKey map[string]types.AttributeValue
+	for {
+		response, err := tClient.DynamoDbClient.Scan(context.Background(), &dynamodb.ScanInput{
+			TableName:                 aws.String(tClient.TableName),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+			FilterExpression:          expr.Filter(),
+			ProjectionExpression:      expr.Projection(),
+			ExclusiveStartKey:         startKey,
+		})
+		if err != nil {
+			log.Printf("Couldn't scan for workerinfo between %v and %v. Here's why: %v\n",
+				startDate, endDate, err)
+			return workerInfoList, util.ClassifyStoreError(err)
+		}
+		var page []WorkerInfo
+		if err = attributevalue.UnmarshalListOfMaps(response.Items, &page); err != nil {
+			log.Printf("Couldn't unmarshal query response. Here's why: %v\n", err)
+			return workerInfoList, err
+		}
+		workerInfoList = append(workerInfoList, page...)
+		if len(response.LastEvaluatedKey) == 0 {
+			return workerInfoList, nil
+		}
+		startKey = response.LastEvaluatedKey
+	}
+}
+
+func workerInfoProjection() expression.ProjectionBuilder {
+	return expression.NamesList(
+		expression.Name("Id"), expression.Name("Date"), expression.Name("TreatedDoctor"),
+		expression.Name("Name"), expression.Name("Spo2Level"), expression.Name("GasLevel"),
+		expression.Name("Temperature"), expression.Name("DangerType"), expression.Name("HeartRate"),
+		expression.Name("EarlyWarningScore"), expression.Name("EarlyWarningRisk"), expression.Name("GasType"),
+		expression.Name("Timestamp"), expression.Name("Sequence"), expression.Name("MeasuredAt"),
+		expression.Name("ReceivedAt"), expression.Name("ClockFlag"))
+}
+
+// InsertWorkerInfo Puts the day's row unless the stored one holds the same or a later reading
+// Buffered readings arrive out of order, an older one is skipped without error (its reading is still appended)
+func (tClient *TClientUserInfo) InsertWorkerInfo(workerInfo *WorkerInfo) error {
+	timestamp := expression.Name("Timestamp")
+	err := tClient.putIf(workerInfo, expression.Name("Id").AttributeNotExists().
+		Or(timestamp.AttributeNotExists(), timestamp.LessThan(expression.Value(workerInfo.Timestamp))))
+	if errors.Is(err, ErrSuperseded) {
+		return nil
+	}
+	return err
+}
+
+// ErrSuperseded is returned by ReplaceWorkerInfo when the table holds a row of a later reading
+var ErrSuperseded = errors.New("superseded by a newer row")
+
+// ReplaceWorkerInfo Puts the row unless the stored one has a later Timestamp
+// An equal Timestamp is written, it's the same reading edited (UpdateWorkerInfo keeps the Timestamp)
+func (tClient *TClientUserInfo) ReplaceWorkerInfo(workerInfo *WorkerInfo) error {
+	timestamp := expression.Name("Timestamp")
+	return tClient.putIf(workerInfo, expression.Name("Id").AttributeNotExists().
+		Or(timestamp.AttributeNotExists(), timestamp.LessThanEqual(expression.Value(workerInfo.Timestamp))))
+}
+
+// putIf Puts the row when condition holds for the stored one, ErrSuperseded when it doesn't
+func (tClient *TClientUserInfo) putIf(workerInfo *WorkerInfo, condition expression.ConditionBuilder) error {
+	item, err := attributevalue.MarshalMap(workerInfo)
+	if err != nil {
+		return err
+	}
+	expr, err := expression.NewBuilder().WithCondition(condition).Build()
+	if err != nil {
+		log.Printf("Couldn't build expression for put. Here's why: %v\n", err)
+		return err
+	}
+	_, err = tClient.DynamoDbClient.PutItem(context.Background(), &dynamodb.PutItemInput{
+		TableName:                 aws.String(tClient.TableName),
+		Item:                      item,
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return ErrSuperseded
+	}
+	if err != nil {
+		log.Printf("Couldn't add workerInfo %v to table. Reason => %v\n", *workerInfo, err)
+	}
+	return util.ClassifyStoreError(err)
+}
+
+func (tClient *TClientUserInfo) InsertReading(reading *WorkerInfo) error {
+	item, err := attributevalue.MarshalMap(reading)
+	if err != nil {
+		return err
+	}
+	_, err = tClient.DynamoDbClient.PutItem(context.Background(), &dynamodb.PutItemInput{
+		TableName: aws.String(tClient.ReadingTableName), Item: item,
+	})
+	if err != nil {
+		log.Printf("Couldn't add reading to table. Reason => %v\n", err)
+	}
+	return util.ClassifyStoreError(err)
+}
+
+// GetReadings Queries the Id partition of the readings table, following LastEvaluatedKey until the range is read
+func (tClient *TClientUserInfo) GetReadings(id, from, to string) ([]WorkerInfo, error) {
+	readingList := []WorkerInfo{}
+	keyEx := expression.Key("Id").Equal(expression.Value(id)).
+		And(expression.Key("Timestamp").Between(expression.Value(from), expression.Value(to)))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyEx).Build()
+	if err != nil {
+		log.Printf("Couldn't build expression for query. Here's why: %v\n", err)
+		return readingList, err
+	}
+
+	var startKey map[string]types.AttributeValue
+	for {
+		response, err := tClient.DynamoDbClient.Query(context.Background(), &dynamodb.QueryInput{
+			TableName:                 aws.String(tClient.ReadingTableName),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+			KeyConditionExpression:    expr.KeyCondition(),
+			ExclusiveStartKey:         startKey,
+		})
+		if err != nil {
+			log.Printf("Couldn't query readings of %v. Here's why: %v\n", id, err)
+			return readingList, util.ClassifyStoreError(err)
+		}
+		var page []WorkerInfo
+		if err = attributevalue.UnmarshalListOfMaps(response.Items, &page); err != nil {
+			log.Printf("Couldn't unmarshal query response. Here's why: %v\n", err)
+			return readingList, err
+		}
+		readingList = append(readingList, page...)
+		if len(response.LastEvaluatedKey) == 0 {
+			return readingList, nil
+		}
+		startKey = response.LastEvaluatedKey
+	}
+}
+
+// DeleteWorkerInfo Deletes the row of info.Date, or every row of the Id partition when Date is empty
+// Each DeleteItem is conditional so a missing row is reported instead of silently succeeding
+func (tClient *TClientUserInfo) DeleteWorkerInfo(info WorkerInfo) error {
+	dates := []string{info.Date}
+	if info.Date == "" {
+		var err error
+		if dates, err = tClient.workerDates(info.Id); err != nil {
+			return err
+		}
+	}
+	deleted := 0
+	for _, date := range dates {
+		err := tClient.deleteRow(WorkerInfo{Id: info.Id, Date: date})
+		if errors.Is(err, util.ErrNotFound) {
+			continue // Deleted meanwhile
+		}
+		if err != nil {
+			return err
+		}
+		deleted++
+	}
+	if deleted == 0 {
+		return errNoRecord(&info)
+	}
+	return nil
+}
+
+// workerDates Queries the Dates of every row of an Id, following LastEvaluatedKey
+func (tClient *TClientUserInfo) workerDates(id string) ([]string, error) {
+	keyEx := expression.Key("Id").Equal(expression.Value(id))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyEx).WithProjection(expression.NamesList(expression.Name("Date"))).Build()
+	if err != nil {
+		return nil, err
+	}
+	var dates []string
+	var startKey map[string]types.AttributeValue
+	for {
+		response, err := tClient.DynamoDbClient.Query(context.Background(), &dynamodb.QueryInput{
+			TableName:                 aws.String(tClient.TableName),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+			KeyConditionExpression:    expr.KeyCondition(),
+			ProjectionExpression:      expr.Projection(),
+			ExclusiveStartKey:         startKey,
+		})
+		if err != nil {
+			log.Printf("Couldn't list the rows of %v. Reason => %v\n", id, err)
+			return nil, util.ClassifyStoreError(err)
+		}
+		for _, item := range response.Items {
+			workerInfo := WorkerInfo{}
+			if err = attributevalue.UnmarshalMap(item, &workerInfo); err != nil {
+				return nil, err
+			}
+			dates = append(dates, workerInfo.Date)
+		}
+		if startKey = response.LastEvaluatedKey; len(startKey) == 0 {
+			return dates, nil
+		}
+	}
+}
+
+func (tClient *TClientUserInfo) deleteRow(info WorkerInfo) error {
+	expr, err := expression.NewBuilder().WithCondition(expression.AttributeExists(expression.Name("Id"))).Build()
+	if err != nil {
+		return err
+	}
+	_, err = tClient.DynamoDbClient.DeleteItem(context.Background(), &dynamodb.DeleteItemInput{
+		TableName:                aws.String(tClient.TableName),
+		Key:                      info.GetKey(),
+		ConditionExpression:      expr.Condition(),
+		ExpressionAttributeNames: expr.Names(),
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return errNoRecord(&info)
+	}
+	if err != nil {
+		log.Printf("Couldn't delete %v from the table. Here's why: %v\n", info, err)
+	}
+	return util.ClassifyStoreError(err)
+}
+
+func (tClient *TClientUserInfo) UpdateWorkerInfo(workerInfo *WorkerInfo) (map[string]interface{}, error) {
+	var err error
+	var response *dynamodb.UpdateItemOutput
+	var attributeMap map[string]interface{}
+
+	update := expression.Set(expression.Name("Name"), expression.Value(workerInfo.Name))
+	update.Set(expression.Name("Spo2Level"), expression.Value(workerInfo.Spo2Level))
+	update.Set(expression.Name("Temperature"), expression.Value(workerInfo.Temperature))
+	update.Set(expression.Name("GasLevel"), expression.Value(workerInfo.GasLevel))
+	update.Set(expression.Name("GasType"), expression.Value(workerInfo.GasType))
+	update.Set(expression.Name("HeartRate"), expression.Value(workerInfo.HeartRate))
+	update.Set(expression.Name("DangerType"), expression.Value(workerInfo.DangerType))
+	update.Set(expression.Name("TreatedDoctor"), expression.Value(workerInfo.TreatedDoctor))
+	update.Set(expression.Name("EarlyWarningScore"), expression.Value(workerInfo.EarlyWarningScore))
+	update.Set(expression.Name("EarlyWarningRisk"), expression.Value(workerInfo.EarlyWarningRisk))
+
+	// UpdateItem would create a missing row holding only these attributes
+	expr, err := expression.NewBuilder().WithUpdate(update).
+		WithCondition(expression.AttributeExists(expression.Name("Id"))).Build()
+	if err != nil {
+		log.Printf("Couldn't build expression for update. Here's why: %v\n", err)
+	} else {
+		response, err = tClient.DynamoDbClient.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
+			TableName:                 aws.String(tClient.TableName),
+			Key:                       workerInfo.GetKey(),
+			ConditionExpression:       expr.Condition(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+			UpdateExpression:          expr.Update(),
+			ReturnValues:              types.ReturnValueUpdatedNew,
+		})
+		var conditionFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionFailed) {
+			err = errNoRecord(workerInfo)
+		} else if err != nil {
+			log.Printf("Couldn't update workerInfo %v. Reason => %v\n", *workerInfo, err)
+			err = util.ClassifyStoreError(err)
+		} else {
+			err = attributevalue.UnmarshalMap(response.Attributes, &attributeMap)
+			if err != nil {
+				log.Printf("Couldn't unmarshall update response. Reason => %v\n", err)
+			}
+		}
+	}
+	return attributeMap, err
+}
+
+// BatchSize is the maximum number of items DynamoDB accepts in one BatchWriteItem call
+const BatchSize = 25
+
+// BatchRetries is how many times UnprocessedItems are resent before giving up
+const BatchRetries = 5
+
+// batchBackoff is the wait before the first resend, doubled for each later one
+var batchBackoff = 100 * time.Millisecond
+
+// WriteBatch Sends write requests with BatchWriteItem in chunks of BatchSize,
+// UnprocessedItems are retried with exponential backoff
+// Requests in one call must not share a key
+func (tClient *TClientUserInfo) WriteBatch(tableName string, writeReqs []types.WriteRequest) error {
+	for _, err := range tClient.WriteBatchItems(tableName, writeReqs) {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ErrUnprocessed is reported for requests DynamoDB still left unprocessed after BatchRetries
+var ErrUnprocessed = fmt.Errorf("unprocessed after %v retries", BatchRetries)
+
+// WriteBatchItems Works like WriteBatch but reports the outcome of every request, nil when it was written
+func (tClient *TClientUserInfo) WriteBatchItems(tableName string, writeReqs []types.WriteRequest) []error {
+	errs := make([]error, len(writeReqs))
+	for start := 0; start < len(writeReqs); start += BatchSize {
+		end := start + BatchSize
+		if end > len(writeReqs) {
+			end = len(writeReqs)
+		}
+
+		pending := map[string][]types.WriteRequest{tableName: writeReqs[start:end]}
+		backoff := batchBackoff
+		for attempt := 0; len(pending) > 0; attempt++ {
+			if attempt > BatchRetries {
+				markUnprocessed(errs[start:end], writeReqs[start:end], pending[tableName], ErrUnprocessed)
+				break
+			}
+			if attempt > 0 {
+				time.Sleep(backoff)
+				backoff *= 2
+			}
+
+			response, err := tClient.DynamoDbClient.BatchWriteItem(context.Background(), &dynamodb.BatchWriteItemInput{
+				RequestItems: pending})
+			if err != nil {
+				log.Printf("Couldn't add a batch of workerInfo to %v. Here's why: %v\n", tableName, err)
+				markUnprocessed(errs[start:end], writeReqs[start:end], pending[tableName], util.ClassifyStoreError(err))
+				break
+			}
+			pending = response.UnprocessedItems
+		}
+	}
+	return errs
+}
+
+// markUnprocessed Sets err for every request of chunk still in unprocessed, requests are matched on their item key
+func markUnprocessed(errs []error, chunk, unprocessed []types.WriteRequest, err error) {
+	remaining := make(map[string]int)
+	for _, writeReq := range unprocessed {
+		remaining[writeRequestKey(writeReq)]++
+	}
+	for i, writeReq := range chunk {
+		if key := writeRequestKey(writeReq); remaining[key] > 0 {
+			remaining[key]--
+			errs[i] = err
+		}
+	}
+}
+
+// writeRequestKey Identifies a request by the Id, Date and Timestamp it carries, enough for both WorkerInfo tables
+func writeRequestKey(writeReq types.WriteRequest) string {
+	item := map[string]types.AttributeValue{}
+	if writeReq.PutRequest != nil {
+		item = writeReq.PutRequest.Item
+	} else if writeReq.DeleteRequest != nil {
+		item = writeReq.DeleteRequest.Key
+	}
+	key := ""
+	for _, name := range []string{"Id", "Date", "Timestamp"} {
+		if value, isFound := item[name].(*types.AttributeValueMemberS); isFound {
+			key += value.Value
+		}
+		key += "#"
+	}
+	return key
+}
+
+// InsertBatchWorkerInfo Writes every reading to READINGTABLENAME, then puts the newest reading of each Id + Date
+// to the WorkerInfo table with InsertWorkerInfo, BatchWriteItem can't skip rows holding a later reading
+// errs holds the outcome of each reading (nil when it was stored)
+func (tClient *TClientUserInfo) InsertBatchWorkerInfo(workerInfoList []WorkerInfo) []error {
+	errs := make([]error, len(workerInfoList))
+	var readingReqs []types.WriteRequest
+	var readingOwners, latestOwners []int
+	latest := make(map[string]int) // Id#Date -> index in latestOwners
+	for i := range workerInfoList {
+		item, err := attributevalue.MarshalMap(workerInfoList[i])
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		readingReqs = append(readingReqs, types.WriteRequest{PutRequest: &types.PutRequest{Item: item}})
+		readingOwners = append(readingOwners, i)
+
+		key := workerInfoList[i].Id + "#" + workerInfoList[i].Date
+		if at, isFound := latest[key]; isFound {
+			if workerInfoList[i].Timestamp > workerInfoList[latestOwners[at]].Timestamp {
+				latestOwners[at] = i
+			}
+			continue
+		}
+		latest[key] = len(latestOwners)
+		latestOwners = append(latestOwners, i)
+	}
+
+	for at, err := range tClient.WriteBatchItems(tClient.ReadingTableName, readingReqs) {
+		errs[readingOwners[at]] = err
+	}
+	for _, owner := range latestOwners {
+		if errs[owner] == nil {
+			errs[owner] = tClient.InsertWorkerInfo(&workerInfoList[owner])
+		}
+	}
+	return errs
+}
@@ -0,0 +1,289 @@
+package userinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_backend/util"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+)
+
+// fakeDynamo stands in for DynamoDB, only the calls a test makes are implemented
+type fakeDynamo struct {
+	DynamoDBAPI
+	mu       sync.Mutex
+	items    []WorkerInfo
+	indexErr error // Query on DATEINDEX fails with it
+	queries  int
+	scans    int
+
+	batchErr    error                                                          // BatchWriteItem fails with it
+	unprocessed func(call int, reqs []types.WriteRequest) []types.WriteRequest // Requests BatchWriteItem leaves unprocessed
+	batches     []int                                                          // Requests sent in each BatchWriteItem call
+	written     map[string][]WorkerInfo                                        // Items put per table
+}
+
+func newFakeStore(fake *fakeDynamo) *TClientUserInfo {
+	return &TClientUserInfo{DynamoDbClient: fake, TableName: TABLENAME, ReadingTableName: READINGTABLENAME}
+}
+
+// PutItem Honours the Timestamp conditions of InsertWorkerInfo (<) and ReplaceWorkerInfo (<=)
+func (fake *fakeDynamo) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	item := WorkerInfo{}
+	if err := attributevalue.UnmarshalMap(params.Item, &item); err != nil {
+		return nil, err
+	}
+	if fake.written == nil {
+		fake.written = make(map[string][]WorkerInfo)
+	}
+	table := *params.TableName
+	if params.ConditionExpression != nil {
+		isEqualAllowed := strings.Contains(*params.ConditionExpression, "<=")
+		for _, stored := range fake.written[table] {
+			if stored.Id != item.Id || stored.Date != item.Date || stored.Timestamp == "" {
+				continue
+			}
+			if stored.Timestamp > item.Timestamp || stored.Timestamp == item.Timestamp && !isEqualAllowed {
+				return nil, &types.ConditionalCheckFailedException{}
+			}
+		}
+	}
+	fake.written[table] = append(fake.written[table], item)
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (fake *fakeDynamo) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.queries++
+	if params.IndexName != nil && fake.indexErr != nil {
+		return nil, fake.indexErr
+	}
+	output := &dynamodb.QueryOutput{}
+	if params.IndexName == nil { // The Id partition of the table, as written
+		id := params.ExpressionAttributeValues[":0"].(*types.AttributeValueMemberS).Value
+		for _, item := range fake.written[*params.TableName] {
+			if item.Id == id {
+				marshalled, _ := attributevalue.MarshalMap(item)
+				output.Items = append(output.Items, marshalled)
+			}
+		}
+		return output, nil
+	}
+	date := params.ExpressionAttributeValues[":0"].(*types.AttributeValueMemberS).Value
+	for _, item := range fake.items {
+		if item.Date == date {
+			marshalled, _ := attributevalue.MarshalMap(item)
+			output.Items = append(output.Items, marshalled)
+		}
+	}
+	return output, nil
+}
+
+func (fake *fakeDynamo) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.scans++
+	output := &dynamodb.ScanOutput{}
+	for _, item := range fake.items {
+		marshalled, _ := attributevalue.MarshalMap(item)
+		output.Items = append(output.Items, marshalled)
+	}
+	return output, nil
+}
+
+func TestIsIndexMissing(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("connection reset"), false},
+		{&types.ResourceNotFoundException{Message: new(string)}, true},
+		{&smithy.GenericAPIError{Code: "ValidationException", Message: "The table does not have the specified index: DateIndex"}, true},
+		{&smithy.GenericAPIError{Code: "ValidationException", Message: "The index DateIndex cannot be used while it is being backfilled"}, true},
+		{&smithy.GenericAPIError{Code: "ValidationException", Message: "Cannot read from backfilling global secondary index: DateIndex"}, true},
+		{&smithy.GenericAPIError{Code: "ValidationException", Message: "One or more parameter values were invalid"}, false},
+		{&smithy.GenericAPIError{Code: "ThrottlingException", Message: "backfill"}, false},
+		{fmt.Errorf("operation Query: %w", &smithy.GenericAPIError{Code: "ValidationException", Message: "being backfilled"}), true},
+	}
+	for _, test := range tests {
+		if got := isIndexMissing(test.err); got != test.want {
+			t.Errorf("isIndexMissing(%v) = %v, want %v", test.err, got, test.want)
+		}
+	}
+}
+
+func TestGetAllWorkerInfoScansWhileIndexBackfills(t *testing.T) {
+	fake := &fakeDynamo{
+		items:    []WorkerInfo{{Id: "1_2", Date: "2024-01-01"}, {Id: "1_3", Date: "2024-01-02"}},
+		indexErr: &smithy.GenericAPIError{Code: "ValidationException", Message: "The index DateIndex cannot be used while it is being backfilled"},
+	}
+	store := newFakeStore(fake)
+
+	workerInfoList, err := store.GetAllWorkerInfo("2024-01-01", "2024-01-02")
+	if err != nil || len(workerInfoList) != 2 {
+		t.Fatalf("GetAllWorkerInfo = %v, %v, want both items from the Scan", workerInfoList, err)
+	}
+	if fake.queries != 1 || fake.scans != 1 {
+		t.Errorf("queries %v, scans %v, want 1 and 1", fake.queries, fake.scans)
+	}
+
+	// Within DateIndexRetry the index isn't tried again
+	if _, _, err = store.GetWorkerInfoPage("2024-01-01", "2024-01-02", "", 10); err != nil {
+		t.Fatal(err)
+	}
+	if fake.queries != 1 || fake.scans != 2 {
+		t.Errorf("queries %v, scans %v after the fallback, want 1 and 2", fake.queries, fake.scans)
+	}
+}
+
+func TestGetAllWorkerInfoUsesIndex(t *testing.T) {
+	fake := &fakeDynamo{items: []WorkerInfo{{Id: "1_2", Date: "2024-01-01"}, {Id: "1_3", Date: "2024-01-02"}, {Id: "1_4", Date: "2024-01-03"}}}
+	workerInfoList, err := newFakeStore(fake).GetAllWorkerInfo("2024-01-01", "2024-01-02")
+	if err != nil || len(workerInfoList) != 2 || fake.queries != 2 || fake.scans != 0 {
+		t.Errorf("GetAllWorkerInfo = %v, %v with %v queries and %v scans, want 2 items from 2 queries", workerInfoList, err, fake.queries, fake.scans)
+	}
+}
+
+func TestInsertWorkerInfoOnlyMovesForward(t *testing.T) {
+	fake := &fakeDynamo{}
+	store := newFakeStore(fake)
+	for _, timestamp := range []string{"2024-01-01T11:00:00Z", "2024-01-01T10:00:00Z", "2024-01-01T11:00:00Z", "2024-01-01T12:00:00Z"} {
+		if err := store.InsertWorkerInfo(&WorkerInfo{Id: "1_2", Date: "2024-01-01", Timestamp: timestamp}); err != nil {
+			t.Fatalf("InsertWorkerInfo(%v) error = %v, a skipped row isn't an error", timestamp, err)
+		}
+	}
+	rows := fake.written[TABLENAME]
+	if len(rows) != 2 || rows[0].Timestamp != "2024-01-01T11:00:00Z" || rows[1].Timestamp != "2024-01-01T12:00:00Z" {
+		t.Errorf("rows put = %+v, want only the 11:00 and 12:00 readings", rows)
+	}
+
+	// An edited row keeps its Timestamp, the edge syncer replays it with ReplaceWorkerInfo
+	if err := store.ReplaceWorkerInfo(&WorkerInfo{Id: "1_2", Date: "2024-01-01", Timestamp: "2024-01-01T12:00:00Z", Name: "A"}); err != nil {
+		t.Errorf("ReplaceWorkerInfo of the same reading error = %v", err)
+	}
+	if err := store.ReplaceWorkerInfo(&WorkerInfo{Id: "1_2", Date: "2024-01-01", Timestamp: "2024-01-01T09:00:00Z"}); !errors.Is(err, ErrSuperseded) {
+		t.Errorf("ReplaceWorkerInfo of an older reading error = %v, want ErrSuperseded", err)
+	}
+}
+
+func TestInsertWorkerInfoCondition(t *testing.T) {
+	var condition string
+	var names map[string]string
+	fake := &conditionRecorder{record: func(params *dynamodb.PutItemInput) {
+		condition, names = *params.ConditionExpression, params.ExpressionAttributeNames
+	}}
+	store := &TClientUserInfo{DynamoDbClient: fake, TableName: TABLENAME}
+	store.InsertWorkerInfo(&WorkerInfo{Id: "1_2", Date: "2024-01-01", Timestamp: "2024-01-01T10:00:00Z"})
+	for placeholder, name := range names {
+		condition = strings.ReplaceAll(condition, placeholder, name)
+	}
+	want := "(attribute_not_exists (Id)) OR (attribute_not_exists (Timestamp)) OR (Timestamp < :0)"
+	if condition != want {
+		t.Errorf("condition = %v, want %v", condition, want)
+	}
+}
+
+// UpdateItem Honours the attribute_exists condition of UpdateWorkerInfo, the update itself is not applied
+func (fake *fakeDynamo) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	key := WorkerInfo{}
+	if err := attributevalue.UnmarshalMap(params.Key, &key); err != nil {
+		return nil, err
+	}
+	condition := *params.ConditionExpression
+	for placeholder, name := range params.ExpressionAttributeNames {
+		condition = strings.ReplaceAll(condition, placeholder, name)
+	}
+	if condition != "attribute_exists (Id)" {
+		return nil, fmt.Errorf("unexpected condition %v", condition)
+	}
+	for _, stored := range fake.written[*params.TableName] {
+		if stored.Id == key.Id && stored.Date == key.Date {
+			return &dynamodb.UpdateItemOutput{}, nil
+		}
+	}
+	return nil, &types.ConditionalCheckFailedException{}
+}
+
+func TestUpdateWorkerInfoOnlyExistingRow(t *testing.T) {
+	fake := &fakeDynamo{}
+	store := newFakeStore(fake)
+	missing := &WorkerInfo{Id: "1_2", Date: "2024-01-01", Name: "Ravi"}
+	if _, err := store.UpdateWorkerInfo(missing); !errors.Is(err, util.ErrNotFound) || util.StoreErrorStatus(err) != http.StatusNotFound {
+		t.Errorf("update of a missing row error = %v, want ErrNotFound", err)
+	}
+
+	store.InsertWorkerInfo(&WorkerInfo{Id: "1_2", Date: "2024-01-01", Timestamp: "2024-01-01T10:00:00Z"})
+	if _, err := store.UpdateWorkerInfo(missing); err != nil {
+		t.Errorf("update of a stored row error = %v", err)
+	}
+}
+
+// DeleteItem Honours the attribute_exists condition of DeleteWorkerInfo against the written rows
+func (fake *fakeDynamo) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	key := WorkerInfo{}
+	if err := attributevalue.UnmarshalMap(params.Key, &key); err != nil {
+		return nil, err
+	}
+	if params.ConditionExpression == nil {
+		return nil, errors.New("DeleteItem without a condition")
+	}
+	table := *params.TableName
+	for i, stored := range fake.written[table] {
+		if stored.Id == key.Id && stored.Date == key.Date {
+			fake.written[table] = append(fake.written[table][:i], fake.written[table][i+1:]...)
+			return &dynamodb.DeleteItemOutput{}, nil
+		}
+	}
+	return nil, &types.ConditionalCheckFailedException{}
+}
+
+func TestDeleteWorkerInfoOnlyExistingRows(t *testing.T) {
+	fake := &fakeDynamo{}
+	store := newFakeStore(fake)
+	if err := store.DeleteWorkerInfo(WorkerInfo{Id: "1_2", Date: "2024-01-01"}); !errors.Is(err, util.ErrNotFound) {
+		t.Errorf("delete of a missing row error = %v, want ErrNotFound", err)
+	}
+	if err := store.DeleteWorkerInfo(WorkerInfo{Id: "1_2"}); !errors.Is(err, util.ErrNotFound) {
+		t.Errorf("delete of a missing worker error = %v, want ErrNotFound", err)
+	}
+
+	for _, date := range []string{"2024-01-01", "2024-01-02", "2024-01-03"} {
+		store.InsertWorkerInfo(&WorkerInfo{Id: "1_2", Date: date, Timestamp: date + "T10:00:00Z"})
+	}
+	store.InsertWorkerInfo(&WorkerInfo{Id: "1_3", Date: "2024-01-01", Timestamp: "2024-01-01T10:00:00Z"})
+	if err := store.DeleteWorkerInfo(WorkerInfo{Id: "1_2", Date: "2024-01-02"}); err != nil {
+		t.Errorf("delete of a stored row error = %v", err)
+	}
+	if err := store.DeleteWorkerInfo(WorkerInfo{Id: "1_2"}); err != nil {
+		t.Errorf("delete of every row error = %v", err)
+	}
+	if rows := fake.written[TABLENAME]; len(rows) != 1 || rows[0].Id != "1_3" {
+		t.Errorf("rows left = %+v, want only 1_3", rows)
+	}
+}
+
+type conditionRecorder struct {
+	DynamoDBAPI
+	record func(params *dynamodb.PutItemInput)
+}
+
+func (fake *conditionRecorder) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	fake.record(params)
+	return &dynamodb.PutItemOutput{}, nil
+}
@@ -0,0 +1,225 @@
+package userinfo
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"go_backend/util"
+	"log"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const JOURNALBUCKET string = "WorkerInfoJournal"
+const DEADLETTERBUCKET string = "WorkerInfoDeadLetter" // Journal entries the central table kept rejecting
+
+// Journal operations, replayed against the central table by the edge syncer
+const (
+	JournalPut    = "PUT"
+	JournalDelete = "DELETE"
+)
+
+// JournalEntry is one local write waiting to be forwarded to the central tables
+type JournalEntry struct {
+	Seq       uint64
+	Operation string // PUT or DELETE
+	Table     string // TABLENAME or READINGTABLENAME, empty in entries written before readings existed
+	Item      WorkerInfo
+	Site      string // GroundNumber part of Item.Id
+	QueuedAt  time.Time
+	Attempts  int // Uploads rejected by the central table so far
+}
+
+// EdgeWorkerInfoStore writes to the embedded database and records every write in a durable outbound journal
+// Reads are served locally, the journal is drained by the edgesync package when connectivity returns
+type EdgeWorkerInfoStore struct {
+	*BoltWorkerInfoStore
+}
+
+func NewEdgeWorkerInfoStore(db *bolt.DB) *EdgeWorkerInfoStore {
+	util.CreateBucket(db, JOURNALBUCKET)
+	util.CreateBucket(db, DEADLETTERBUCKET)
+	return &EdgeWorkerInfoStore{BoltWorkerInfoStore: NewBoltWorkerInfoStore(db)}
+}
+
+// GetSite Returns the ground number encoded in a WorkerInfo Id
+func GetSite(id string) string {
+	site, _, _ := strings.Cut(id, "_")
+	return site
+}
+
+func journalKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
+
+func (store *EdgeWorkerInfoStore) appendJournal(tx *bolt.Tx, table string, operation string, item WorkerInfo) error {
+	bucket := tx.Bucket([]byte(JOURNALBUCKET))
+	seq, err := bucket.NextSequence()
+	if err != nil {
+		return err
+	}
+	entry, err := json.Marshal(JournalEntry{
+		Seq: seq, Operation: operation, Table: table, Item: item, Site: GetSite(item.Id), QueuedAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	return bucket.Put(journalKey(seq), entry)
+}
+
+func (store *EdgeWorkerInfoStore) InsertWorkerInfo(workerInfo *WorkerInfo) error {
+	err := store.DB.Update(func(tx *bolt.Tx) error {
+		isPut, err := store.putLatest(tx, workerInfo)
+		if err != nil || !isPut {
+			return err
+		}
+		return store.appendJournal(tx, TABLENAME, JournalPut, *workerInfo)
+	})
+	if err != nil {
+		log.Printf("Couldn't add item to local database. Reason => %v\n", err)
+	}
+	return err
+}
+
+func (store *EdgeWorkerInfoStore) UpdateWorkerInfo(workerInfo *WorkerInfo) (map[string]interface{}, error) {
+	var attributeMap map[string]interface{}
+	err := store.DB.Update(func(tx *bolt.Tx) error {
+		stored, err := store.update(tx, workerInfo)
+		if err != nil {
+			return err
+		}
+		attributeMap = updatedAttributes(&stored)
+		// The full item is journaled so the central table ends up with the same row as the edge
+		return store.appendJournal(tx, TABLENAME, JournalPut, stored)
+	})
+	if err != nil && !errors.Is(err, util.ErrNotFound) {
+		log.Printf("Couldn't update workerInfo %v. Reason => %v\n", *workerInfo, err)
+	}
+	return attributeMap, err
+}
+
+func (store *EdgeWorkerInfoStore) DeleteWorkerInfo(info WorkerInfo) error {
+	err := store.DB.Update(func(tx *bolt.Tx) error {
+		dates, err := store.delete(tx, &info)
+		if err != nil {
+			return err
+		}
+		// One entry per row, the central table needs the Date of each key
+		for _, date := range dates {
+			if err = store.appendJournal(tx, TABLENAME, JournalDelete, WorkerInfo{Id: info.Id, Date: date}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, util.ErrNotFound) {
+		log.Printf("Couldn't delete %v from the local database. Here's why: %v\n", info, err)
+	}
+	return err
+}
+
+func (store *EdgeWorkerInfoStore) InsertBatchWorkerInfo(workerInfoList []WorkerInfo) []error {
+	return insertEach(store, workerInfoList)
+}
+
+func (store *EdgeWorkerInfoStore) InsertReading(reading *WorkerInfo) error {
+	err := store.DB.Update(func(tx *bolt.Tx) error {
+		if err := store.putReading(tx, reading); err != nil {
+			return err
+		}
+		return store.appendJournal(tx, READINGTABLENAME, JournalPut, *reading)
+	})
+	if err != nil {
+		log.Printf("Couldn't add reading to local database. Reason => %v\n", err)
+	}
+	return err
+}
+
+// PendingJournal Returns up to limit journal entries, oldest first (limit <= 0 returns everything)
+func (store *EdgeWorkerInfoStore) PendingJournal(limit int) ([]JournalEntry, error) {
+	var entries []JournalEntry
+	err := store.DB.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket([]byte(JOURNALBUCKET)).Cursor()
+		for key, value := cursor.First(); key != nil && (limit <= 0 || len(entries) < limit); key, value = cursor.Next() {
+			entry := JournalEntry{}
+			if err := json.Unmarshal(value, &entry); err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// AckJournal Removes entries that reached the central table
+func (store *EdgeWorkerInfoStore) AckJournal(seqs []uint64) error {
+	return store.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(JOURNALBUCKET))
+		for _, seq := range seqs {
+			if err := bucket.Delete(journalKey(seq)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// FailJournal Counts a rejected upload of each entry, entries rejected maxAttempts times are moved
+// to DEADLETTERBUCKET so they stop holding up the journal. Returns how many were moved
+func (store *EdgeWorkerInfoStore) FailJournal(seqs []uint64, maxAttempts int) (int, error) {
+	moved := 0
+	err := store.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(JOURNALBUCKET))
+		for _, seq := range seqs {
+			value := bucket.Get(journalKey(seq))
+			if value == nil {
+				continue
+			}
+			entry := JournalEntry{}
+			if err := json.Unmarshal(value, &entry); err != nil {
+				return err
+			}
+			entry.Attempts++
+			value, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			if entry.Attempts < maxAttempts {
+				if err := bucket.Put(journalKey(seq), value); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := tx.Bucket([]byte(DEADLETTERBUCKET)).Put(journalKey(seq), value); err != nil {
+				return err
+			}
+			if err := bucket.Delete(journalKey(seq)); err != nil {
+				return err
+			}
+			moved++
+		}
+		return nil
+	})
+	return moved, err
+}
+
+// DeadLetters Returns the entries moved out of the journal by FailJournal, oldest first
+func (store *EdgeWorkerInfoStore) DeadLetters() ([]JournalEntry, error) {
+	var entries []JournalEntry
+	err := store.DB.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(DEADLETTERBUCKET)).ForEach(func(key, value []byte) error {
+			entry := JournalEntry{}
+			if err := json.Unmarshal(value, &entry); err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+			return nil
+		})
+	})
+	return entries, err
+}
@@ -0,0 +1,124 @@
+package userinfo
+
+import (
+	"fmt"
+	"go_backend/telemetry"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxFrameBody bounds what is read from a telemetry.ContentType request body
+const maxFrameBody = 256
+
+// IsFrame Reports whether the request body is a binary frame rather than JSON
+func IsFrame(ctx *gin.Context) bool {
+	return ctx.ContentType() == telemetry.ContentType
+}
+
+// ReadFrame Decodes the single frame of kind sent as the request body
+func ReadFrame(ctx *gin.Context, kind byte) (*RawWorkerInfo, error) {
+	data, err := io.ReadAll(io.LimitReader(ctx.Request.Body, maxFrameBody+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxFrameBody {
+		return nil, fmt.Errorf("frame body is over %v bytes", maxFrameBody)
+	}
+	frame := telemetry.Frame{}
+	if err = frame.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	if frame.Kind != kind {
+		return nil, fmt.Errorf("%w %v on this route, expected %v", telemetry.ErrKind, frame.Kind, kind)
+	}
+	return FromFrame(&frame)
+}
+
+// FromFrame Converts a decoded frame into the RawWorkerInfo the JSON routes take
+// A danger frame without a DangerType is an SOS
+func FromFrame(frame *telemetry.Frame) (*RawWorkerInfo, error) {
+	gasType, isKnown := frame.GasName()
+	if !isKnown {
+		return nil, fmt.Errorf("unknown gas type code %v", frame.GasType)
+	}
+	dangerType, isKnown := frame.DangerName()
+	if !isKnown {
+		return nil, fmt.Errorf("unknown danger type code %v", frame.DangerType)
+	}
+	if frame.Kind == telemetry.KindDanger && frame.DangerType == 0 {
+		dangerType = telemetry.DangerTypes[telemetry.DangerSOS]
+	}
+
+	rawWorkInfo := &RawWorkerInfo{
+		GroundNumber: strconv.Itoa(int(frame.GroundNumber)),
+		HelmetNumber: strconv.Itoa(int(frame.HelmetNumber)),
+		Spo2Level:    int16(frame.Spo2Level),
+		Temperature:  int32(frame.Temperature),
+		GasLevel:     int32(frame.GasLevel),
+		GasType:      gasType,
+		HeartRate:    int32(frame.HeartRate),
+		DangerType:   dangerType,
+		Sequence:     uint64(frame.Sequence),
+	}
+	if measuredAt := frame.MeasuredTime(); !measuredAt.IsZero() {
+		rawWorkInfo.MeasuredAt = measuredAt.Format(time.RFC3339)
+	}
+	return rawWorkInfo, nil
+}
+
+// ToFrame Encodes a reading as a frame of kind, fields that don't fit the fixed widths are an error
+// IdempotencyKey can't be carried, frames are deduplicated by Sequence
+func (rawWorkInfo *RawWorkerInfo) ToFrame(kind byte) (telemetry.Frame, error) {
+	frame := telemetry.Frame{Version: telemetry.Version1, Kind: kind}
+	ground, err := strconv.ParseUint(rawWorkInfo.GroundNumber, 10, 16)
+	if err != nil {
+		return frame, fmt.Errorf("GroundNumber must be a number up to %v", math.MaxUint16)
+	}
+	helmet, err := strconv.ParseUint(rawWorkInfo.HelmetNumber, 10, 16)
+	if err != nil {
+		return frame, fmt.Errorf("HelmetNumber must be a number up to %v", math.MaxUint16)
+	}
+	if rawWorkInfo.Sequence > math.MaxUint32 {
+		return frame, fmt.Errorf("Sequence is over %v", uint32(math.MaxUint32))
+	}
+	switch {
+	case rawWorkInfo.Spo2Level < 0 || rawWorkInfo.Spo2Level > math.MaxUint8:
+		return frame, fmt.Errorf("Spo2Level %v doesn't fit a byte", rawWorkInfo.Spo2Level)
+	case rawWorkInfo.Temperature < math.MinInt16 || rawWorkInfo.Temperature > math.MaxInt16:
+		return frame, fmt.Errorf("Temperature %v doesn't fit 16 bits", rawWorkInfo.Temperature)
+	case rawWorkInfo.GasLevel < 0 || rawWorkInfo.GasLevel > math.MaxUint16:
+		return frame, fmt.Errorf("GasLevel %v doesn't fit 16 bits", rawWorkInfo.GasLevel)
+	case rawWorkInfo.HeartRate < 0 || rawWorkInfo.HeartRate > math.MaxUint8:
+		return frame, fmt.Errorf("HeartRate %v doesn't fit a byte", rawWorkInfo.HeartRate)
+	}
+	gasType, isKnown := telemetry.GasCode(rawWorkInfo.GasType)
+	if !isKnown {
+		return frame, fmt.Errorf("gas type %v has no frame code", rawWorkInfo.GasType)
+	}
+	dangerType, isKnown := telemetry.DangerCode(rawWorkInfo.DangerType)
+	if !isKnown {
+		return frame, fmt.Errorf("danger type %v has no frame code", rawWorkInfo.DangerType)
+	}
+	if rawWorkInfo.MeasuredAt != "" {
+		measuredAt, err := time.Parse(time.RFC3339, rawWorkInfo.MeasuredAt)
+		if err != nil || measuredAt.Unix() <= 0 || measuredAt.Unix() > math.MaxUint32 {
+			return frame, fmt.Errorf("MeasuredAt %v can't be sent as unix seconds", rawWorkInfo.MeasuredAt)
+		}
+		frame.MeasuredAt = uint32(measuredAt.Unix())
+	}
+
+	frame.GroundNumber = uint16(ground)
+	frame.HelmetNumber = uint16(helmet)
+	frame.Sequence = uint32(rawWorkInfo.Sequence)
+	frame.Spo2Level = uint8(rawWorkInfo.Spo2Level)
+	frame.Temperature = int16(rawWorkInfo.Temperature)
+	frame.GasLevel = uint16(rawWorkInfo.GasLevel)
+	frame.GasType = gasType
+	frame.HeartRate = uint8(rawWorkInfo.HeartRate)
+	frame.DangerType = dangerType
+	return frame, nil
+}
@@ -0,0 +1,43 @@
+package userinfo
+
+import (
+	"sync"
+	"time"
+)
+
+// IngestHook is called with every reading received from a helmet, at is when the reading was taken
+type IngestHook func(workerInfo *WorkerInfo, at time.Time)
+
+var hooksMu sync.RWMutex
+var ingestHooks []IngestHook
+
+// AddIngestHook Registers a hook run after each reading is ingested, Used by the rules engine
+func AddIngestHook(hook IngestHook) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	ingestHooks = append(ingestHooks, hook)
+}
+
+// RunIngestHooks Hands a reading to every registered hook
+func RunIngestHooks(workerInfo *WorkerInfo, at time.Time) {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	for _, hook := range ingestHooks {
+		hook(workerInfo, at)
+	}
+}
+
+// GetField Returns a numeric vital sign by its WorkerInfo field name
+func (workerInfo *WorkerInfo) GetField(name string) (float64, bool) {
+	switch name {
+	case "Spo2Level":
+		return float64(workerInfo.Spo2Level), true
+	case "Temperature":
+		return float64(workerInfo.Temperature), true
+	case "GasLevel":
+		return float64(workerInfo.GasLevel), true
+	case "HeartRate":
+		return float64(workerInfo.HeartRate), true
+	}
+	return 0, false
+}
@@ -2,52 +2,24 @@ package userinfo
 
 import (
 	"cloud.google.com/go/civil"
-	"context"
-	"fmt"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"errors"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/gin-gonic/gin"
+	"go_backend/telemetry"
 	"go_backend/util"
-	"log"
+	"math"
 	"net/http"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
 )
 
 const TABLENAME string = "WorkerInfo"
+const READINGTABLENAME string = "WorkerReading" // Every reading, keyed by Id + Timestamp
 
-var tClient *TClientUserInfo
-
-type TClientUserInfo struct {
-	DynamoDbClient *dynamodb.Client
-	TableName      string
-}
-
-func init() {
-	tClient = &TClientUserInfo{}
-	tClient.TableName = TABLENAME
-	tClient.DynamoDbClient = GetClientFromEnv()
-}
-
-func GetClientFromEnv() *dynamodb.Client {
-	cfg, err := config.LoadDefaultConfig(context.Background(), func(l *config.LoadOptions) error {
-		return nil
-	})
-
-	CheckError(err)
-
-	client := dynamodb.NewFromConfig(cfg)
-	return client
-}
-
-func CheckError(err error) {
-	if err != nil {
-		log.Fatalln(err.Error())
-	}
-}
+var tClient WorkerInfoStore
+var tClientOnce sync.Once
 
 type RawWorkerInfo struct {
 	GroundNumber string
@@ -55,8 +27,13 @@ type RawWorkerInfo struct {
 	Spo2Level    int16
 	Temperature  int32
 	GasLevel     int32
+	GasType      string // Gas the helmet sensor measures, GAS_DEFAULT_TYPE when empty
 	HeartRate    int32
-	DangerType   string // Only two types possible (SOS ,Water)
+	DangerType   string // Set by the helmet, SOS or Water
+
+	MeasuredAt     string // Optional RFC 3339 time the helmet took the reading, the receive time is used without it
+	Sequence       uint64 // Optional per helmet counter, used for deduplication and gap detection
+	IdempotencyKey string // Optional, the Idempotency-Key header works too
 }
 
 type WorkerInfo struct {
@@ -65,22 +42,41 @@ type WorkerInfo struct {
 	Spo2Level     int16
 	Temperature   int32
 	GasLevel      int32
+	GasType       string
 	HeartRate     int32
-	DangerType    string // Only two types possible (SOS ,Water)
+	DangerType    string // SOS or Water from the helmet, or a type raised by the rules engine
 	Date          string // Secondary Index
+	Timestamp     string // When the reading was taken, sort key of WorkerReading (see FormatTimestamp)
+	Sequence      uint64 // Helmet sequence number, 0 when not sent
+	MeasuredAt    string // Time sent by the helmet, as sent
+	ReceivedAt    string // Server time the reading arrived at
+	ClockFlag     string // Why MeasuredAt was not used (see clock.go), empty when it was or wasn't sent
 	TreatedDoctor string
+
+	EarlyWarningScore int    // NEWS2 style score from Spo2Level, HeartRate and Temperature
+	EarlyWarningRisk  string // low, low-medium, medium or high
 }
 
 func (rawWorkInfo *RawWorkerInfo) ConvertToWorkInfo() *WorkerInfo {
+	return rawWorkInfo.ConvertToWorkInfoAt(time.Now())
+}
+
+// ConvertToWorkInfoAt Converts a reading received at now, filed under MeasuredAt when the helmet sent a plausible one
+func (rawWorkInfo *RawWorkerInfo) ConvertToWorkInfoAt(now time.Time) *WorkerInfo {
 	workInfo := &WorkerInfo{}
 	workInfo.FillRawFields(rawWorkInfo)
 	workInfo.Name = "Just-xxx"
 	workInfo.TreatedDoctor = "Just-Doc-XXX"
-	workInfo.Date = civil.DateOf(time.Now()).String()
+	workInfo.fillTimes(rawWorkInfo, now)
+	workInfo.FillEarlyWarningScore()
 
 	return workInfo
 }
 
+func civilDate(t time.Time) string {
+	return civil.DateOf(t).String()
+}
+
 func (wInfo *WorkerInfo) GetKey() map[string]types.AttributeValue {
 	return map[string]types.AttributeValue{"Id": &types.AttributeValueMemberS{Value: wInfo.Id}, "Date": &types.AttributeValueMemberS{Value: wInfo.Date}}
 }
@@ -90,206 +86,205 @@ func (workerInfo *WorkerInfo) FillRawFields(ruserInfo *RawWorkerInfo) {
 	workerInfo.Spo2Level = ruserInfo.Spo2Level
 	workerInfo.Temperature = ruserInfo.Temperature
 	workerInfo.GasLevel = ruserInfo.GasLevel
+	workerInfo.GasType = ruserInfo.GasType
+	if workerInfo.GasType == "" {
+		workerInfo.GasType = util.GetDefaultGasType()
+	}
 	workerInfo.HeartRate = ruserInfo.HeartRate
 	workerInfo.DangerType = ruserInfo.DangerType
+	workerInfo.Sequence = ruserInfo.Sequence
 }
 
 const FILENAME = "userinfo/index.go"
 
-func (tClient *TClientUserInfo) GetWorkerInfo(id string) WorkerInfo {
-	workerInfo := WorkerInfo{Id: id}
-	response, err := tClient.DynamoDbClient.GetItem(context.Background(), &dynamodb.GetItemInput{
-		Key: workerInfo.GetKey(), TableName: aws.String(tClient.TableName),
-	})
-	if err != nil {
-		log.Printf("Couldn't get info about %v. Reason => : %v\n", id, err)
-	} else {
-		err = attributevalue.UnmarshalMap(response.Item, &workerInfo)
+// YYYY-MM-DD, today or yesterday. range=today|yesterday|lastNd can replace sdate and edate
+// Dates are days of the ground query param's time zone (DEFAULT_TIME_ZONE without it), ground also filters the workers
+// Range results can be filtered with minScore and risk, and sorted with sort=score (highest first)
+// limit and cursor page the range in Date, Id order, format=ndjson streams all of it (see util.WritePage)
+func Get(ctx *gin.Context) {
+	util.DebugPrint(FILENAME, "GET", "Just Test")
+	id, isFound := ctx.GetQuery("Id")
+	sdate, sfound := ctx.GetQuery("sdate")
+	edate, efound := ctx.GetQuery("edate")
+
+	if isFound {
+		workerInfo, err := GetStore().GetWorkerInfo(id)
 		if err != nil {
-			log.Printf("Couldn't unmarshal response. Reason => : %v\n", err)
+			util.WriteStoreError(ctx, err)
+			return
 		}
+		ctx.JSON(http.StatusOK, workerInfo)
+		return
 	}
-	return workerInfo
-}
-
-// GetAllWorkerInfo Returns all worker info recorded btw start and end date
-func (tClient *TClientUserInfo) GetAllWorkerInfo(startDate, endDate string) []WorkerInfo {
-	var workerInfoList []WorkerInfo
-	var err error
-	var response *dynamodb.ScanOutput
-	filtExpre := expression.Name("Date").Between(expression.Value(startDate), expression.Value(endDate))
-	projEx := expression.NamesList(
-		expression.Name("Id"), expression.Name("Date"), expression.Name("TreatedDoctor"),
-		expression.Name("Name"), expression.Name("Spo2Level"), expression.Name("GasLevel"),
-		expression.Name("Temperature"), expression.Name("DangerType"), expression.Name("HeartRate"))
-	expr, err := expression.NewBuilder().WithFilter(filtExpre).WithProjection(projEx).Build()
+	name, hasRange := ctx.GetQuery("range")
+	if !hasRange && !(sfound && efound) {
+		util.WriteError(ctx, http.StatusBadRequest, "Id, range, or sdate and edate not provided")
+		return
+	}
+	sdate, edate, err := ResolveListDates(name, sdate, edate, time.Now(), util.GetGroundLocation(ctx.Query("ground")))
 	if err != nil {
-		log.Printf("Couldn't build expressions for scan. Here's why: %v\n", err)
-	} else {
-		response, err = tClient.DynamoDbClient.Scan(context.Background(), &dynamodb.ScanInput{
-			TableName:                 aws.String(tClient.TableName),
-			ExpressionAttributeNames:  expr.Names(),
-			ExpressionAttributeValues: expr.Values(),
-			FilterExpression:          expr.Filter(),
-			ProjectionExpression:      expr.Projection(),
-		})
-		if err != nil {
-			log.Printf("Couldn't scan for workerinfo between %v and %v. Here's why: %v\n",
-				startDate, endDate, err)
-		} else {
-			err = attributevalue.UnmarshalListOfMaps(response.Items, &workerInfoList)
-			if err != nil {
-				log.Printf("Couldn't unmarshal query response. Here's why: %v\n", err)
-			}
+		util.WriteError(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	keep, isValid := rangeFilter(ctx)
+	if !isValid {
+		return
+	}
+	if util.IsPaged(ctx) {
+		if ctx.Query("sort") == "score" {
+			util.WriteError(ctx, http.StatusBadRequest, "sort=score can't be combined with limit, cursor or ndjson")
+			return
 		}
+		// Filters apply per page, a page may hold fewer than limit entries
+		util.WritePage(ctx, "userinfo|"+sdate+"|"+edate+"|"+ctx.Query("ground"), func(after string, limit int) ([]WorkerInfo, string, error) {
+			workerInfoList, next, err := GetStore().GetWorkerInfoPage(sdate, edate, after, limit)
+			return FilterWorkerInfo(workerInfoList, keep), next, err
+		})
+		return
 	}
-	return workerInfoList
-}
 
-func (tClient *TClientUserInfo) InsertWorkerInfo(workerInfo *WorkerInfo) error {
-	item, err := attributevalue.MarshalMap(workerInfo)
-	CheckError(err)
-	_, err = tClient.DynamoDbClient.PutItem(context.Background(), &dynamodb.PutItemInput{
-		TableName: aws.String(tClient.TableName), Item: item,
-	})
+	workerInfoList, err := GetStore().GetAllWorkerInfo(sdate, edate)
 	if err != nil {
-		log.Printf("Couldn't add item to table. Reason => %v\n", err)
+		util.WriteStoreError(ctx, err)
+		return
+	}
+	workerInfoList = FilterWorkerInfo(workerInfoList, keep)
+	if ctx.Query("sort") == "score" {
+		sort.SliceStable(workerInfoList, func(i, j int) bool {
+			return workerInfoList[i].EarlyWarningScore > workerInfoList[j].EarlyWarningScore
+		})
 	}
-	return err
+	ctx.JSON(http.StatusOK, workerInfoList)
 }
 
-func (tClient *TClientUserInfo) GetWorkerInfoOnQuery() {
-
+// ResolveListDates Returns the sdate and edate of a range listing, rangeName (today, yesterday or lastNd) wins over sdate and edate
+func ResolveListDates(rangeName, sdate, edate string, now time.Time, loc *time.Location) (string, string, error) {
+	if rangeName != "" {
+		startDate, endDate, isValid := ResolveDateRange(rangeName, now, loc)
+		if !isValid {
+			return "", "", errors.New("range must be today, yesterday or lastNd (e.g. last7d)")
+		}
+		return startDate, endDate, nil
+	}
+	startDate, sValid := ResolveDate(sdate, now, loc)
+	endDate, eValid := ResolveDate(edate, now, loc)
+	if !sValid || !eValid {
+		return "", "", errors.New("sdate and edate must be YYYY-MM-DD, today or yesterday")
+	}
+	return startDate, endDate, nil
 }
 
-func (tClient *TClientUserInfo) DeleteWorkerInfo(info WorkerInfo) error {
-	_, err := tClient.DynamoDbClient.DeleteItem(context.Background(), &dynamodb.DeleteItemInput{
-		TableName: aws.String(tClient.TableName), Key: info.GetKey(),
-	})
-	if err != nil {
-		log.Printf("Couldn't delete %v from the table. Here's why: %v\n", info, err)
+// rangeFilter Builds the minScore, risk and ground filter, responds with 400 and returns false when minScore is invalid
+func rangeFilter(ctx *gin.Context) (func(workerInfo *WorkerInfo) bool, bool) {
+	score := math.MinInt
+	if minScore, isFound := ctx.GetQuery("minScore"); isFound {
+		var err error
+		if score, err = strconv.Atoi(minScore); err != nil {
+			util.WriteError(ctx, http.StatusBadRequest, "minScore must be a number")
+			return nil, false
+		}
 	}
-	return err
+	return MatchFilter(score, ctx.QueryArray("risk"), ctx.Query("ground")), true
 }
 
-func (tClient *TClientUserInfo) UpdateWorkerInfo(workerInfo *WorkerInfo) (map[string]interface{}, error) {
-	var err error
-	var response *dynamodb.UpdateItemOutput
-	var attributeMap map[string]interface{}
-
-	fmt.Println("Update Called")
-
-	update := expression.Set(expression.Name("Name"), expression.Value(workerInfo.Name))
-	update.Set(expression.Name("Spo2Level"), expression.Value(workerInfo.Spo2Level))
-	update.Set(expression.Name("Temperature"), expression.Value(workerInfo.Temperature))
-	update.Set(expression.Name("GasLevel"), expression.Value(workerInfo.GasLevel))
-	update.Set(expression.Name("HeartRate"), expression.Value(workerInfo.HeartRate))
-	update.Set(expression.Name("DangerType"), expression.Value(workerInfo.DangerType))
-	update.Set(expression.Name("TreatedDoctor"), expression.Value(workerInfo.TreatedDoctor))
-
-	expr, err := expression.NewBuilder().WithUpdate(update).Build()
-	if err != nil {
-		log.Printf("Couldn't build expression for update. Here's why: %v\n", err)
-	} else {
-		response, err = tClient.DynamoDbClient.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
-			TableName:                 aws.String(tClient.TableName),
-			Key:                       workerInfo.GetKey(),
-			ExpressionAttributeNames:  expr.Names(),
-			ExpressionAttributeValues: expr.Values(),
-			UpdateExpression:          expr.Update(),
-			ReturnValues:              types.ReturnValueUpdatedNew,
-		})
-		if err != nil {
-			log.Printf("Couldn't update workerInfo %v. Reason => %v\n", *workerInfo, err)
-		} else {
-			err = attributevalue.UnmarshalMap(response.Attributes, &attributeMap)
-			if err != nil {
-				log.Printf("Couldn't unmarshall update response. Reason => %v\n", err)
+// MatchFilter Keeps entries scoring at least minScore, at one of risks and on ground
+// An empty risks or ground matches every entry
+func MatchFilter(minScore int, risks []string, ground string) func(workerInfo *WorkerInfo) bool {
+	return func(workerInfo *WorkerInfo) bool {
+		if workerInfo.EarlyWarningScore < minScore || (ground != "" && GetSite(workerInfo.Id) != ground) {
+			return false
+		}
+		for _, risk := range risks {
+			if workerInfo.EarlyWarningRisk == risk {
+				return true
 			}
 		}
+		return len(risks) == 0
 	}
-	return attributeMap, err
 }
 
-// TODO:
-func (tClient *TClientUserInfo) InsertBatchWorkerInfo() {
-	//var err error
-	//var item map[string]types.AttributeValue
-	//written := 0
-	//batchSize := 25 // DynamoDB allows a maximum batch size of 25 items.
-	//start := 0
-	//end := start + batchSize
-	//for start < maxMovies && start < len(movies) {
-	//	var writeReqs []types.WriteRequest
-	//	if end > len(movies) {
-	//		end = len(movies)
-	//	}
-	//	for _, movie := range movies[start:end] {
-	//		item, err = attributevalue.MarshalMap(movie)
-	//		if err != nil {
-	//			log.Printf("Couldn't marshal movie %v for batch writing. Here's why: %v\n", movie.Title, err)
-	//		} else {
-	//			writeReqs = append(
-	//				writeReqs,
-	//				types.WriteRequest{PutRequest: &types.PutRequest{Item: item}},
-	//			)
-	//		}
-	//	}
-	//	_, err = basics.DynamoDbClient.BatchWriteItem(context.TODO(), &dynamodb.BatchWriteItemInput{
-	//		RequestItems: map[string][]types.WriteRequest{basics.TableName: writeReqs}})
-	//	if err != nil {
-	//		log.Printf("Couldn't add a batch of workerInfo to %v. Here's why: %v\n", basics.TableName, err)
-	//	} else {
-	//		written += len(writeReqs)
-	//	}
-	//	start = end
-	//	end += batchSize
-	//}
-	//
-	//return written, err
-}
-
-// YYYY-MM-DD
-func Get(ctx *gin.Context) {
-	util.DebugPrint(FILENAME, "GET", "Just Test")
-	id, isFound := ctx.GetQuery("Id")
-	sdate, sfound := ctx.GetQuery("sdate")
-	edate, efound := ctx.GetQuery("edate")
-
-	if isFound {
-		ctx.JSON(http.StatusOK, tClient.GetWorkerInfo(id))
-	} else if !(sfound && efound) {
-		ctx.JSON(http.StatusBadRequest, "")
-	} else {
-		ctx.JSON(http.StatusOK, tClient.GetAllWorkerInfo(sdate, edate))
+// FilterWorkerInfo Keeps the entries keep returns true for
+func FilterWorkerInfo(workerInfoList []WorkerInfo, keep func(workerInfo *WorkerInfo) bool) []WorkerInfo {
+	filtered := []WorkerInfo{}
+	for i := range workerInfoList {
+		if keep(&workerInfoList[i]) {
+			filtered = append(filtered, workerInfoList[i])
+		}
 	}
+	return filtered
 }
 
 func Post(ctx *gin.Context) {
 	util.DebugPrint(FILENAME, "POST", "Just Test")
 	rworkInfo := &RawWorkerInfo{}
-	err := ctx.BindJSON(rworkInfo)
-	CheckError(err)
-	workInfo := rworkInfo.ConvertToWorkInfo()
-	err = tClient.InsertWorkerInfo(workInfo)
-	CheckError(err)
+	var err error
+	if IsFrame(ctx) {
+		// Binary frame from a radio gateway (see the telemetry package)
+		if rworkInfo, err = ReadFrame(ctx, telemetry.KindTelemetry); err != nil {
+			util.WriteError(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+	} else if err = ctx.ShouldBindJSON(rworkInfo); err != nil {
+		util.WriteError(ctx, http.StatusBadRequest, "Body must be a JSON reading")
+		return
+	}
+	rworkInfo.ReadIdempotencyHeader(ctx)
+	_, isNew, err := Ingest(rworkInfo)
+	if err != nil {
+		util.WriteStoreError(ctx, err)
+		return
+	}
+	if !isNew {
+		// A retried reading is acknowledged again without being stored or alerting twice
+		ctx.Header(ReplayedHeader, "true")
+		ctx.Status(http.StatusOK)
+	}
+}
+
+// Ingest Stores a helmet reading and hands it to the ingest hooks, shared by POST /userinfo and the MQTT subscriber
+// A reading already received within DEDUP_WINDOW returns false without being stored again
+func Ingest(rawWorkInfo *RawWorkerInfo) (*WorkerInfo, bool, error) {
+	dedupKey := rawWorkInfo.DedupKey()
+	if _, isNew := ingestDedup.Claim(dedupKey, time.Now()); !isNew {
+		return nil, false, nil
+	}
+	workInfo := rawWorkInfo.ConvertToWorkInfo()
+	// The reading is always appended, the day's record only moves forward (see InsertWorkerInfo)
+	err := GetStore().InsertReading(workInfo)
+	if err == nil {
+		err = GetStore().InsertWorkerInfo(workInfo)
+	}
+	if err != nil {
+		ingestDedup.Release(dedupKey)
+		return workInfo, true, err
+	}
+	TrackSequence(workInfo, time.Now())
+	RunIngestHooks(workInfo, workInfo.MeasuredTime())
+	return workInfo, true, nil
 }
 
 func Update(ctx *gin.Context) {
 	workInfo := &WorkerInfo{}
-	err := ctx.BindJSON(workInfo)
-	CheckError(err)
-	_, err = tClient.UpdateWorkerInfo(workInfo)
-	CheckError(err)
+	if err := ctx.ShouldBindJSON(workInfo); err != nil {
+		util.WriteError(ctx, http.StatusBadRequest, "Body must be a JSON worker info")
+		return
+	}
+	workInfo.FillEarlyWarningScore()
+	if _, err := GetStore().UpdateWorkerInfo(workInfo); err != nil {
+		util.WriteStoreError(ctx, err)
+	}
 }
 
 func Delete(ctx *gin.Context) {
 	id, isFound := ctx.GetQuery("Id")
 
 	if isFound {
-		err := tClient.DeleteWorkerInfo(WorkerInfo{Id: id})
-		CheckError(err)
+		// Without a Date every row of the worker goes
+		if err := GetStore().DeleteWorkerInfo(WorkerInfo{Id: id, Date: ctx.Query("Date")}); err != nil {
+			util.WriteStoreError(ctx, err)
+		}
 	} else {
-		ctx.String(http.StatusBadRequest, "Id not provided")
+		util.WriteError(ctx, http.StatusBadRequest, "Id not provided")
 	}
 }
@@ -0,0 +1,162 @@
+package userinfo
+
+import (
+	"go_backend/util"
+	"sort"
+	"sync"
+)
+
+// MemoryWorkerInfoStore keeps WorkerInfo in process memory, Contents are lost on restart
+type MemoryWorkerInfoStore struct {
+	mu       sync.RWMutex
+	items    map[string]WorkerInfo
+	readings map[string][]WorkerInfo // Id -> readings ordered by Timestamp
+}
+
+func NewMemoryWorkerInfoStore() *MemoryWorkerInfoStore {
+	return &MemoryWorkerInfoStore{items: make(map[string]WorkerInfo), readings: make(map[string][]WorkerInfo)}
+}
+
+func memoryKey(id, date string) string {
+	return id + "|" + date
+}
+
+// GetWorkerInfo Returns the entry of the latest Date recorded for id
+func (store *MemoryWorkerInfoStore) GetWorkerInfo(id string) (WorkerInfo, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	workerInfo, isFound := WorkerInfo{Id: id}, false
+	for _, stored := range store.items {
+		if stored.Id == id && (!isFound || stored.Date > workerInfo.Date) {
+			workerInfo, isFound = stored, true
+		}
+	}
+	if !isFound {
+		return workerInfo, util.NewStoreError(util.ErrNotFound, "No worker info for "+id)
+	}
+	return workerInfo, nil
+}
+
+// GetAllWorkerInfo Returns all worker info recorded btw start and end date (both inclusive)
+func (store *MemoryWorkerInfoStore) GetAllWorkerInfo(startDate, endDate string) ([]WorkerInfo, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	var workerInfoList []WorkerInfo
+	for _, workerInfo := range store.items {
+		if workerInfo.Date >= startDate && workerInfo.Date <= endDate {
+			workerInfoList = append(workerInfoList, workerInfo)
+		}
+	}
+	sort.Slice(workerInfoList, func(i, j int) bool {
+		if workerInfoList[i].Date != workerInfoList[j].Date {
+			return workerInfoList[i].Date < workerInfoList[j].Date
+		}
+		return workerInfoList[i].Id < workerInfoList[j].Id
+	})
+	return workerInfoList, nil
+}
+
+func (store *MemoryWorkerInfoStore) GetWorkerInfoPage(startDate, endDate, after string, limit int) ([]WorkerInfo, string, error) {
+	workerInfoList, _ := store.GetAllWorkerInfo(startDate, endDate)
+	page, next := util.PageSorted(workerInfoList, WorkerInfoCursor, after, limit)
+	return page, next, nil
+}
+
+func (store *MemoryWorkerInfoStore) InsertWorkerInfo(workerInfo *WorkerInfo) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	key := memoryKey(workerInfo.Id, workerInfo.Date)
+	if stored, isFound := store.items[key]; isFound && stored.Timestamp >= workerInfo.Timestamp {
+		return nil
+	}
+	store.items[key] = *workerInfo
+	return nil
+}
+
+// UpdateWorkerInfo Behaves like the conditional DynamoDB UpdateItem, util.ErrNotFound when the row is missing
+func (store *MemoryWorkerInfoStore) UpdateWorkerInfo(workerInfo *WorkerInfo) (map[string]interface{}, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	key := memoryKey(workerInfo.Id, workerInfo.Date)
+	stored, isFound := store.items[key]
+	if !isFound {
+		return nil, errNoRecord(workerInfo)
+	}
+	stored.Id = workerInfo.Id
+	stored.Date = workerInfo.Date
+	stored.Name = workerInfo.Name
+	stored.Spo2Level = workerInfo.Spo2Level
+	stored.Temperature = workerInfo.Temperature
+	stored.GasLevel = workerInfo.GasLevel
+	stored.GasType = workerInfo.GasType
+	stored.HeartRate = workerInfo.HeartRate
+	stored.DangerType = workerInfo.DangerType
+	stored.TreatedDoctor = workerInfo.TreatedDoctor
+	stored.EarlyWarningScore = workerInfo.EarlyWarningScore
+	stored.EarlyWarningRisk = workerInfo.EarlyWarningRisk
+	store.items[key] = stored
+
+	return updatedAttributes(&stored), nil
+}
+
+func (store *MemoryWorkerInfoStore) DeleteWorkerInfo(info WorkerInfo) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	isFound := false
+	for key, stored := range store.items {
+		if stored.Id == info.Id && (info.Date == "" || stored.Date == info.Date) {
+			delete(store.items, key)
+			isFound = true
+		}
+	}
+	if !isFound {
+		return errNoRecord(&info)
+	}
+	return nil
+}
+
+func (store *MemoryWorkerInfoStore) InsertBatchWorkerInfo(workerInfoList []WorkerInfo) []error {
+	return insertEach(store, workerInfoList)
+}
+
+func (store *MemoryWorkerInfoStore) InsertReading(reading *WorkerInfo) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	series := store.readings[reading.Id]
+	i := sort.Search(len(series), func(i int) bool {
+		return series[i].Timestamp >= reading.Timestamp
+	})
+	if i < len(series) && series[i].Timestamp == reading.Timestamp {
+		series[i] = *reading
+		return nil
+	}
+	series = append(series, WorkerInfo{})
+	copy(series[i+1:], series[i:])
+	series[i] = *reading
+	store.readings[reading.Id] = series
+	return nil
+}
+
+func (store *MemoryWorkerInfoStore) GetReadings(id, from, to string) ([]WorkerInfo, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	series := store.readings[id]
+	start := sort.Search(len(series), func(i int) bool {
+		return series[i].Timestamp >= from
+	})
+	readingList := []WorkerInfo{}
+	for _, reading := range series[start:] {
+		if reading.Timestamp > to {
+			break
+		}
+		readingList = append(readingList, reading)
+	}
+	return readingList, nil
+}
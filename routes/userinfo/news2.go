@@ -0,0 +1,72 @@
+package userinfo
+
+// National Early Warning Score (NEWS2) style triage number built from the vitals a helmet reports
+// Each parameter scores 0-3, the score is their sum. A vital of 0 means not reported and scores nothing
+
+// Clinical risk bands
+const (
+	RiskLow       = "low"
+	RiskLowMedium = "low-medium" // a single parameter scored 3
+	RiskMedium    = "medium"
+	RiskHigh      = "high"
+)
+
+// scoreBand gives points to values up to and including Max
+type scoreBand struct {
+	Max    float64
+	Points int
+}
+
+// earlyWarningParameter scores one vital, Bands are ordered by Max and the last one catches everything above
+type earlyWarningParameter struct {
+	Field string
+	Bands []scoreBand
+}
+
+// earlyWarningParameters follow the NEWS2 chart (SpO2 scale 1), respiration rate joins here once helmets report it
+var earlyWarningParameters = []earlyWarningParameter{
+	{Field: "Spo2Level", Bands: []scoreBand{{91, 3}, {93, 2}, {95, 1}, {100, 0}}},
+	{Field: "HeartRate", Bands: []scoreBand{{40, 3}, {50, 1}, {90, 0}, {110, 1}, {130, 2}, {1 << 16, 3}}},
+	{Field: "Temperature", Bands: []scoreBand{{35, 3}, {36, 1}, {38, 0}, {39, 1}, {1 << 16, 2}}},
+}
+
+func (parameter *earlyWarningParameter) score(value float64) int {
+	for _, band := range parameter.Bands {
+		if value <= band.Max {
+			return band.Points
+		}
+	}
+	return parameter.Bands[len(parameter.Bands)-1].Points
+}
+
+// ComputeEarlyWarningScore Returns the aggregate score and its risk band
+func (workerInfo *WorkerInfo) ComputeEarlyWarningScore() (int, string) {
+	total := 0
+	singleRed := false
+	for _, parameter := range earlyWarningParameters {
+		value, _ := workerInfo.GetField(parameter.Field)
+		if value == 0 {
+			continue
+		}
+		points := parameter.score(value)
+		total += points
+		if points == 3 {
+			singleRed = true
+		}
+	}
+
+	switch {
+	case total >= 7:
+		return total, RiskHigh
+	case total >= 5:
+		return total, RiskMedium
+	case singleRed:
+		return total, RiskLowMedium
+	}
+	return total, RiskLow
+}
+
+// FillEarlyWarningScore Stores the score alongside the reading
+func (workerInfo *WorkerInfo) FillEarlyWarningScore() {
+	workerInfo.EarlyWarningScore, workerInfo.EarlyWarningRisk = workerInfo.ComputeEarlyWarningScore()
+}
@@ -0,0 +1,88 @@
+package userinfo
+
+import "testing"
+
+func TestEarlyWarningBands(t *testing.T) {
+	tests := []struct {
+		field  string
+		values []float64
+		points []int
+	}{
+		{"Spo2Level", []float64{85, 91, 92, 93, 94, 95, 96, 100}, []int{3, 3, 2, 2, 1, 1, 0, 0}},
+		{"HeartRate", []float64{30, 40, 41, 50, 51, 90, 91, 110, 111, 130, 131, 200}, []int{3, 3, 1, 1, 0, 0, 1, 1, 2, 2, 3, 3}},
+		{"Temperature", []float64{34, 35, 36, 37, 38, 39, 40}, []int{3, 3, 1, 0, 0, 1, 2}},
+	}
+	for _, test := range tests {
+		var parameter *earlyWarningParameter
+		for i := range earlyWarningParameters {
+			if earlyWarningParameters[i].Field == test.field {
+				parameter = &earlyWarningParameters[i]
+			}
+		}
+		if parameter == nil {
+			t.Fatalf("no parameter for %v", test.field)
+		}
+		for i, value := range test.values {
+			if got := parameter.score(value); got != test.points[i] {
+				t.Errorf("%v %v scores %v, want %v", test.field, value, got, test.points[i])
+			}
+		}
+	}
+}
+
+func TestComputeEarlyWarningScore(t *testing.T) {
+	tests := []struct {
+		name       string
+		workerInfo WorkerInfo
+		score      int
+		risk       string
+	}{
+		{"normal", WorkerInfo{Spo2Level: 98, HeartRate: 70, Temperature: 37}, 0, RiskLow},
+		{"nothing reported", WorkerInfo{}, 0, RiskLow},
+		{"mild", WorkerInfo{Spo2Level: 95, HeartRate: 95, Temperature: 37}, 2, RiskLow},
+		{"single red", WorkerInfo{Spo2Level: 90, HeartRate: 70, Temperature: 37}, 3, RiskLowMedium},
+		{"medium", WorkerInfo{Spo2Level: 93, HeartRate: 115, Temperature: 39}, 5, RiskMedium},
+		{"medium wins over single red", WorkerInfo{Spo2Level: 90, HeartRate: 115, Temperature: 37}, 5, RiskMedium},
+		{"two reds", WorkerInfo{Spo2Level: 90, HeartRate: 140, Temperature: 37}, 6, RiskMedium},
+		{"high", WorkerInfo{Spo2Level: 90, HeartRate: 140, Temperature: 35}, 9, RiskHigh},
+		{"unreported sensor scores nothing", WorkerInfo{Spo2Level: 0, HeartRate: 35, Temperature: 0}, 3, RiskLowMedium},
+	}
+	for _, test := range tests {
+		score, risk := test.workerInfo.ComputeEarlyWarningScore()
+		if score != test.score || risk != test.risk {
+			t.Errorf("%v: %v scores %v %v, want %v %v", test.name, test.workerInfo, score, risk, test.score, test.risk)
+		}
+	}
+}
+
+func TestMatchFilter(t *testing.T) {
+	workerInfoList := []WorkerInfo{
+		{Id: "1_2", EarlyWarningScore: 0, EarlyWarningRisk: RiskLow},
+		{Id: "1_3", EarlyWarningScore: 3, EarlyWarningRisk: RiskLowMedium},
+		{Id: "2_1", EarlyWarningScore: 7, EarlyWarningRisk: RiskHigh},
+	}
+	ids := func(list []WorkerInfo) string {
+		text := ""
+		for _, workerInfo := range list {
+			text += workerInfo.Id + " "
+		}
+		return text
+	}
+	tests := []struct {
+		minScore int
+		risks    []string
+		ground   string
+		want     string
+	}{
+		{0, nil, "", "1_2 1_3 2_1 "},
+		{3, nil, "", "1_3 2_1 "},
+		{0, []string{RiskHigh, RiskLowMedium}, "", "1_3 2_1 "},
+		{0, nil, "1", "1_2 1_3 "},
+		{1, []string{RiskLow}, "", ""},
+	}
+	for _, test := range tests {
+		if got := ids(FilterWorkerInfo(workerInfoList, MatchFilter(test.minScore, test.risks, test.ground))); got != test.want {
+			t.Errorf("MatchFilter(%v, %v, %q) kept %q, want %q", test.minScore, test.risks, test.ground, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package userinfo
+
+import (
+	"cloud.google.com/go/civil"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/gin-gonic/gin"
+	"go_backend/util"
+	"net/http"
+	"time"
+)
+
+// TimestampLayout is fixed width UTC so timestamps sort as strings
+const TimestampLayout = "2006-01-02T15:04:05.000000000Z"
+
+// DefaultReadingsWindow is returned when no from is given
+const DefaultReadingsWindow = 24 * time.Hour
+
+func FormatTimestamp(t time.Time) string {
+	return t.UTC().Format(TimestampLayout)
+}
+
+// ParseTime Accepts RFC 3339 timestamps or a YYYY-MM-DD date (start of that day, UTC)
+func ParseTime(value string) (time.Time, error) {
+	return ParseTimeIn(value, time.UTC)
+}
+
+// ParseTimeIn Accepts RFC 3339 timestamps, or a YYYY-MM-DD date, today or yesterday (start of that day in loc)
+func ParseTimeIn(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	resolved, isValid := ResolveDate(value, time.Now(), loc)
+	if !isValid {
+		return time.Time{}, fmt.Errorf("invalid time %q", value)
+	}
+	date, err := civil.ParseDate(resolved)
+	return date.In(loc), err
+}
+
+// endOfDay Returns the last instant of the day a date value starts, value itself when it is a timestamp
+func endOfDay(value string, start time.Time, loc *time.Location) time.Time {
+	if _, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return start
+	}
+	return civil.DateOf(start.In(loc)).AddDays(1).In(loc).Add(-time.Nanosecond)
+}
+
+func (reading *WorkerInfo) GetReadingKey() map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{"Id": &types.AttributeValueMemberS{Value: reading.Id}, "Timestamp": &types.AttributeValueMemberS{Value: reading.Timestamp}}
+}
+
+// GetReadings Returns the ordered readings of one worker
+// GET /userinfo/:id/readings?from=&to= , from defaults to 24 hours before to, to defaults to now
+// Dates are days of the worker's ground time zone, a date as to covers that whole day
+// range=today|yesterday|lastNd can replace from and to
+func GetReadings(ctx *gin.Context) {
+	util.DebugPrint(FILENAME, "GET READINGS", "Just Test")
+	from, to, isValid := readingsRange(ctx)
+	if !isValid {
+		return
+	}
+	readings, err := GetStore().GetReadings(ctx.Param("id"), FormatTimestamp(from), FormatTimestamp(to))
+	if err != nil {
+		util.WriteStoreError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, readings)
+}
+
+// ReadingsQuery holds the from, to and range params of the readings routes, an empty value is not set
+type ReadingsQuery struct {
+	From  string
+	To    string
+	Range string
+}
+
+// Resolve Returns the time range of a worker's readings the query selects
+func (query *ReadingsQuery) Resolve(id string, now time.Time) (time.Time, time.Time, error) {
+	loc := GetLocation(id)
+	to := now
+	if query.Range != "" {
+		startDate, endDate, isValid := ResolveDateRange(query.Range, to, loc)
+		if !isValid {
+			return to, to, errors.New("range must be today, yesterday or lastNd (e.g. last7d)")
+		}
+		from, _ := ParseTimeIn(startDate, loc)
+		end, _ := ParseTimeIn(endDate, loc)
+		return from, endOfDay(endDate, end, loc), nil
+	}
+
+	if query.To != "" {
+		parsed, err := ParseTimeIn(query.To, loc)
+		if err != nil {
+			return to, to, errors.New("to must be RFC 3339, YYYY-MM-DD, today or yesterday")
+		}
+		to = endOfDay(query.To, parsed, loc)
+	}
+	from := to.Add(-DefaultReadingsWindow)
+	if query.From != "" {
+		parsed, err := ParseTimeIn(query.From, loc)
+		if err != nil {
+			return from, to, errors.New("from must be RFC 3339, YYYY-MM-DD, today or yesterday")
+		}
+		from = parsed
+	}
+	if from.After(to) {
+		return from, to, errors.New("from is after to")
+	}
+	return from, to, nil
+}
+
+// readingsRange Parses from and to, responds with 400 and returns false when they are invalid
+func readingsRange(ctx *gin.Context) (time.Time, time.Time, bool) {
+	query := ReadingsQuery{From: ctx.Query("from"), To: ctx.Query("to"), Range: ctx.Query("range")}
+	from, to, err := query.Resolve(ctx.Param("id"), time.Now())
+	if err != nil {
+		util.WriteError(ctx, http.StatusBadRequest, err.Error())
+		return from, to, false
+	}
+	return from, to, true
+}
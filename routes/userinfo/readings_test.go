@@ -0,0 +1,125 @@
+package userinfo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// useMemoryStore Points the route handlers at an empty in-memory store for one test
+func useMemoryStore(t *testing.T) *MemoryWorkerInfoStore {
+	t.Helper()
+	store := NewMemoryWorkerInfoStore()
+	tClientOnce.Do(func() {}) // GetStore keeps the test store instead of building one
+	previous := tClient
+	tClient = store
+	t.Cleanup(func() { tClient = previous })
+	return store
+}
+
+func TestFormatTimestampSortsAsTime(t *testing.T) {
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	times := []time.Time{
+		base,
+		base.Add(time.Nanosecond),
+		base.Add(time.Millisecond),
+		base.Add(time.Second),
+		base.In(time.FixedZone("IST", 5*3600+1800)).Add(time.Hour),
+	}
+	for i := 1; i < len(times); i++ {
+		if previous, current := FormatTimestamp(times[i-1]), FormatTimestamp(times[i]); previous >= current {
+			t.Errorf("%v sorts after %v", previous, current)
+		}
+	}
+	if got := FormatTimestamp(base.In(time.FixedZone("IST", 5*3600+1800))); got != "2024-01-01T10:00:00.000000000Z" {
+		t.Errorf("FormatTimestamp = %v, want UTC", got)
+	}
+}
+
+func TestReadingsQueryResolve(t *testing.T) {
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		query    ReadingsQuery
+		from, to time.Time
+		isValid  bool
+	}{
+		{ReadingsQuery{}, now.Add(-DefaultReadingsWindow), now, true},
+		{ReadingsQuery{From: "2024-01-02T10:00:00Z"}, now.Add(-2 * time.Hour), now, true},
+		{ReadingsQuery{To: "2024-01-02T06:00:00Z"}, now.Add(-30 * time.Hour), now.Add(-6 * time.Hour), true},
+		{ReadingsQuery{From: "2024-01-02T10:00:00+05:30", To: "2024-01-02T11:00:00Z"}, now.Add(-7*time.Hour - 30*time.Minute), now.Add(-time.Hour), true},
+		{ReadingsQuery{From: "noon"}, time.Time{}, time.Time{}, false},
+		{ReadingsQuery{To: "2024-13-01"}, time.Time{}, time.Time{}, false},
+		{ReadingsQuery{From: "2024-01-02T11:00:00Z", To: "2024-01-02T10:00:00Z"}, time.Time{}, time.Time{}, false},
+		{ReadingsQuery{Range: "fortnight"}, time.Time{}, time.Time{}, false},
+	}
+	for _, test := range tests {
+		from, to, err := test.query.Resolve("1_2", now)
+		if (err == nil) != test.isValid {
+			t.Errorf("Resolve(%+v) error = %v, want valid %v", test.query, err, test.isValid)
+			continue
+		}
+		if test.isValid && (!from.Equal(test.from) || !to.Equal(test.to)) {
+			t.Errorf("Resolve(%+v) = %v, %v, want %v, %v", test.query, from, to, test.from, test.to)
+		}
+	}
+}
+
+func TestMemoryReadingsOrderedAndInclusive(t *testing.T) {
+	store := NewMemoryWorkerInfoStore()
+	for _, timestamp := range []string{"2024-01-01T12:00:00Z", "2024-01-01T10:00:00Z", "2024-01-01T11:00:00Z"} {
+		if err := store.InsertReading(&WorkerInfo{Id: "1_2", Timestamp: timestamp}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Same Timestamp replaces the reading instead of adding one
+	store.InsertReading(&WorkerInfo{Id: "1_2", Timestamp: "2024-01-01T11:00:00Z", HeartRate: 80})
+
+	readings, _ := store.GetReadings("1_2", "2024-01-01T10:00:00Z", "2024-01-01T11:00:00Z")
+	if len(readings) != 2 || readings[0].Timestamp != "2024-01-01T10:00:00Z" || readings[1].HeartRate != 80 {
+		t.Errorf("GetReadings = %v, want 10:00 and the replaced 11:00", readings)
+	}
+	if readings, _ = store.GetReadings("1_3", "", "9"); len(readings) != 0 {
+		t.Errorf("GetReadings(1_3) = %v, want none", readings)
+	}
+}
+
+func TestGetReadingsRoute(t *testing.T) {
+	store := useMemoryStore(t)
+	now := time.Now()
+	for _, at := range []time.Time{now.Add(-48 * time.Hour), now.Add(-2 * time.Hour), now.Add(-time.Hour)} {
+		store.InsertReading(&WorkerInfo{Id: "1_2", Timestamp: FormatTimestamp(at)})
+	}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/userinfo/:id/readings", GetReadings)
+
+	tests := []struct {
+		query  string
+		status int
+		count  int
+	}{
+		{"", http.StatusOK, 2},
+		{"?from=" + now.Add(-90*time.Minute).UTC().Format(time.RFC3339), http.StatusOK, 1},
+		{"?from=" + now.Add(-72*time.Hour).UTC().Format(time.RFC3339), http.StatusOK, 3},
+		{"?from=yesterday-ish", http.StatusBadRequest, 0},
+	}
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/userinfo/1_2/readings"+test.query, nil))
+		if recorder.Code != test.status {
+			t.Errorf("GET %v = %v, want %v", test.query, recorder.Code, test.status)
+			continue
+		}
+		if test.status != http.StatusOK {
+			continue
+		}
+		var readings []WorkerInfo
+		if err := json.Unmarshal(recorder.Body.Bytes(), &readings); err != nil || len(readings) != test.count {
+			t.Errorf("GET %v returned %v readings (%v), want %v", test.query, len(readings), err, test.count)
+		}
+	}
+}
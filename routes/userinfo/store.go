@@ -0,0 +1,88 @@
+package userinfo
+
+import (
+	"go_backend/util"
+	"log"
+)
+
+// WorkerInfoStore is implemented by every storage backend able to hold WorkerInfo records
+// Records are keyed by Id + Date, each of them holds the latest reading of that day
+// Readings are the full time series, keyed by Id + Timestamp
+// Errors are domain errors where one applies (util.ErrNotFound, util.ErrThrottled...), see util.ClassifyStoreError
+type WorkerInfoStore interface {
+	GetWorkerInfo(id string) (WorkerInfo, error) // Record of the latest Date, util.ErrNotFound when the worker has none
+	GetAllWorkerInfo(startDate, endDate string) ([]WorkerInfo, error)
+	// GetWorkerInfoPage Reads the range in Date, Id order, after and the returned cursor are WorkerInfoCursor values
+	GetWorkerInfoPage(startDate, endDate, after string, limit int) ([]WorkerInfo, string, error)
+	// InsertWorkerInfo Replaces the day's record only with a later reading (by Timestamp), others are skipped without error
+	InsertWorkerInfo(workerInfo *WorkerInfo) error
+	UpdateWorkerInfo(workerInfo *WorkerInfo) (map[string]interface{}, error)
+	// DeleteWorkerInfo Removes the row of info.Date, or every row of info.Id when Date is empty, util.ErrNotFound when there is none
+	DeleteWorkerInfo(info WorkerInfo) error
+
+	InsertReading(reading *WorkerInfo) error
+	// InsertBatchWorkerInfo Stores each entry as a reading and as the latest WorkerInfo of its day, one error per entry
+	InsertBatchWorkerInfo(workerInfoList []WorkerInfo) []error
+	GetReadings(id, from, to string) ([]WorkerInfo, error) // from and to are FormatTimestamp values, both inclusive, oldest first
+}
+
+// NewWorkerInfoStore Returns the store for the backend named in config.env (STORAGE_BACKEND)
+func NewWorkerInfoStore(backend string) WorkerInfoStore {
+	switch backend {
+	case util.StorageMemory:
+		return NewMemoryWorkerInfoStore()
+	case util.StorageEdge:
+		return NewEdgeWorkerInfoStore(util.GetLocalDB())
+	case util.StorageBolt:
+		return NewBoltWorkerInfoStore(util.GetLocalDB())
+	case util.StorageDynamoDB:
+		return NewTClientUserInfo()
+	default:
+		log.Fatalf("Unknown storage backend %v\n", backend)
+	}
+	return nil
+}
+
+// GetStore Returns the store used by the route handlers
+// Built from STORAGE_BACKEND on first use, so programs importing only the types (the client package) don't connect to a store
+func GetStore() WorkerInfoStore {
+	tClientOnce.Do(func() {
+		tClient = NewWorkerInfoStore(util.GetStorageBackend())
+	})
+	return tClient
+}
+
+// errNoRecord Is returned by UpdateWorkerInfo and DeleteWorkerInfo when the worker has no row on that Date
+// (no row at all for a Date left empty), nothing is created
+func errNoRecord(workerInfo *WorkerInfo) error {
+	if workerInfo.Date == "" {
+		return util.NewStoreError(util.ErrNotFound, "No worker info for "+workerInfo.Id)
+	}
+	return util.NewStoreError(util.ErrNotFound, "No worker info for "+workerInfo.Id+" on "+workerInfo.Date)
+}
+
+// updatedAttributes Mirrors what DynamoDB returns for UpdateWorkerInfo (ReturnValueUpdatedNew)
+func updatedAttributes(stored *WorkerInfo) map[string]interface{} {
+	return map[string]interface{}{
+		"Name": stored.Name, "Spo2Level": stored.Spo2Level, "Temperature": stored.Temperature,
+		"GasLevel": stored.GasLevel, "GasType": stored.GasType, "HeartRate": stored.HeartRate, "DangerType": stored.DangerType,
+		"TreatedDoctor": stored.TreatedDoctor, "EarlyWarningScore": stored.EarlyWarningScore,
+		"EarlyWarningRisk": stored.EarlyWarningRisk,
+	}
+}
+
+// WorkerInfoCursor Is the store cursor of a WorkerInfo page, Date#Id of the last item returned
+func WorkerInfoCursor(workerInfo *WorkerInfo) string {
+	return workerInfo.Date + "#" + workerInfo.Id
+}
+
+// insertEach Implements InsertBatchWorkerInfo one entry at a time for the local backends
+func insertEach(store WorkerInfoStore, workerInfoList []WorkerInfo) []error {
+	errs := make([]error, len(workerInfoList))
+	for i := range workerInfoList {
+		if errs[i] = store.InsertReading(&workerInfoList[i]); errs[i] == nil {
+			errs[i] = store.InsertWorkerInfo(&workerInfoList[i])
+		}
+	}
+	return errs
+}
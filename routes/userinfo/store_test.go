@@ -0,0 +1,140 @@
+package userinfo
+
+import (
+	"errors"
+	"go_backend/util"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalStoresKeepNewestRow(t *testing.T) {
+	stores := map[string]WorkerInfoStore{
+		"memory": NewMemoryWorkerInfoStore(),
+		"bolt":   NewBoltWorkerInfoStore(openTestDB(t)),
+		"edge":   NewEdgeWorkerInfoStore(openTestDB(t)),
+	}
+	for name, store := range stores {
+		// A buffered reading arriving after a later one, then a replay of the stored reading
+		for i, timestamp := range []string{"2024-01-01T11:00:00Z", "2024-01-01T10:00:00Z", "2024-01-01T11:00:00Z"} {
+			errs := store.InsertBatchWorkerInfo([]WorkerInfo{{Id: "1_2", Date: "2024-01-01", Timestamp: timestamp, HeartRate: int32(70 + i)}})
+			if errs[0] != nil {
+				t.Fatalf("%v: insert %v error = %v", name, timestamp, errs[0])
+			}
+		}
+		if got, _ := store.GetWorkerInfo("1_2"); got.Timestamp != "2024-01-01T11:00:00Z" || got.HeartRate != 70 {
+			t.Errorf("%v: record = %+v, want the first 11:00 reading", name, got)
+		}
+		if readings, _ := store.GetReadings("1_2", "", "\xff"); len(readings) != 2 {
+			t.Errorf("%v: %v readings, want the older one appended too", name, len(readings))
+		}
+
+		store.InsertBatchWorkerInfo([]WorkerInfo{{Id: "1_2", Date: "2024-01-01", Timestamp: "2024-01-01T12:00:00Z"}})
+		if got, _ := store.GetWorkerInfo("1_2"); got.Timestamp != "2024-01-01T12:00:00Z" {
+			t.Errorf("%v: record = %+v, want the 12:00 reading", name, got)
+		}
+	}
+
+	// Only rows that changed the record are forwarded, every reading is
+	entries, _ := stores["edge"].(*EdgeWorkerInfoStore).PendingJournal(0)
+	rows := 0
+	for _, entry := range entries {
+		if entry.Table == TABLENAME {
+			rows++
+		}
+	}
+	if rows != 2 || len(entries) != 6 {
+		t.Errorf("journal holds %v rows of %v entries, want 2 of 6", rows, len(entries))
+	}
+}
+
+func TestIngestAppendsOlderReading(t *testing.T) {
+	store := useMemoryStore(t)
+	later := time.Now().UTC().Add(-time.Minute).Format(time.RFC3339)
+	for _, measuredAt := range []string{later, time.Now().UTC().Add(-2 * time.Minute).Format(time.RFC3339)} {
+		if _, _, err := Ingest(&RawWorkerInfo{GroundNumber: "5", HelmetNumber: "5", MeasuredAt: measuredAt}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	readings, _ := store.GetReadings("5_5", "", "\xff")
+	got, _ := store.GetWorkerInfo("5_5")
+	if len(readings) != 2 || got.MeasuredAt != later {
+		t.Errorf("%v readings and record measured at %v, want 2 and the later one", len(readings), got.MeasuredAt)
+	}
+}
+
+func TestLocalStoresUpdateOnlyExistingRow(t *testing.T) {
+	stores := map[string]WorkerInfoStore{
+		"memory": NewMemoryWorkerInfoStore(),
+		"bolt":   NewBoltWorkerInfoStore(openTestDB(t)),
+		"edge":   NewEdgeWorkerInfoStore(openTestDB(t)),
+	}
+	for name, store := range stores {
+		update := &WorkerInfo{Id: "1_2", Date: "2024-01-01", Name: "Ravi"}
+		if _, err := store.UpdateWorkerInfo(update); !errors.Is(err, util.ErrNotFound) {
+			t.Errorf("%v: update of a missing row error = %v, want ErrNotFound", name, err)
+		}
+		if _, err := store.GetWorkerInfo("1_2"); !errors.Is(err, util.ErrNotFound) {
+			t.Errorf("%v: the failed update created a row, GetWorkerInfo error = %v", name, err)
+		}
+
+		store.InsertWorkerInfo(&WorkerInfo{Id: "1_2", Date: "2024-01-01", Timestamp: "2024-01-01T10:00:00Z", HeartRate: 80})
+		attributes, err := store.UpdateWorkerInfo(update)
+		if err != nil || attributes["Name"] != "Ravi" {
+			t.Errorf("%v: update of a stored row = %v, %v", name, attributes, err)
+		}
+		if got, _ := store.GetWorkerInfo("1_2"); got.Name != "Ravi" || got.Timestamp != "2024-01-01T10:00:00Z" {
+			t.Errorf("%v: record after the update = %+v", name, got)
+		}
+	}
+
+	// Only the insert and the successful update are forwarded
+	if entries, _ := stores["edge"].(*EdgeWorkerInfoStore).PendingJournal(0); len(entries) != 2 {
+		t.Errorf("journal holds %v entries, want 2", len(entries))
+	}
+}
+
+func TestLocalStoresDeleteOnlyExistingRows(t *testing.T) {
+	stores := map[string]WorkerInfoStore{
+		"memory": NewMemoryWorkerInfoStore(),
+		"bolt":   NewBoltWorkerInfoStore(openTestDB(t)),
+		"edge":   NewEdgeWorkerInfoStore(openTestDB(t)),
+	}
+	for name, store := range stores {
+		if err := store.DeleteWorkerInfo(WorkerInfo{Id: "1_2", Date: "2024-01-01"}); !errors.Is(err, util.ErrNotFound) {
+			t.Errorf("%v: delete of a missing row error = %v, want ErrNotFound", name, err)
+		}
+		if err := store.DeleteWorkerInfo(WorkerInfo{Id: "1_2"}); !errors.Is(err, util.ErrNotFound) {
+			t.Errorf("%v: delete of a missing worker error = %v, want ErrNotFound", name, err)
+		}
+
+		for _, date := range []string{"2024-01-01", "2024-01-02", "2024-01-03"} {
+			store.InsertWorkerInfo(&WorkerInfo{Id: "1_2", Date: date, Timestamp: date + "T10:00:00Z"})
+		}
+		store.InsertWorkerInfo(&WorkerInfo{Id: "1_20", Date: "2024-01-01", Timestamp: "2024-01-01T10:00:00Z"})
+		if err := store.DeleteWorkerInfo(WorkerInfo{Id: "1_2", Date: "2024-01-02"}); err != nil {
+			t.Errorf("%v: delete of a stored row error = %v", name, err)
+		}
+		if err := store.DeleteWorkerInfo(WorkerInfo{Id: "1_2"}); err != nil {
+			t.Errorf("%v: delete of every row error = %v", name, err)
+		}
+		if _, err := store.GetWorkerInfo("1_2"); !errors.Is(err, util.ErrNotFound) {
+			t.Errorf("%v: GetWorkerInfo after deleting every row error = %v, want ErrNotFound", name, err)
+		}
+		if _, err := store.GetWorkerInfo("1_20"); err != nil {
+			t.Errorf("%v: deleting 1_2 removed 1_20, error = %v", name, err)
+		}
+	}
+
+	// Every delete names the Date of the row it removed, the central table needs the whole key
+	entries, _ := stores["edge"].(*EdgeWorkerInfoStore).PendingJournal(0)
+	var dates []string
+	for _, entry := range entries {
+		if entry.Operation == JournalDelete {
+			dates = append(dates, entry.Item.Date)
+		}
+	}
+	if strings.Join(dates, ",") != "2024-01-02,2024-01-01,2024-01-03" {
+		t.Errorf("journaled deletes of %v, want one per row", dates)
+	}
+}
@@ -0,0 +1,46 @@
+package userinfo
+
+import (
+	"cloud.google.com/go/civil"
+	"go_backend/util"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxRelativeDays bounds lastNd ranges
+const MaxRelativeDays = 366
+
+// GetLocation Returns the time zone of the ground a worker Id belongs to
+func GetLocation(id string) *time.Location {
+	return util.GetGroundLocation(GetSite(id))
+}
+
+// ResolveDate Turns today, yesterday or a YYYY-MM-DD date into a date of loc
+func ResolveDate(value string, now time.Time, loc *time.Location) (string, bool) {
+	today := civil.DateOf(now.In(loc))
+	switch value {
+	case "today":
+		return today.String(), true
+	case "yesterday":
+		return today.AddDays(-1).String(), true
+	}
+	date, err := civil.ParseDate(value)
+	return date.String(), err == nil
+}
+
+// ResolveDateRange Turns today, yesterday or lastNd (the N days ending today, e.g. last7d) into start and end dates of loc
+func ResolveDateRange(name string, now time.Time, loc *time.Location) (string, string, bool) {
+	if date, isValid := ResolveDate(name, now, loc); isValid && (name == "today" || name == "yesterday") {
+		return date, date, true
+	}
+	if !strings.HasPrefix(name, "last") || !strings.HasSuffix(name, "d") {
+		return "", "", false
+	}
+	days, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "last"), "d"))
+	if err != nil || days <= 0 || days > MaxRelativeDays {
+		return "", "", false
+	}
+	today := civil.DateOf(now.In(loc))
+	return today.AddDays(1 - days).String(), today.String(), true
+}
@@ -0,0 +1,103 @@
+package userinfo
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone data for %v is missing: %v", name, err)
+	}
+	return loc
+}
+
+func TestResolveDateInGroundZone(t *testing.T) {
+	kolkata := mustLoadLocation(t, "Asia/Kolkata")
+	// 20:00 UTC is already the next day in Kolkata
+	now := time.Date(2024, 3, 10, 20, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		loc   *time.Location
+		want  string
+	}{
+		{"today", time.UTC, "2024-03-10"},
+		{"today", kolkata, "2024-03-11"},
+		{"yesterday", kolkata, "2024-03-10"},
+		{"yesterday", time.UTC, "2024-03-09"},
+		{"2024-01-31", kolkata, "2024-01-31"},
+	}
+	for _, test := range tests {
+		if got, isValid := ResolveDate(test.value, now, test.loc); !isValid || got != test.want {
+			t.Errorf("ResolveDate(%v, %v) = %v, %v, want %v", test.value, test.loc, got, isValid, test.want)
+		}
+	}
+	for _, value := range []string{"tomorrow", "2024-02-30", ""} {
+		if _, isValid := ResolveDate(value, now, kolkata); isValid {
+			t.Errorf("ResolveDate(%q) is valid", value)
+		}
+	}
+}
+
+func TestResolveDateRange(t *testing.T) {
+	kolkata := mustLoadLocation(t, "Asia/Kolkata")
+	now := time.Date(2024, 3, 10, 20, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		start, end string
+	}{
+		{"today", "2024-03-11", "2024-03-11"},
+		{"yesterday", "2024-03-10", "2024-03-10"},
+		{"last1d", "2024-03-11", "2024-03-11"},
+		{"last7d", "2024-03-05", "2024-03-11"},
+		{"last366d", "2023-03-12", "2024-03-11"},
+	}
+	for _, test := range tests {
+		start, end, isValid := ResolveDateRange(test.name, now, kolkata)
+		if !isValid || start != test.start || end != test.end {
+			t.Errorf("ResolveDateRange(%v) = %v, %v, %v, want %v to %v", test.name, start, end, isValid, test.start, test.end)
+		}
+	}
+	for _, name := range []string{"last0d", "last367d", "lastd", "last-2d", "last7", "7d", "2024-03-10"} {
+		if _, _, isValid := ResolveDateRange(name, now, kolkata); isValid {
+			t.Errorf("ResolveDateRange(%q) is valid", name)
+		}
+	}
+}
+
+func TestDayBoundsAcrossDST(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+	tests := []struct {
+		date       string
+		start, end string
+	}{
+		// Clocks go forward, the day lasts 23 hours
+		{"2024-03-10", "2024-03-10T05:00:00Z", "2024-03-11T03:59:59.999999999Z"},
+		// Clocks go back, the day lasts 25 hours
+		{"2024-11-03", "2024-11-03T04:00:00Z", "2024-11-04T04:59:59.999999999Z"},
+	}
+	for _, test := range tests {
+		start, err := ParseTimeIn(test.date, newYork)
+		if err != nil {
+			t.Fatal(err)
+		}
+		end := endOfDay(test.date, start, newYork)
+		if got := start.UTC().Format(time.RFC3339Nano); got != test.start {
+			t.Errorf("%v starts at %v, want %v", test.date, got, test.start)
+		}
+		if got := end.UTC().Format(time.RFC3339Nano); got != test.end {
+			t.Errorf("%v ends at %v, want %v", test.date, got, test.end)
+		}
+	}
+
+	// A timestamp is its own bound
+	at, _ := ParseTimeIn("2024-03-10T12:00:00Z", newYork)
+	if end := endOfDay("2024-03-10T12:00:00Z", at, newYork); !end.Equal(at) {
+		t.Errorf("endOfDay of a timestamp = %v", end)
+	}
+	if civilDate(time.Date(2024, 3, 11, 3, 30, 0, 0, time.UTC).In(newYork)) != "2024-03-10" {
+		t.Error("03:30 UTC is still the previous day in New York")
+	}
+}
@@ -0,0 +1,234 @@
+/*
+Telemetry Package encodes and decodes the compact binary helmet frame used over LoRa / ZigBee links
+It only depends on the standard library so the firmware team can generate test vectors with it
+
+Version 1 frames are 24 bytes, big endian:
+
+	offset size field
+	0      1    Version      (1)
+	1      1    Kind         (1 telemetry, 2 danger)
+	2      2    GroundNumber
+	4      2    HelmetNumber
+	6      4    Sequence     (0 when the helmet doesn't count readings)
+	10     4    MeasuredAt   (unix seconds, 0 when the helmet has no clock)
+	14     1    Spo2Level    (%)
+	15     2    Temperature  (signed, °C)
+	17     2    GasLevel     (ppm)
+	19     1    GasType      (see GasTypes, 0 for the server default)
+	20     1    HeartRate    (bpm)
+	21     1    DangerType   (see DangerTypes, 0 for none)
+	22     2    CRC          (CRC-16/CCITT-FALSE of bytes 0-21)
+
+A datagram may carry several frames back to back (see DecodeAll)
+
+Test vectors (hex):
+
+	Telemetry ground 1 helmet 7, Sequence 42, MeasuredAt 1700000000, Spo2 97, 37 °C, CO 12 ppm, 80 bpm
+	0101000100070000002A6553F100610025000C0150006DA3
+	Danger ground 1 helmet 7, Sequence 43, SOS, -5 °C, no clock
+	0102000100070000002B0000000000FFFB0000000001EBC9
+*/
+
+package telemetry
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ContentType marks an HTTP body holding one frame
+const ContentType = "application/vnd.helmet.frame"
+
+const Version1 byte = 1
+
+// Frame kinds, a telemetry frame is stored as a reading, a danger frame raises an alert
+const (
+	KindTelemetry byte = 1
+	KindDanger    byte = 2
+)
+
+// FrameSizeV1 is the length of a version 1 frame, CRC included
+const FrameSizeV1 = 24
+
+// GasTypes maps the GasType byte to the gas name used by the server, index 0 is the server default
+var GasTypes = []string{"", "CO", "H2S", "SO2", "NO2", "CH4", "O2"}
+
+// DangerTypes maps the DangerType byte to the helmet danger types, index 0 is no danger
+var DangerTypes = []string{"", "SOS", "Water"}
+
+// DangerSOS is the DangerType byte of an SOS, assumed for a danger frame without one
+const DangerSOS uint8 = 1
+
+var (
+	ErrShortFrame = errors.New("frame is shorter than its version requires")
+	ErrVersion    = errors.New("unsupported frame version")
+	ErrKind       = errors.New("unknown frame kind")
+	ErrCRC        = errors.New("frame CRC mismatch")
+)
+
+// Frame is the decoded content of a frame, fields hold the wire values
+type Frame struct {
+	Version      byte
+	Kind         byte
+	GroundNumber uint16
+	HelmetNumber uint16
+	Sequence     uint32
+	MeasuredAt   uint32
+	Spo2Level    uint8
+	Temperature  int16
+	GasLevel     uint16
+	GasType      uint8
+	HeartRate    uint8
+	DangerType   uint8
+}
+
+// MeasuredTime Returns MeasuredAt as a time, the zero time when the helmet sent none
+func (frame *Frame) MeasuredTime() time.Time {
+	if frame.MeasuredAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(frame.MeasuredAt), 0).UTC()
+}
+
+// GasName Returns the gas the GasType byte stands for, false for an unknown code
+func (frame *Frame) GasName() (string, bool) {
+	if int(frame.GasType) >= len(GasTypes) {
+		return "", false
+	}
+	return GasTypes[frame.GasType], true
+}
+
+// DangerName Returns the danger the DangerType byte stands for, false for an unknown code
+func (frame *Frame) DangerName() (string, bool) {
+	if int(frame.DangerType) >= len(DangerTypes) {
+		return "", false
+	}
+	return DangerTypes[frame.DangerType], true
+}
+
+// GasCode Returns the GasType byte of a gas name, false when the name has no code
+func GasCode(name string) (uint8, bool) {
+	return code(GasTypes, name)
+}
+
+// DangerCode Returns the DangerType byte of a danger name, false when the name has no code
+func DangerCode(name string) (uint8, bool) {
+	return code(DangerTypes, name)
+}
+
+func code(names []string, name string) (uint8, bool) {
+	for i := range names {
+		if names[i] == name {
+			return uint8(i), true
+		}
+	}
+	return 0, false
+}
+
+// MarshalBinary Encodes the frame with its CRC, Version 0 is encoded as Version1
+func (frame Frame) MarshalBinary() ([]byte, error) {
+	if frame.Version == 0 {
+		frame.Version = Version1
+	}
+	if frame.Version != Version1 {
+		return nil, fmt.Errorf("%w %v", ErrVersion, frame.Version)
+	}
+	if frame.Kind != KindTelemetry && frame.Kind != KindDanger {
+		return nil, fmt.Errorf("%w %v", ErrKind, frame.Kind)
+	}
+
+	data := make([]byte, FrameSizeV1)
+	data[0] = frame.Version
+	data[1] = frame.Kind
+	binary.BigEndian.PutUint16(data[2:], frame.GroundNumber)
+	binary.BigEndian.PutUint16(data[4:], frame.HelmetNumber)
+	binary.BigEndian.PutUint32(data[6:], frame.Sequence)
+	binary.BigEndian.PutUint32(data[10:], frame.MeasuredAt)
+	data[14] = frame.Spo2Level
+	binary.BigEndian.PutUint16(data[15:], uint16(frame.Temperature))
+	binary.BigEndian.PutUint16(data[17:], frame.GasLevel)
+	data[19] = frame.GasType
+	data[20] = frame.HeartRate
+	data[21] = frame.DangerType
+	binary.BigEndian.PutUint16(data[22:], CRC16(data[:22]))
+	return data, nil
+}
+
+// UnmarshalBinary Decodes exactly one frame, data must not hold anything after it
+func (frame *Frame) UnmarshalBinary(data []byte) error {
+	size, err := frame.decode(data)
+	if err == nil && size != len(data) {
+		err = fmt.Errorf("%v trailing bytes after the frame", len(data)-size)
+	}
+	return err
+}
+
+// decode Reads the frame at the start of data and returns its length
+func (frame *Frame) decode(data []byte) (int, error) {
+	if len(data) < 1 {
+		return 0, ErrShortFrame
+	}
+	if data[0] != Version1 {
+		return 0, fmt.Errorf("%w %v", ErrVersion, data[0])
+	}
+	if len(data) < FrameSizeV1 {
+		return 0, ErrShortFrame
+	}
+	if CRC16(data[:22]) != binary.BigEndian.Uint16(data[22:]) {
+		return 0, ErrCRC
+	}
+	if data[1] != KindTelemetry && data[1] != KindDanger {
+		return 0, fmt.Errorf("%w %v", ErrKind, data[1])
+	}
+
+	*frame = Frame{
+		Version:      data[0],
+		Kind:         data[1],
+		GroundNumber: binary.BigEndian.Uint16(data[2:]),
+		HelmetNumber: binary.BigEndian.Uint16(data[4:]),
+		Sequence:     binary.BigEndian.Uint32(data[6:]),
+		MeasuredAt:   binary.BigEndian.Uint32(data[10:]),
+		Spo2Level:    data[14],
+		Temperature:  int16(binary.BigEndian.Uint16(data[15:])),
+		GasLevel:     binary.BigEndian.Uint16(data[17:]),
+		GasType:      data[19],
+		HeartRate:    data[20],
+		DangerType:   data[21],
+	}
+	return FrameSizeV1, nil
+}
+
+// DecodeAll Decodes back to back frames, stopping at the first one that doesn't decode
+// The frames before it are returned with the error
+func DecodeAll(data []byte) ([]Frame, error) {
+	frames := []Frame{}
+	for len(data) > 0 {
+		frame := Frame{}
+		size, err := frame.decode(data)
+		if err != nil {
+			return frames, fmt.Errorf("frame %v: %w", len(frames), err)
+		}
+		frames = append(frames, frame)
+		data = data[size:]
+	}
+	return frames, nil
+}
+
+// CRC16 Computes CRC-16/CCITT-FALSE (poly 0x1021, init 0xFFFF, no reflection, no final xor)
+// The check value of "123456789" is 0x29B1
+func CRC16(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,164 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+)
+
+// The vectors documented at the top of frame.go, the firmware team tests against them
+var vectors = []struct {
+	name  string
+	hex   string
+	frame Frame
+}{
+	{"telemetry", "0101000100070000002A6553F100610025000C0150006DA3", Frame{
+		Version: Version1, Kind: KindTelemetry, GroundNumber: 1, HelmetNumber: 7, Sequence: 42, MeasuredAt: 1700000000,
+		Spo2Level: 97, Temperature: 37, GasLevel: 12, GasType: 1, HeartRate: 80,
+	}},
+	{"danger", "0102000100070000002B0000000000FFFB0000000001EBC9", Frame{
+		Version: Version1, Kind: KindDanger, GroundNumber: 1, HelmetNumber: 7, Sequence: 43, Temperature: -5, DangerType: DangerSOS,
+	}},
+}
+
+func TestDocumentedVectors(t *testing.T) {
+	for _, vector := range vectors {
+		data, err := hex.DecodeString(vector.hex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		frame := Frame{}
+		if err = frame.UnmarshalBinary(data); err != nil || frame != vector.frame {
+			t.Errorf("%v: decoded %+v, %v, want %+v", vector.name, frame, err, vector.frame)
+		}
+		encoded, err := vector.frame.MarshalBinary()
+		if err != nil || !bytes.Equal(encoded, data) {
+			t.Errorf("%v: encoded %X, %v, want %v", vector.name, encoded, err, vector.hex)
+		}
+	}
+}
+
+func TestCRC16CheckValue(t *testing.T) {
+	if got := CRC16([]byte("123456789")); got != 0x29B1 {
+		t.Errorf("CRC16(123456789) = %#04x, want 0x29b1", got)
+	}
+	if got := CRC16(nil); got != 0xFFFF {
+		t.Errorf("CRC16 of nothing = %#04x, want the initial 0xffff", got)
+	}
+}
+
+func TestFrameFields(t *testing.T) {
+	frame := vectors[0].frame
+	if got := frame.MeasuredTime(); !got.Equal(time.Unix(1700000000, 0)) || got.Location() != time.UTC {
+		t.Errorf("MeasuredTime = %v", got)
+	}
+	if got := vectors[1].frame.MeasuredTime(); !got.IsZero() {
+		t.Errorf("MeasuredTime without a clock = %v, want the zero time", got)
+	}
+	if gas, isKnown := frame.GasName(); gas != "CO" || !isKnown {
+		t.Errorf("GasName = %v, %v", gas, isKnown)
+	}
+	frame.GasType = uint8(len(GasTypes))
+	if _, isKnown := frame.GasName(); isKnown {
+		t.Error("an out of range GasType is known")
+	}
+	if code, isKnown := DangerCode("Water"); code != 2 || !isKnown {
+		t.Errorf("DangerCode(Water) = %v, %v", code, isKnown)
+	}
+	if _, isKnown := GasCode("XX"); isKnown {
+		t.Error("GasCode of an unknown gas is known")
+	}
+}
+
+func TestMarshalRejects(t *testing.T) {
+	if _, err := (Frame{Version: 2, Kind: KindTelemetry}).MarshalBinary(); !errors.Is(err, ErrVersion) {
+		t.Errorf("version 2 error = %v", err)
+	}
+	if _, err := (Frame{Kind: 3}).MarshalBinary(); !errors.Is(err, ErrKind) {
+		t.Errorf("kind 3 error = %v", err)
+	}
+	data, err := (Frame{Kind: KindTelemetry}).MarshalBinary()
+	if err != nil || data[0] != Version1 {
+		t.Errorf("Version 0 encoded as %v, %v, want Version1", data, err)
+	}
+}
+
+func mustDecodeHex(t *testing.T, value string) []byte {
+	t.Helper()
+	data, err := hex.DecodeString(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestUnmarshalRejects(t *testing.T) {
+	valid := mustDecodeHex(t, vectors[0].hex)
+	corrupted := append([]byte{}, valid...)
+	corrupted[14] ^= 0x01 // Spo2Level off by one, the CRC no longer matches
+	badKind := append([]byte{}, valid...)
+	badKind[1] = 3
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"empty", nil, ErrShortFrame},
+		{"truncated", valid[:FrameSizeV1-1], ErrShortFrame},
+		{"version 2", append([]byte{2}, valid[1:]...), ErrVersion},
+		{"corrupted", corrupted, ErrCRC},
+		{"unknown kind with a wrong CRC", badKind, ErrCRC},
+	}
+	for _, test := range tests {
+		frame := Frame{}
+		if err := frame.UnmarshalBinary(test.data); !errors.Is(err, test.want) {
+			t.Errorf("%v: error = %v, want %v", test.name, err, test.want)
+		}
+	}
+
+	// A kind the server doesn't know is reported as such once the CRC is right
+	copy(badKind[22:], []byte{byte(CRC16(badKind[:22]) >> 8), byte(CRC16(badKind[:22]))})
+	if err := (&Frame{}).UnmarshalBinary(badKind); !errors.Is(err, ErrKind) {
+		t.Errorf("kind 3 error = %v, want ErrKind", err)
+	}
+	if err := (&Frame{}).UnmarshalBinary(append(valid, 0)); err == nil {
+		t.Error("a trailing byte after the frame is accepted")
+	}
+}
+
+func TestDecodeAll(t *testing.T) {
+	telemetry, danger := mustDecodeHex(t, vectors[0].hex), mustDecodeHex(t, vectors[1].hex)
+	datagram := append(append([]byte{}, telemetry...), danger...)
+
+	frames, err := DecodeAll(datagram)
+	if err != nil || len(frames) != 2 || frames[0] != vectors[0].frame || frames[1] != vectors[1].frame {
+		t.Fatalf("DecodeAll = %+v, %v", frames, err)
+	}
+	if frames, err = DecodeAll(nil); err != nil || len(frames) != 0 {
+		t.Errorf("DecodeAll of nothing = %v, %v", frames, err)
+	}
+
+	// The second frame is cut short, the first one is still returned
+	frames, err = DecodeAll(datagram[:len(datagram)-5])
+	if !errors.Is(err, ErrShortFrame) || len(frames) != 1 || frames[0] != vectors[0].frame {
+		t.Errorf("DecodeAll of a truncated datagram = %+v, %v", frames, err)
+	}
+
+	// A corrupted first frame stops decoding, nothing after it is trusted
+	corrupted := append([]byte{}, datagram...)
+	corrupted[3] ^= 0xFF
+	frames, err = DecodeAll(corrupted)
+	if !errors.Is(err, ErrCRC) || len(frames) != 0 {
+		t.Errorf("DecodeAll with a corrupted first frame = %+v, %v", frames, err)
+	}
+
+	corrupted = append([]byte{}, datagram...)
+	corrupted[len(corrupted)-1] ^= 0xFF
+	frames, err = DecodeAll(corrupted)
+	if !errors.Is(err, ErrCRC) || len(frames) != 1 {
+		t.Errorf("DecodeAll with a corrupted CRC = %+v, %v", frames, err)
+	}
+}
@@ -0,0 +1,157 @@
+package util
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorBody is the JSON envelope of every error response
+type ErrorBody struct {
+	Code      string `json:"code"` // Stable name of the failure, see ErrorCode
+	Message   string `json:"message"`
+	RequestId string `json:"request_id"` // Also in the X-Request-Id header, quote it when reporting a problem
+}
+
+const RequestIdHeader = "X-Request-Id"
+const requestIdKey = "requestId"
+const maxRequestId = 64
+
+// RequestId Middleware, keeps the caller's X-Request-Id or makes one, and echoes it in the response
+func RequestId(ctx *gin.Context) {
+	id := ctx.GetHeader(RequestIdHeader)
+	if !isValidRequestId(id) {
+		id = NewRequestId()
+	}
+	ctx.Set(requestIdKey, id)
+	ctx.Header(RequestIdHeader, id)
+	ctx.Next()
+}
+
+func NewRequestId() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		log.Printf("Couldn't make a request id. Reason => %v\n", err)
+	}
+	return hex.EncodeToString(id)
+}
+
+// isValidRequestId Accepts ids a log line can hold safely, printable ASCII without spaces
+func isValidRequestId(id string) bool {
+	if id == "" || len(id) > maxRequestId {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// GetRequestId Returns the id RequestId gave the request, "" when the middleware isn't installed
+func GetRequestId(ctx *gin.Context) string {
+	return ctx.GetString(requestIdKey)
+}
+
+// ErrorCode Returns the code of an HTTP status, 404 is not_found
+func ErrorCode(status int) string {
+	switch status {
+	case http.StatusInternalServerError:
+		return "internal"
+	case http.StatusServiceUnavailable:
+		return "unavailable"
+	case http.StatusTooManyRequests:
+		return "throttled"
+	}
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
+
+// WriteError Answers with the error envelope and stops the handler chain
+func WriteError(ctx *gin.Context, status int, message string) {
+	ctx.AbortWithStatusJSON(status, ErrorBody{Code: ErrorCode(status), Message: message, RequestId: GetRequestId(ctx)})
+}
+
+// WriteStoreError Answers a failed storage call with the status of its domain error (see ClassifyStoreError)
+// The cause is logged with the request id and not sent to the client, an unclassified error answers 500
+func WriteStoreError(ctx *gin.Context, err error) {
+	status := StoreErrorStatus(err)
+	if status != http.StatusNotFound {
+		log.Printf("Request %v failed in the store. Reason => %v\n", GetRequestId(ctx), err)
+	}
+	if status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable {
+		ctx.Header("Retry-After", retryAfterSeconds())
+	}
+	WriteError(ctx, status, StoreErrorMessage(err))
+}
+
+// StoreErrorMessage Returns the text a client may see for a store error, never the database's own
+func StoreErrorMessage(err error) string {
+	status := StoreErrorStatus(err)
+	message := ""
+	var storeErr *StoreError
+	if errors.As(err, &storeErr) {
+		message = storeErr.Message
+	}
+	switch {
+	case message != "" && status != http.StatusInternalServerError:
+		return message
+	case status == http.StatusTooManyRequests:
+		return "The database is throttling requests, retry after " + retryAfterSeconds() + "s"
+	case status == http.StatusServiceUnavailable:
+		return "The database can't be reached, retry after " + retryAfterSeconds() + "s"
+	case status == http.StatusInternalServerError:
+		return "The database failed, retry later"
+	}
+	return http.StatusText(status)
+}
+
+func retryAfterSeconds() string {
+	return strconv.Itoa(int(math.Ceil(GetStoreRetryAfter().Seconds())))
+}
+
+// StoreErrorStatus Returns the HTTP status of a store error, 500 when it has no domain error
+func StoreErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, ErrThrottled):
+		return http.StatusTooManyRequests
+	case errors.Is(err, ErrUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrInvalid):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// Recovery Middleware, answers a panicking handler with a 500 envelope instead of dropping the connection
+// The panic and its stack go to gin's error writer (stderr)
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecovery(func(ctx *gin.Context, recovered interface{}) {
+		log.Printf("Request %v panicked. Reason => %v\n", GetRequestId(ctx), recovered)
+		if ctx.Writer.Written() {
+			ctx.Abort() // Too late for an envelope, a stream was already answering
+			return
+		}
+		WriteError(ctx, http.StatusInternalServerError, "Internal error")
+	})
+}
+
+// NoRoute Answers unknown paths with the error envelope
+func NoRoute(ctx *gin.Context) {
+	WriteError(ctx, http.StatusNotFound, "No route "+ctx.Request.Method+" "+ctx.Request.URL.Path)
+}
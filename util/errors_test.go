@@ -0,0 +1,155 @@
+package util
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestEngine Serves the middlewares of routes.Attach in front of handlers
+func newTestEngine(handlers map[string]gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultErrorWriter = io.Discard // Recovery prints the stack there
+	engine := gin.New()
+	engine.Use(RequestId, Recovery())
+	engine.NoRoute(NoRoute)
+	for path, handler := range handlers {
+		engine.GET(path, handler)
+	}
+	return engine
+}
+
+func serve(engine *gin.Engine, req *http.Request) (*httptest.ResponseRecorder, ErrorBody) {
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, req)
+	body := ErrorBody{}
+	json.Unmarshal(recorder.Body.Bytes(), &body)
+	return recorder, body
+}
+
+func TestRequestId(t *testing.T) {
+	engine := newTestEngine(map[string]gin.HandlerFunc{"/id": func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, GetRequestId(ctx))
+	}})
+	tests := []struct {
+		sent   string
+		isKept bool
+	}{
+		{"helmet-7.42", true},
+		{"", false},
+		{"two words", false},
+		{"line\nbreak", false},
+		{"café", false},
+		{strings.Repeat("a", maxRequestId), true},
+		{strings.Repeat("a", maxRequestId+1), false},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/id", nil)
+		req.Header[RequestIdHeader] = []string{test.sent}
+		recorder, _ := serve(engine, req)
+		id := recorder.Header().Get(RequestIdHeader)
+		if id != recorder.Body.String() {
+			t.Errorf("%q: header %q and handler %q disagree", test.sent, id, recorder.Body)
+		}
+		if isKept := id == test.sent; isKept != test.isKept {
+			t.Errorf("%q: answered with id %q, want kept = %v", test.sent, id, test.isKept)
+		}
+		if !isValidRequestId(id) {
+			t.Errorf("%q: made an invalid id %q", test.sent, id)
+		}
+	}
+
+	first, _ := serve(engine, httptest.NewRequest(http.MethodGet, "/id", nil))
+	second, _ := serve(engine, httptest.NewRequest(http.MethodGet, "/id", nil))
+	if first.Body.String() == second.Body.String() {
+		t.Errorf("two requests got the same id %v", first.Body)
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	engine := newTestEngine(map[string]gin.HandlerFunc{"/bad": func(ctx *gin.Context) {
+		WriteError(ctx, http.StatusBadRequest, "Body must be a JSON Contact")
+		if !ctx.IsAborted() {
+			t.Error("WriteError didn't stop the handler chain")
+		}
+	}})
+	req := httptest.NewRequest(http.MethodGet, "/bad", nil)
+	req.Header.Set(RequestIdHeader, "req-1")
+	recorder, body := serve(engine, req)
+	want := ErrorBody{Code: "bad_request", Message: "Body must be a JSON Contact", RequestId: "req-1"}
+	if recorder.Code != http.StatusBadRequest || body != want {
+		t.Errorf("WriteError answered %v %+v, want 400 %+v", recorder.Code, body, want)
+	}
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+		t.Errorf("Content-Type = %v", contentType)
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	tests := map[int]string{
+		http.StatusBadRequest:            "bad_request",
+		http.StatusNotFound:              "not_found",
+		http.StatusConflict:              "conflict",
+		http.StatusRequestEntityTooLarge: "request_entity_too_large",
+		http.StatusTooManyRequests:       "throttled",
+		http.StatusInternalServerError:   "internal",
+		http.StatusNotImplemented:        "not_implemented",
+		http.StatusServiceUnavailable:    "unavailable",
+		599:                              "error",
+	}
+	for status, want := range tests {
+		if got := ErrorCode(status); got != want {
+			t.Errorf("ErrorCode(%v) = %v, want %v", status, got, want)
+		}
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	engine := newTestEngine(map[string]gin.HandlerFunc{
+		"/panic": func(ctx *gin.Context) {
+			var contact map[string]string
+			contact["Name"] = "nil map" // The kind of bug that used to take the server down
+		},
+		"/stream": func(ctx *gin.Context) {
+			ctx.String(http.StatusOK, "data: 1\n\n")
+			panic("stream broke")
+		},
+		"/ok": func(ctx *gin.Context) {
+			ctx.String(http.StatusOK, "still up")
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header.Set(RequestIdHeader, "req-2")
+	recorder, body := serve(engine, req)
+	want := ErrorBody{Code: "internal", Message: "Internal error", RequestId: "req-2"}
+	if recorder.Code != http.StatusInternalServerError || body != want {
+		t.Errorf("panicking handler answered %v %+v, want 500 %+v", recorder.Code, body, want)
+	}
+
+	// Already answering, the envelope would corrupt the body
+	recorder, _ = serve(engine, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "data: 1\n\n" {
+		t.Errorf("panicking stream answered %v %q", recorder.Code, recorder.Body)
+	}
+
+	if recorder, _ = serve(engine, httptest.NewRequest(http.MethodGet, "/ok", nil)); recorder.Code != http.StatusOK {
+		t.Errorf("after the panics /ok answered %v", recorder.Code)
+	}
+}
+
+func TestNoRoute(t *testing.T) {
+	engine := newTestEngine(nil)
+	req := httptest.NewRequest(http.MethodDelete, "/v1/contact?id=1", nil)
+	req.Header.Set(RequestIdHeader, "req-3")
+	recorder, body := serve(engine, req)
+	want := ErrorBody{Code: "not_found", Message: "No route DELETE /v1/contact", RequestId: "req-3"}
+	if recorder.Code != http.StatusNotFound || body != want {
+		t.Errorf("unknown path answered %v %+v, want 404 %+v", recorder.Code, body, want)
+	}
+}
@@ -5,18 +5,34 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
+var configErr error
+
+// init Reads config.env from the working directory, environment variables of the same name win over it
+// A missing file only stops the server (see RequireConfig), programs importing the client package run without one
 func init() {
 	viper.SetConfigName("config")
 	viper.SetConfigType("env")
 	viper.AddConfigPath("./")
+	viper.AutomaticEnv()
 
-	err := viper.ReadInConfig()
-	if err != nil {
-		log.Fatalln(err)
+	configErr = viper.ReadInConfig()
+	if _, isMissing := configErr.(viper.ConfigFileNotFoundError); configErr != nil && !isMissing {
+		log.Fatalln(configErr)
+	}
+}
+
+// RequireConfig Stops the program when config.env couldn't be read
+func RequireConfig() {
+	if configErr != nil {
+		log.Fatalln(configErr)
 	}
 }
 
@@ -79,3 +95,231 @@ func GetLogger() *log.Logger {
 func GetDataBaseName() string {
 	return viper.GetString("DATABASE_TABLE_NAME")
 }
+
+// Storage backends accepted by STORAGE_BACKEND
+const (
+	StorageDynamoDB = "dynamodb"
+	StorageMemory   = "memory"
+	StorageBolt     = "bolt"
+	StorageEdge     = "edge" // bolt locally, WorkerInfo forwarded to DynamoDB by the edge syncer
+)
+
+// GetStorageBackend Defaults to dynamodb when STORAGE_BACKEND is not set
+func GetStorageBackend() string {
+	backend := viper.GetString("STORAGE_BACKEND")
+	if backend == "" {
+		return StorageDynamoDB
+	}
+	return backend
+}
+
+// GetSyncInterval Seconds between two edge sync rounds
+func GetSyncInterval() int32 {
+	interval := viper.GetInt32("SYNC_INTERVAL")
+	if interval <= 0 {
+		return 30
+	}
+	return interval
+}
+
+// GetSyncBatchLimit Maximum journal entries uploaded in one edge sync round
+func GetSyncBatchLimit() int {
+	limit := viper.GetInt("SYNC_BATCH_LIMIT")
+	if limit <= 0 {
+		return 500
+	}
+	return limit
+}
+
+// GetSyncMaxAttempts Uploads of a journal entry the central table may reject before it is dead-lettered
+func GetSyncMaxAttempts() int {
+	attempts := viper.GetInt("SYNC_MAX_ATTEMPTS")
+	if attempts <= 0 {
+		return 5
+	}
+	return attempts
+}
+
+// GetConfigFloat Returns fallback when key is not set in config.env
+func GetConfigFloat(key string, fallback float64) float64 {
+	if !viper.IsSet(key) {
+		return fallback
+	}
+	return viper.GetFloat64(key)
+}
+
+// GetConfigDuration Accepts plain seconds (30) or a Go duration (2m), Returns fallback when key is not set
+func GetConfigDuration(key string, fallback time.Duration) time.Duration {
+	value := viper.GetString(key)
+	if value == "" {
+		return fallback
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		return duration
+	}
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Invalid duration %v for %v, using %v\n", value, key, fallback)
+		return fallback
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// GetRulesFile Alert rules file, empty means rules are built from the RULE_* values
+func GetRulesFile() string {
+	return viper.GetString("RULES_FILE")
+}
+
+// GetDefaultGasType Gas assumed for readings that don't name one
+func GetDefaultGasType() string {
+	gasType := viper.GetString("GAS_DEFAULT_TYPE")
+	if gasType == "" {
+		return "CO"
+	}
+	return gasType
+}
+
+// GetConfigMap Parses a key:value list such as 1:Asia/Kolkata,2:UTC
+func GetConfigMap(key string) map[string]string {
+	values := make(map[string]string)
+	for _, entry := range strings.Split(viper.GetString(key), ",") {
+		name, value, isFound := strings.Cut(strings.TrimSpace(entry), ":")
+		if isFound {
+			values[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+	}
+	return values
+}
+
+// GetConfigLimits Parses a GAS:limit list such as CO:25,H2S:1
+func GetConfigLimits(key string) map[string]float64 {
+	limits := make(map[string]float64)
+	for _, entry := range strings.Split(viper.GetString(key), ",") {
+		name, value, isFound := strings.Cut(strings.TrimSpace(entry), ":")
+		if !isFound {
+			continue
+		}
+		limit, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			log.Printf("Invalid limit %v in %v\n", entry, key)
+			continue
+		}
+		limits[strings.TrimSpace(name)] = limit
+	}
+	return limits
+}
+
+// GetDedupWindow How long a sequence number or Idempotency-Key is remembered, DEDUP_WINDOW in config.env
+func GetDedupWindow() time.Duration {
+	return GetConfigDuration("DEDUP_WINDOW", 10*time.Minute)
+}
+
+// GetClockSkewTolerance How far ahead of the server a helmet's MeasuredAt may be, CLOCK_SKEW_TOLERANCE in config.env
+func GetClockSkewTolerance() time.Duration {
+	return GetConfigDuration("CLOCK_SKEW_TOLERANCE", 2*time.Minute)
+}
+
+// GetMaxReadingAge Oldest MeasuredAt accepted for a buffered reading, MAX_READING_AGE in config.env
+func GetMaxReadingAge() time.Duration {
+	return GetConfigDuration("MAX_READING_AGE", 72*time.Hour)
+}
+
+var locationsOnce sync.Once
+var defaultLocation *time.Location
+var groundLocations map[string]*time.Location
+
+func loadLocation(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		log.Fatalf("Invalid time zone %v. Reason => %v\n", name, err)
+	}
+	return location
+}
+
+// GetGroundLocation Time zone a ground's days are counted in, GROUND_TIME_ZONES in config.env
+// Grounds not listed (and an empty ground) use DEFAULT_TIME_ZONE, the server's zone when that isn't set either
+func GetGroundLocation(ground string) *time.Location {
+	locationsOnce.Do(func() {
+		defaultLocation = time.Local
+		if name := viper.GetString("DEFAULT_TIME_ZONE"); name != "" {
+			defaultLocation = loadLocation(name)
+		}
+		groundLocations = make(map[string]*time.Location)
+		for groundNumber, name := range GetConfigMap("GROUND_TIME_ZONES") {
+			groundLocations[groundNumber] = loadLocation(name)
+		}
+	})
+	if location, isFound := groundLocations[ground]; isFound {
+		return location
+	}
+	return defaultLocation
+}
+
+// GetMQTTBroker Broker URL such as tcp://localhost:1883, empty disables MQTT ingestion
+func GetMQTTBroker() string {
+	return viper.GetString("MQTT_BROKER")
+}
+
+func GetMQTTClientId() string {
+	clientId := viper.GetString("MQTT_CLIENT_ID")
+	if clientId == "" {
+		return "smlr-backend"
+	}
+	return clientId
+}
+
+func GetMQTTUsername() string {
+	return viper.GetString("MQTT_USERNAME")
+}
+
+func GetMQTTPassword() string {
+	return viper.GetString("MQTT_PASSWORD")
+}
+
+// GetMQTTTopicPrefix First topic level of helmet messages, site in site/{ground}/helmet/{helmet}/telemetry
+func GetMQTTTopicPrefix() string {
+	prefix := viper.GetString("MQTT_TOPIC_PREFIX")
+	if prefix == "" {
+		return "site"
+	}
+	return prefix
+}
+
+// GetMQTTQoS Subscription QoS, 1 (at least once) unless MQTT_QOS says otherwise
+func GetMQTTQoS() byte {
+	if !viper.IsSet("MQTT_QOS") {
+		return 1
+	}
+	qos := viper.GetInt("MQTT_QOS")
+	if qos < 0 || qos > 2 {
+		log.Printf("Invalid MQTT_QOS %v, using 1\n", qos)
+		return 1
+	}
+	return byte(qos)
+}
+
+// GetUDPListenAddr Address binary helmet frames are received on, such as :4001, empty disables the UDP listener
+func GetUDPListenAddr() string {
+	return viper.GetString("UDP_LISTEN_ADDR")
+}
+
+// GetGRPCListenAddr Address the gRPC API is served on, such as localhost:4002, empty disables it
+func GetGRPCListenAddr() string {
+	return viper.GetString("GRPC_LISTEN_ADDR")
+}
+
+// GetHubToken Shared secret a hub client of role (helmet, gateway or dashboard) must present, HUB_<ROLE>_TOKEN in config.env
+func GetHubToken(role string) string {
+	return viper.GetString("HUB_" + strings.ToUpper(role) + "_TOKEN")
+}
+
+// GetHubAllowedOrigins Browser origins besides this host's that may open a hub WebSocket, HUB_ALLOWED_ORIGINS in config.env
+func GetHubAllowedOrigins() []string {
+	var origins []string
+	for _, origin := range strings.Split(viper.GetString("HUB_ALLOWED_ORIGINS"), ",") {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
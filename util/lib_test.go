@@ -0,0 +1,48 @@
+package util
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// useTimeZones Sets the time zone keys and makes GetGroundLocation read them again
+func useTimeZones(t *testing.T, defaultZone, groundZones string) {
+	t.Helper()
+	viper.Set("DEFAULT_TIME_ZONE", defaultZone)
+	viper.Set("GROUND_TIME_ZONES", groundZones)
+	locationsOnce = sync.Once{}
+	t.Cleanup(func() {
+		viper.Set("DEFAULT_TIME_ZONE", "")
+		viper.Set("GROUND_TIME_ZONES", "")
+		locationsOnce = sync.Once{}
+	})
+}
+
+func TestGetGroundLocation(t *testing.T) {
+	if _, err := time.LoadLocation("Asia/Kolkata"); err != nil {
+		t.Skipf("time zone data is missing: %v", err)
+	}
+	useTimeZones(t, "Europe/London", " 1:Asia/Kolkata , 2 : America/New_York,bad")
+	tests := map[string]string{
+		"1":  "Asia/Kolkata",
+		"2":  "America/New_York",
+		"3":  "Europe/London",
+		"":   "Europe/London",
+		"10": "Europe/London",
+	}
+	for ground, want := range tests {
+		if got := GetGroundLocation(ground).String(); got != want {
+			t.Errorf("GetGroundLocation(%q) = %v, want %v", ground, got, want)
+		}
+	}
+}
+
+func TestGetGroundLocationDefaultsToServerZone(t *testing.T) {
+	useTimeZones(t, "", "")
+	if got := GetGroundLocation("1"); got != time.Local {
+		t.Errorf("GetGroundLocation without config = %v, want the server's zone", got)
+	}
+}
@@ -0,0 +1,41 @@
+package util
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	bolt "go.etcd.io/bbolt"
+)
+
+var localDB *bolt.DB
+var localDBOnce sync.Once
+
+// GetLocalDB Opens the embedded database named by LOCAL_DB_FILE once and shares it between packages
+func GetLocalDB() *bolt.DB {
+	localDBOnce.Do(func() {
+		fileName := viper.GetString("LOCAL_DB_FILE")
+		if fileName == "" {
+			fileName = "smlr.db"
+		}
+
+		db, err := bolt.Open(GetFilePath(fileName), 0600, &bolt.Options{Timeout: 5 * time.Second})
+		if err != nil {
+			log.Fatalln(err)
+		}
+		localDB = db
+	})
+	return localDB
+}
+
+// CreateBucket Makes sure a bucket exists in the embedded database
+func CreateBucket(db *bolt.DB, name string) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(name))
+		return err
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
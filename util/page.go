@@ -0,0 +1,262 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// Page is the response envelope of a list endpoint called with limit or cursor
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// PageReader Reads up to limit items (every item when limit is 0) following the store cursor after,
+// Returns the store cursor of the next page, "" when there is nothing left, or the error of the store
+type PageReader[T any] func(after string, limit int) ([]T, string, error)
+
+const (
+	DefaultPageLimit = 100
+	MaxPageLimit     = 1000
+	NDJSONPageLimit  = 500 // Page size used while streaming an export
+)
+
+const NDJSONContentType = "application/x-ndjson"
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+var ErrInvalidLimit = fmt.Errorf("limit must be a number between 1 and %v", MaxPageLimit)
+
+var cursorSecretOnce sync.Once
+var cursorSecret []byte
+
+// GetCursorSecret Key signing cursors, CURSOR_SECRET in config.env
+// Without it a random key is used and cursors stop working when the server restarts
+func GetCursorSecret() []byte {
+	cursorSecretOnce.Do(func() {
+		if secret := viper.GetString("CURSOR_SECRET"); secret != "" {
+			cursorSecret = []byte(secret)
+			return
+		}
+		log.Println("CURSOR_SECRET not set, cursors are only valid until restart")
+		cursorSecret = make([]byte, 32)
+		if _, err := rand.Read(cursorSecret); err != nil {
+			log.Fatalln(err)
+		}
+	})
+	return cursorSecret
+}
+
+type cursorPayload struct {
+	Scope string `json:"s"`
+	After string `json:"a"`
+}
+
+func signCursor(payload []byte) []byte {
+	mac := hmac.New(sha256.New, GetCursorSecret())
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// EncodeCursor Wraps a store cursor into an opaque token, scope ties the token to one endpoint and query
+func EncodeCursor(scope, after string) string {
+	if after == "" {
+		return ""
+	}
+	payload, _ := json.Marshal(cursorPayload{Scope: scope, After: after})
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(signCursor(payload))
+}
+
+// DecodeCursor Returns the store cursor of a token made by EncodeCursor for the same scope
+func DecodeCursor(scope, token string) (string, error) {
+	encodedPayload, encodedMac, isFound := strings.Cut(token, ".")
+	if !isFound {
+		return "", ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return "", ErrInvalidCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMac)
+	if err != nil || !hmac.Equal(mac, signCursor(payload)) {
+		return "", ErrInvalidCursor
+	}
+	cursor := cursorPayload{}
+	if err = json.Unmarshal(payload, &cursor); err != nil || cursor.Scope != scope || cursor.After == "" {
+		return "", ErrInvalidCursor
+	}
+	return cursor.After, nil
+}
+
+// IsPaged Reports whether a list request asked for pages (limit, cursor or NDJSON) instead of a plain array
+func IsPaged(ctx *gin.Context) bool {
+	_, hasLimit := ctx.GetQuery("limit")
+	_, hasCursor := ctx.GetQuery("cursor")
+	return hasLimit || hasCursor || IsNDJSON(ctx)
+}
+
+// IsNDJSON Reports whether every page should be streamed as newline delimited JSON
+func IsNDJSON(ctx *gin.Context) bool {
+	return ctx.Query("format") == "ndjson" || strings.Contains(ctx.GetHeader("Accept"), NDJSONContentType)
+}
+
+// WritePage Answers a paged list request
+// ?limit=&cursor= returns one Page, ?format=ndjson (or Accept: application/x-ndjson) streams
+// every item from cursor to the end, one JSON object per line
+func WritePage[T any](ctx *gin.Context, scope string, read PageReader[T]) {
+	after := ""
+	if token, isFound := ctx.GetQuery("cursor"); isFound {
+		var err error
+		if after, err = DecodeCursor(scope, token); err != nil {
+			WriteError(ctx, http.StatusBadRequest, "cursor is invalid or belongs to another query")
+			return
+		}
+	}
+
+	if IsNDJSON(ctx) {
+		// The first page is read before answering, a store failing later can only cut the stream short
+		items, next, err := read(after, NDJSONPageLimit)
+		if err != nil {
+			WriteStoreError(ctx, err)
+			return
+		}
+		ctx.Status(http.StatusOK)
+		ctx.Header("Content-Type", NDJSONContentType)
+		encoder := json.NewEncoder(ctx.Writer)
+		for {
+			for _, item := range items {
+				if err := encoder.Encode(item); err != nil {
+					log.Printf("Couldn't stream %v export. Reason => %v\n", scope, err)
+					return
+				}
+			}
+			ctx.Writer.Flush()
+			if next == "" {
+				return
+			}
+			if items, next, err = read(next, NDJSONPageLimit); err != nil {
+				log.Printf("Couldn't stream %v export, request %v. Reason => %v\n", scope, GetRequestId(ctx), err)
+				return
+			}
+		}
+	}
+
+	limit := DefaultPageLimit
+	if value, isFound := ctx.GetQuery("limit"); isFound {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > MaxPageLimit {
+			WriteError(ctx, http.StatusBadRequest, ErrInvalidLimit.Error())
+			return
+		}
+		limit = parsed
+	}
+	page, err := readPage(scope, after, limit, read)
+	if err != nil {
+		WriteStoreError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, page)
+}
+
+// ReadPage Reads one page for callers outside gin (the gRPC API), token is a cursor from a previous page
+// A limit of 0 reads DefaultPageLimit items, errors other than ErrInvalidCursor and ErrInvalidLimit come from the store
+func ReadPage[T any](scope, token string, limit int, read PageReader[T]) (Page[T], error) {
+	after := ""
+	if token != "" {
+		var err error
+		if after, err = DecodeCursor(scope, token); err != nil {
+			return Page[T]{}, err
+		}
+	}
+	if limit == 0 {
+		limit = DefaultPageLimit
+	}
+	if limit < 0 || limit > MaxPageLimit {
+		return Page[T]{}, ErrInvalidLimit
+	}
+	return readPage(scope, after, limit, read)
+}
+
+func readPage[T any](scope, after string, limit int, read PageReader[T]) (Page[T], error) {
+	items, next, err := read(after, limit)
+	if err != nil {
+		return Page[T]{}, err
+	}
+	if items == nil {
+		items = []T{}
+	}
+	return Page[T]{Items: items, NextCursor: EncodeCursor(scope, next)}, nil
+}
+
+// EncodeKey Turns a DynamoDB LastEvaluatedKey into a store cursor, only string and number attributes are supported
+func EncodeKey(key map[string]types.AttributeValue) string {
+	if len(key) == 0 {
+		return ""
+	}
+	attributes := make(map[string][2]string)
+	for name, value := range key {
+		switch typed := value.(type) {
+		case *types.AttributeValueMemberS:
+			attributes[name] = [2]string{"S", typed.Value}
+		case *types.AttributeValueMemberN:
+			attributes[name] = [2]string{"N", typed.Value}
+		default:
+			log.Printf("Can't encode key attribute %v of type %T\n", name, value)
+			return ""
+		}
+	}
+	encoded, _ := json.Marshal(attributes)
+	return string(encoded)
+}
+
+// DecodeKey Turns a store cursor made by EncodeKey back into an ExclusiveStartKey, nil for ""
+func DecodeKey(after string) (map[string]types.AttributeValue, error) {
+	if after == "" {
+		return nil, nil
+	}
+	attributes := make(map[string][2]string)
+	if err := json.Unmarshal([]byte(after), &attributes); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	key := make(map[string]types.AttributeValue)
+	for name, value := range attributes {
+		switch value[0] {
+		case "S":
+			key[name] = &types.AttributeValueMemberS{Value: value[1]}
+		case "N":
+			key[name] = &types.AttributeValueMemberN{Value: value[1]}
+		default:
+			return nil, ErrInvalidCursor
+		}
+	}
+	return key, nil
+}
+
+// PageSorted Pages a slice sorted by key, the store cursor is the key of the last item returned
+func PageSorted[T any](sorted []T, key func(item *T) string, after string, limit int) ([]T, string) {
+	start := sort.Search(len(sorted), func(i int) bool {
+		return key(&sorted[i]) > after
+	})
+	end := len(sorted)
+	if limit > 0 && start+limit < end {
+		end = start + limit
+	}
+	if end == len(sorted) || end == start {
+		return sorted[start:end], ""
+	}
+	return sorted[start:end], key(&sorted[end-1])
+}
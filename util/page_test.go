@@ -0,0 +1,202 @@
+package util
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/gin-gonic/gin"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	token := EncodeCursor("contacts", "2024-01-01#1_2")
+	after, err := DecodeCursor("contacts", token)
+	if err != nil || after != "2024-01-01#1_2" {
+		t.Fatalf("DecodeCursor = %q, %v", after, err)
+	}
+	if EncodeCursor("contacts", "") != "" {
+		t.Error("an empty store cursor must encode to no token")
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	token := EncodeCursor("contacts", "5")
+	payload, mac, _ := strings.Cut(token, ".")
+	forged, _ := json.Marshal(cursorPayload{Scope: "contacts", After: "9"})
+	forgedPayload := strings.TrimRight(strings.NewReplacer("+", "-", "/", "_").Replace(string(forged)), "=")
+
+	tests := map[string]struct {
+		scope string
+		token string
+	}{
+		"other scope":      {"hospital", token},
+		"no mac":           {"contacts", payload},
+		"empty":            {"contacts", ""},
+		"truncated mac":    {"contacts", payload + "." + mac[:len(mac)-2]},
+		"mac not base64":   {"contacts", payload + ".!!"},
+		"payload replaced": {"contacts", forgedPayload + "." + mac},
+		"garbage":          {"contacts", "abc.def"},
+	}
+	for name, test := range tests {
+		if _, err := DecodeCursor(test.scope, test.token); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%v: DecodeCursor error = %v, want ErrInvalidCursor", name, err)
+		}
+	}
+}
+
+func TestKeyRoundTrip(t *testing.T) {
+	key := map[string]types.AttributeValue{
+		"Id":    &types.AttributeValueMemberS{Value: "1_2"},
+		"Count": &types.AttributeValueMemberN{Value: "42"},
+	}
+	decoded, err := DecodeKey(EncodeKey(key))
+	if err != nil || !reflect.DeepEqual(decoded, key) {
+		t.Fatalf("DecodeKey(EncodeKey) = %v, %v", decoded, err)
+	}
+	if EncodeKey(map[string]types.AttributeValue{"B": &types.AttributeValueMemberBOOL{Value: true}}) != "" {
+		t.Error("unsupported attribute types must not produce a cursor")
+	}
+	if _, err = DecodeKey(`{"Id":["X","1"]}`); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("DecodeKey of an unknown type error = %v", err)
+	}
+}
+
+func TestPageSorted(t *testing.T) {
+	sorted := []string{"a", "b", "c", "d", "e"}
+	key := func(item *string) string { return *item }
+
+	var walked []string
+	after := ""
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("paging doesn't end")
+		}
+		page, next := PageSorted(sorted, key, after, 2)
+		walked = append(walked, page...)
+		if next == "" {
+			break
+		}
+		after = next
+	}
+	if !reflect.DeepEqual(walked, sorted) {
+		t.Errorf("pages of 2 walked %v", walked)
+	}
+
+	if page, next := PageSorted(sorted, key, "", 5); len(page) != 5 || next != "" {
+		t.Errorf("exact fit = %v, %q, want everything and no cursor", page, next)
+	}
+	if page, next := PageSorted(sorted, key, "bb", 0); !reflect.DeepEqual(page, []string{"c", "d", "e"}) || next != "" {
+		t.Errorf("after a removed key = %v, %q", page, next)
+	}
+	if page, next := PageSorted(sorted, key, "e", 2); len(page) != 0 || next != "" {
+		t.Errorf("after the last key = %v, %q", page, next)
+	}
+}
+
+func numbersReader(count int) PageReader[int] {
+	numbers := make([]int, count)
+	for i := range numbers {
+		numbers[i] = i
+	}
+	return func(after string, limit int) ([]int, string, error) {
+		start := 0
+		if after != "" {
+			start, _ = strconv.Atoi(after)
+			start++
+		}
+		end := len(numbers)
+		if limit > 0 && start+limit < end {
+			return numbers[start : start+limit], strconv.Itoa(start + limit - 1), nil
+		}
+		return numbers[start:end], "", nil
+	}
+}
+
+func newPageRouter(count int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/numbers", func(ctx *gin.Context) {
+		WritePage(ctx, "numbers", numbersReader(count))
+	})
+	return router
+}
+
+func TestWritePageWalksCursors(t *testing.T) {
+	router := newPageRouter(5)
+	var walked []int
+	query := "?limit=2"
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("paging doesn't end")
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/numbers"+query, nil))
+		page := Page[int]{}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &page); recorder.Code != http.StatusOK || err != nil {
+			t.Fatalf("GET %v = %v %v", query, recorder.Code, recorder.Body)
+		}
+		walked = append(walked, page.Items...)
+		if page.NextCursor == "" {
+			break
+		}
+		query = "?limit=2&cursor=" + page.NextCursor
+	}
+	if !reflect.DeepEqual(walked, []int{0, 1, 2, 3, 4}) {
+		t.Errorf("walked %v", walked)
+	}
+}
+
+func TestWritePageNDJSON(t *testing.T) {
+	router := newPageRouter(NDJSONPageLimit + 3)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/numbers?format=ndjson", nil))
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != NDJSONContentType {
+		t.Fatalf("GET ndjson = %v %v", recorder.Code, recorder.Header())
+	}
+	lines := 0
+	scanner := bufio.NewScanner(recorder.Body)
+	for scanner.Scan() {
+		if scanner.Text() != strconv.Itoa(lines) {
+			t.Fatalf("line %v = %q", lines, scanner.Text())
+		}
+		lines++
+	}
+	if lines != NDJSONPageLimit+3 {
+		t.Errorf("streamed %v lines, want every item across pages", lines)
+	}
+}
+
+func TestWritePageRejects(t *testing.T) {
+	router := newPageRouter(5)
+	otherScope := EncodeCursor("contacts", "1")
+	for _, query := range []string{"?limit=0", "?limit=1001", "?limit=ten", "?cursor=nope", "?cursor=" + otherScope} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/numbers"+query, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("GET %v = %v, want 400", query, recorder.Code)
+		}
+	}
+}
+
+func TestReadPage(t *testing.T) {
+	page, err := ReadPage("numbers", "", 0, numbersReader(DefaultPageLimit+1))
+	if err != nil || len(page.Items) != DefaultPageLimit || page.NextCursor == "" {
+		t.Fatalf("ReadPage with limit 0 = %v items, %q, %v", len(page.Items), page.NextCursor, err)
+	}
+	if page, err = ReadPage("numbers", page.NextCursor, 0, numbersReader(DefaultPageLimit+1)); err != nil || len(page.Items) != 1 {
+		t.Errorf("second page = %v, %v", page, err)
+	}
+	if _, err = ReadPage("numbers", "", MaxPageLimit+1, numbersReader(1)); !errors.Is(err, ErrInvalidLimit) {
+		t.Errorf("ReadPage over MaxPageLimit error = %v", err)
+	}
+	if _, err = ReadPage("numbers", EncodeCursor("other", "1"), 1, numbersReader(1)); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("ReadPage of another scope's cursor error = %v", err)
+	}
+}
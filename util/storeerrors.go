@@ -0,0 +1,112 @@
+package util
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+)
+
+// Domain errors of the stores, WriteStoreError answers each with its own status
+var (
+	ErrNotFound    = errors.New("not found")     // 404
+	ErrConflict    = errors.New("conflict")      // 409, a condition failed or the resource is in use
+	ErrThrottled   = errors.New("throttled")     // 429 with Retry-After
+	ErrUnavailable = errors.New("unavailable")   // 503 with Retry-After
+	ErrInvalid     = errors.New("invalid input") // 400, the database rejected the request
+)
+
+// StoreError is a failed store call, Kind is the domain error it answers as
+type StoreError struct {
+	Kind    error
+	Message string // Sent to the client, a text of Kind's status when empty
+	Err     error  // Cause, only logged, nil when the store made the error
+}
+
+// NewStoreError Returns a domain error made by the store itself, e.g. ErrNotFound for a missing item
+func NewStoreError(kind error, message string) *StoreError {
+	return &StoreError{Kind: kind, Message: message}
+}
+
+func (err *StoreError) Error() string {
+	text := err.Kind.Error()
+	if err.Message != "" {
+		text += ": " + err.Message
+	}
+	if err.Err != nil {
+		text += ": " + err.Err.Error()
+	}
+	return text
+}
+
+// Is Matches Kind, errors.Is(err, ErrNotFound)
+func (err *StoreError) Is(target error) bool {
+	return target == err.Kind
+}
+
+func (err *StoreError) Unwrap() error {
+	return err.Err
+}
+
+// ClassifyStoreError Wraps an AWS SDK error in a StoreError of its domain error
+// Errors without one (and nil) are returned as they are, they answer 500
+func ClassifyStoreError(err error) error {
+	var storeErr *StoreError
+	if err == nil || errors.As(err, &storeErr) {
+		return err
+	}
+	kind, message := classify(err)
+	if kind == nil {
+		return err
+	}
+	return &StoreError{Kind: kind, Message: message, Err: err}
+}
+
+func classify(err error) (error, string) {
+	var notFound *types.ResourceNotFoundException
+	var conditionFailed *types.ConditionalCheckFailedException
+	var inUse *types.ResourceInUseException
+	var transactionConflict *types.TransactionConflictException
+	var throughputExceeded *types.ProvisionedThroughputExceededException
+	var requestLimit *types.RequestLimitExceeded
+	var internal *types.InternalServerError
+	switch {
+	case errors.As(err, &notFound):
+		return ErrNotFound, "Table or index not found"
+	case errors.As(err, &conditionFailed):
+		return ErrConflict, "The item changed, read it again"
+	case errors.As(err, &inUse), errors.As(err, &transactionConflict):
+		return ErrConflict, "The table or item is being changed"
+	case errors.As(err, &throughputExceeded), errors.As(err, &requestLimit):
+		return ErrThrottled, ""
+	case errors.As(err, &internal):
+		return ErrUnavailable, ""
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "ValidationException", "SerializationException":
+			// The database's own message names tables and attributes, WriteStoreError logs it with the request id
+			return ErrInvalid, "The database rejected the request"
+		case "ThrottlingException":
+			return ErrThrottled, ""
+		case "ServiceUnavailable", "InternalFailure":
+			return ErrUnavailable, ""
+		}
+		return nil, ""
+	}
+	// Never got an answer, DynamoDB can't be reached
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return ErrUnavailable, ""
+	}
+	return nil, ""
+}
+
+// GetStoreRetryAfter Wait suggested with a 429 or 503 from the store, STORE_RETRY_AFTER in config.env
+func GetStoreRetryAfter() time.Duration {
+	return GetConfigDuration("STORE_RETRY_AFTER", time.Second)
+}
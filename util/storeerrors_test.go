@@ -0,0 +1,117 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+func TestClassifyStoreError(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	tests := []struct {
+		name   string
+		err    error
+		kind   error
+		status int
+	}{
+		{"resource not found", &types.ResourceNotFoundException{}, ErrNotFound, http.StatusNotFound},
+		{"condition failed", &types.ConditionalCheckFailedException{}, ErrConflict, http.StatusConflict},
+		{"resource in use", &types.ResourceInUseException{}, ErrConflict, http.StatusConflict},
+		{"transaction conflict", &types.TransactionConflictException{}, ErrConflict, http.StatusConflict},
+		{"throughput exceeded", &types.ProvisionedThroughputExceededException{}, ErrThrottled, http.StatusTooManyRequests},
+		{"request limit", &types.RequestLimitExceeded{}, ErrThrottled, http.StatusTooManyRequests},
+		{"throttling", &smithy.GenericAPIError{Code: "ThrottlingException"}, ErrThrottled, http.StatusTooManyRequests},
+		{"internal", &types.InternalServerError{}, ErrUnavailable, http.StatusServiceUnavailable},
+		{"service unavailable", &smithy.GenericAPIError{Code: "ServiceUnavailable"}, ErrUnavailable, http.StatusServiceUnavailable},
+		{"unreachable", fmt.Errorf("operation Scan: %w", dialErr), ErrUnavailable, http.StatusServiceUnavailable},
+		{"validation", &smithy.GenericAPIError{Code: "ValidationException", Message: "One or more parameter values were invalid"}, ErrInvalid, http.StatusBadRequest},
+		{"serialization", &smithy.GenericAPIError{Code: "SerializationException"}, ErrInvalid, http.StatusBadRequest},
+		{"wrapped", fmt.Errorf("operation PutItem: %w", &types.ConditionalCheckFailedException{}), ErrConflict, http.StatusConflict},
+		{"unknown API error", &smithy.GenericAPIError{Code: "AccessDeniedException"}, nil, http.StatusInternalServerError},
+		{"plain", errors.New("disk full"), nil, http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		classified := ClassifyStoreError(test.err)
+		if test.kind == nil {
+			if classified != test.err {
+				t.Errorf("%v: classified as %v, want it returned as it is", test.name, classified)
+			}
+		} else if !errors.Is(classified, test.kind) || !errors.Is(classified, test.err) {
+			t.Errorf("%v: classified as %v, want %v wrapping the cause", test.name, classified, test.kind)
+		}
+		if status := StoreErrorStatus(classified); status != test.status {
+			t.Errorf("%v: status = %v, want %v", test.name, status, test.status)
+		}
+	}
+
+	if ClassifyStoreError(nil) != nil {
+		t.Error("nil is classified as an error")
+	}
+	made := NewStoreError(ErrNotFound, "No worker info for 1_2")
+	if ClassifyStoreError(made) != made {
+		t.Error("a StoreError is wrapped again")
+	}
+}
+
+func TestStoreErrorText(t *testing.T) {
+	cause := &types.ConditionalCheckFailedException{Message: new(string)}
+	err := ClassifyStoreError(cause)
+	if got := err.Error(); !strings.HasPrefix(got, "conflict: The item changed, read it again: ") {
+		t.Errorf("Error() = %q", got)
+	}
+	if got := NewStoreError(ErrNotFound, "").Error(); got != "not found" {
+		t.Errorf("Error() without a message = %q", got)
+	}
+}
+
+func TestWriteStoreError(t *testing.T) {
+	viper.Set("STORE_RETRY_AFTER", "1500ms")
+	t.Cleanup(func() { viper.Set("STORE_RETRY_AFTER", "") })
+
+	secret := "Requested resource not found: Table: WorkerInfo-prod not found"
+	tests := []struct {
+		name       string
+		err        error
+		status     int
+		message    string
+		retryAfter string
+	}{
+		{"not found", NewStoreError(ErrNotFound, "No worker info for 1_2"), http.StatusNotFound, "No worker info for 1_2", ""},
+		{"conflict", ClassifyStoreError(&types.ConditionalCheckFailedException{}), http.StatusConflict, "The item changed, read it again", ""},
+		{"throttled", ClassifyStoreError(&types.ProvisionedThroughputExceededException{}), http.StatusTooManyRequests,
+			"The database is throttling requests, retry after 2s", "2"},
+		{"unavailable", ClassifyStoreError(&types.InternalServerError{}), http.StatusServiceUnavailable,
+			"The database can't be reached, retry after 2s", "2"},
+		// The database's own text stays in the log, it names tables and attributes
+		{"invalid", ClassifyStoreError(&smithy.GenericAPIError{Code: "ValidationException", Message: secret}), http.StatusBadRequest,
+			"The database rejected the request", ""},
+		{"unclassified", errors.New(secret), http.StatusInternalServerError, "The database failed, retry later", ""},
+	}
+	for _, test := range tests {
+		engine := newTestEngine(map[string]gin.HandlerFunc{"/store": func(ctx *gin.Context) {
+			WriteStoreError(ctx, test.err)
+		}})
+		req := httptest.NewRequest(http.MethodGet, "/store", nil)
+		req.Header.Set(RequestIdHeader, "store-1")
+		recorder, body := serve(engine, req)
+		want := ErrorBody{Code: ErrorCode(test.status), Message: test.message, RequestId: "store-1"}
+		if recorder.Code != test.status || body != want {
+			t.Errorf("%v: answered %v %+v, want %v %+v", test.name, recorder.Code, body, test.status, want)
+		}
+		if got := recorder.Header().Get("Retry-After"); got != test.retryAfter {
+			t.Errorf("%v: Retry-After = %q, want %q", test.name, got, test.retryAfter)
+		}
+		if strings.Contains(recorder.Body.String(), "WorkerInfo-prod") {
+			t.Errorf("%v: the cause reached the client: %v", test.name, recorder.Body)
+		}
+	}
+}